@@ -4,5 +4,5 @@ import (
 	"embed"
 )
 
-//go:embed "emails" "migrations"
+//go:embed "emails" "migrations" "sqlite_migrations"
 var EmbeddedFiles embed.FS
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN admin bool NOT NULL DEFAULT false;
+
+UPDATE users SET admin = true WHERE id IN (SELECT user_id FROM user_roles WHERE role = 'admin');
+
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
@@ -0,0 +1,37 @@
+CREATE TABLE roles (
+    name TEXT PRIMARY KEY NOT NULL,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE permissions (
+    name TEXT PRIMARY KEY NOT NULL
+);
+
+CREATE TABLE role_permissions (
+    role TEXT NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO permissions (name) VALUES ('users:read'), ('users:write'), ('users:admin');
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to user management, including granting roles'),
+    ('user-manager', 'Can view and modify user accounts');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'users:read'),
+    ('admin', 'users:write'),
+    ('admin', 'users:admin'),
+    ('user-manager', 'users:read'),
+    ('user-manager', 'users:write');
+
+INSERT INTO user_roles (user_id, role) SELECT id, 'admin' FROM users WHERE admin;
+
+ALTER TABLE users DROP COLUMN admin;
@@ -0,0 +1,9 @@
+CREATE TABLE tokens (
+    hash bytea PRIMARY KEY NOT NULL,
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expiry TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX tokens_user_id_scope_idx ON tokens (user_id, scope);
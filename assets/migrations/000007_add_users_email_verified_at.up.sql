@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Accounts created before verification existed are treated as verified so
+-- they aren't locked out when verification is required.
+UPDATE users SET email_verified_at = created;
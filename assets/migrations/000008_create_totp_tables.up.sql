@@ -0,0 +1,13 @@
+CREATE TABLE user_totp (
+    user_id uuid PRIMARY KEY NOT NULL REFERENCES users ON DELETE CASCADE,
+    secret bytea NOT NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    confirmed TIMESTAMPTZ
+);
+
+CREATE TABLE recovery_codes (
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    code_hash bytea NOT NULL,
+    used TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE api_keys (
+    id uuid PRIMARY KEY NOT NULL,
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash bytea NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires TIMESTAMPTZ,
+    last_used TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
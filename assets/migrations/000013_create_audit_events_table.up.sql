@@ -0,0 +1,14 @@
+CREATE TABLE audit_events (
+    id uuid PRIMARY KEY,
+    actor_id uuid,
+    target_id uuid,
+    action TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    diff JSONB NOT NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_created_idx ON audit_events (created);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, created);
+CREATE INDEX audit_events_target_id_idx ON audit_events (target_id, created);
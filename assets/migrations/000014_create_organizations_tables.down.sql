@@ -0,0 +1,2 @@
+DROP TABLE organization_memberships;
+DROP TABLE organizations;
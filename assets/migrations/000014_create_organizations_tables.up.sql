@@ -0,0 +1,15 @@
+CREATE TABLE organizations (
+    id uuid PRIMARY KEY,
+    name TEXT NOT NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE organization_memberships (
+    organization_id uuid NOT NULL REFERENCES organizations ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX organization_memberships_user_id_idx ON organization_memberships (user_id);
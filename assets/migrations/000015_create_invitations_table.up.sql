@@ -0,0 +1,11 @@
+CREATE TABLE invitations (
+    id uuid PRIMARY KEY,
+    organization_id uuid NOT NULL REFERENCES organizations ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    hash BYTEA NOT NULL UNIQUE,
+    invited_by uuid REFERENCES users ON DELETE SET NULL,
+    created TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires TIMESTAMPTZ NOT NULL,
+    UNIQUE (organization_id, email)
+);
@@ -0,0 +1,4 @@
+DROP INDEX users_status_idx;
+ALTER TABLE users DROP COLUMN status_changed_at;
+ALTER TABLE users DROP COLUMN status_reason;
+ALTER TABLE users DROP COLUMN status;
@@ -0,0 +1,2 @@
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,5 @@
+DROP INDEX invitations_organization_id_email_key;
+ALTER TABLE invitations ADD CONSTRAINT invitations_organization_id_email_key UNIQUE (organization_id, email);
+
+DROP INDEX users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
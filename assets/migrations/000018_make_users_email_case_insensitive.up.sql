@@ -0,0 +1,92 @@
+-- Emails are stored in the form returned by emailaddr.Normalize and compared
+-- case-insensitively from now on. Existing addresses are normalized here as
+-- far as SQL can: surrounding whitespace is removed, the address is put into
+-- NFC, and the domain loses one trailing dot and is lowercased. Domains that
+-- need converting to punycode, addresses that can't be split into a local
+-- part and a domain, and accounts whose normalized addresses collide can't be
+-- fixed automatically, so list them and stop instead of failing on the unique
+-- index below.
+CREATE TEMPORARY TABLE normalized_emails AS
+SELECT
+    id,
+    created,
+    email,
+    left(address, -strpos(reverse(address), '@')) AS local_part,
+    lower(regexp_replace(right(address, strpos(reverse(address), '@') - 1), '\.$', '')) AS domain
+FROM (
+    SELECT id, created, email, normalize(btrim(email, E' \t\n\r\x0B\f'), NFC) AS address
+    FROM users
+) AS trimmed;
+
+DO $$
+DECLARE
+    invalid TEXT;
+    collisions TEXT;
+BEGIN
+    SELECT string_agg(format('%s (%s)', email, id), '; ' ORDER BY created) INTO invalid
+    FROM normalized_emails
+    WHERE local_part = '' OR domain !~ '^[a-z0-9-]+(\.[a-z0-9-]+)*$';
+
+    IF invalid IS NOT NULL THEN
+        RAISE EXCEPTION 'users.email has addresses that can''t be normalized: %', invalid
+            USING HINT = 'Change internationalized domains to their punycode form and fix or delete the other accounts, then run the migration again.';
+    END IF;
+
+    SELECT string_agg(format('%s (%s)', emails, ids), '; ') INTO collisions
+    FROM (
+        SELECT string_agg(email, ', ' ORDER BY created) AS emails, string_agg(id::text, ', ' ORDER BY created) AS ids
+        FROM normalized_emails
+        GROUP BY lower(local_part || '@' || domain)
+        HAVING COUNT(*) > 1
+    ) AS duplicates;
+
+    IF collisions IS NOT NULL THEN
+        RAISE EXCEPTION 'users.email has addresses that are the same once normalized: %', collisions
+            USING HINT = 'Rename or delete all but one of each set of accounts, then run the migration again.';
+    END IF;
+END
+$$;
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+
+UPDATE users
+SET email = normalized_emails.local_part || '@' || normalized_emails.domain
+FROM normalized_emails
+WHERE users.id = normalized_emails.id AND users.email <> normalized_emails.local_part || '@' || normalized_emails.domain;
+
+DROP TABLE normalized_emails;
+
+CREATE UNIQUE INDEX users_email_key ON users (lower(email));
+
+-- Invitations are normalized the same way where possible. Pending
+-- invitations that become duplicates are replaced by the most recent one,
+-- which can be resent.
+ALTER TABLE invitations DROP CONSTRAINT invitations_organization_id_email_key;
+
+UPDATE invitations
+SET email = normalized.local_part || '@' || normalized.domain
+FROM (
+    SELECT
+        id,
+        left(address, -strpos(reverse(address), '@')) AS local_part,
+        lower(regexp_replace(right(address, strpos(reverse(address), '@') - 1), '\.$', '')) AS domain
+    FROM (
+        SELECT id, normalize(btrim(email, E' \t\n\r\x0B\f'), NFC) AS address
+        FROM invitations
+    ) AS trimmed
+) AS normalized
+WHERE invitations.id = normalized.id
+    AND normalized.local_part <> ''
+    AND normalized.domain ~ '^[a-z0-9-]+(\.[a-z0-9-]+)*$';
+
+DELETE FROM invitations
+WHERE id IN (
+    SELECT id
+    FROM (
+        SELECT id, row_number() OVER (PARTITION BY organization_id, lower(email) ORDER BY created DESC) AS position
+        FROM invitations
+    ) AS ranked
+    WHERE position > 1
+);
+
+CREATE UNIQUE INDEX invitations_organization_id_email_key ON invitations (organization_id, lower(email));
@@ -0,0 +1,17 @@
+DROP TABLE invitations;
+DROP TABLE organization_memberships;
+DROP TABLE organizations;
+DROP TABLE audit_events;
+DROP TABLE sessions;
+DROP TABLE login_throttles;
+DROP TABLE user_identities;
+DROP TABLE api_keys;
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
+DROP TABLE tokens;
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
+DROP TABLE refresh_tokens;
+DROP TABLE users;
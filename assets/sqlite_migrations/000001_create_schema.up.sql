@@ -0,0 +1,189 @@
+-- The SQLite schema mirrors the PostgreSQL one in assets/migrations, with a
+-- few differences:
+--   * UUIDs are stored as text and hashes as blobs.
+--   * Timestamps are stored as microseconds since the Unix epoch, the same
+--     precision as PostgreSQL timestamps, and are always set by the store.
+--   * Emails are compared using the email_key columns, which hold the
+--     lowercased address, since SQLite's lower() only handles ASCII.
+--   * Arrays and JSONB are stored as JSON text.
+
+CREATE TABLE users (
+    id TEXT PRIMARY KEY NOT NULL,
+    created INTEGER NOT NULL,
+    email TEXT NOT NULL,
+    email_key TEXT NOT NULL UNIQUE,
+    hashed_password TEXT NOT NULL,
+    token_version INTEGER NOT NULL DEFAULT 1,
+    email_verified_at INTEGER,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'suspended', 'deactivated')),
+    status_reason TEXT NOT NULL DEFAULT '',
+    status_changed_at INTEGER,
+    deleted_at INTEGER
+);
+
+CREATE INDEX users_status_idx ON users (status);
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE refresh_tokens (
+    id TEXT PRIMARY KEY NOT NULL,
+    family_id TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    token_hash BLOB NOT NULL UNIQUE,
+    created INTEGER NOT NULL,
+    expires INTEGER NOT NULL,
+    used INTEGER,
+    revoked INTEGER
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+CREATE TABLE roles (
+    name TEXT PRIMARY KEY NOT NULL,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE permissions (
+    name TEXT PRIMARY KEY NOT NULL
+);
+
+CREATE TABLE role_permissions (
+    role TEXT NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE user_roles (
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO permissions (name) VALUES ('users:read'), ('users:write'), ('users:admin');
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full access to user management, including granting roles'),
+    ('user-manager', 'Can view and modify user accounts');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'users:read'),
+    ('admin', 'users:write'),
+    ('admin', 'users:admin'),
+    ('user-manager', 'users:read'),
+    ('user-manager', 'users:write');
+
+CREATE TABLE tokens (
+    hash BLOB PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    created INTEGER NOT NULL,
+    expiry INTEGER NOT NULL
+);
+
+CREATE INDEX tokens_user_id_scope_idx ON tokens (user_id, scope);
+
+CREATE TABLE user_totp (
+    user_id TEXT PRIMARY KEY NOT NULL REFERENCES users ON DELETE CASCADE,
+    secret BLOB NOT NULL,
+    created INTEGER NOT NULL,
+    confirmed INTEGER
+);
+
+CREATE TABLE recovery_codes (
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    code_hash BLOB NOT NULL,
+    used INTEGER,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+CREATE TABLE api_keys (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    hash BLOB NOT NULL UNIQUE,
+    scopes TEXT NOT NULL DEFAULT '[]',
+    created INTEGER NOT NULL,
+    expires INTEGER,
+    last_used INTEGER
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+
+CREATE TABLE user_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    created INTEGER NOT NULL,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+CREATE TABLE login_throttles (
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failure INTEGER NOT NULL,
+    locked_until INTEGER,
+    PRIMARY KEY (kind, subject)
+);
+
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    hash BLOB NOT NULL UNIQUE,
+    device TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    created INTEGER NOT NULL,
+    last_seen INTEGER NOT NULL,
+    expires INTEGER NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+CREATE TABLE audit_events (
+    id TEXT PRIMARY KEY NOT NULL,
+    actor_id TEXT,
+    target_id TEXT,
+    action TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    diff TEXT NOT NULL,
+    created INTEGER NOT NULL
+);
+
+CREATE INDEX audit_events_created_idx ON audit_events (created);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, created);
+CREATE INDEX audit_events_target_id_idx ON audit_events (target_id, created);
+
+CREATE TABLE organizations (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    created INTEGER NOT NULL
+);
+
+CREATE TABLE organization_memberships (
+    organization_id TEXT NOT NULL REFERENCES organizations ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    created INTEGER NOT NULL,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX organization_memberships_user_id_idx ON organization_memberships (user_id);
+
+CREATE TABLE invitations (
+    id TEXT PRIMARY KEY NOT NULL,
+    organization_id TEXT NOT NULL REFERENCES organizations ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    email_key TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    hash BLOB NOT NULL UNIQUE,
+    invited_by TEXT REFERENCES users ON DELETE SET NULL,
+    created INTEGER NOT NULL,
+    expires INTEGER NOT NULL,
+    UNIQUE (organization_id, email_key)
+);
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/internal/password"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// bootstrapAdmin gives the user with BOOTSTRAP_ADMIN_EMAIL the admin role at
+// startup, creating them with BOOTSTRAP_ADMIN_PASSWORD if they don't exist.
+// Only admins can make other users admins, so this is how a new database gets
+// its first one.
+func (app *application) bootstrapAdmin(ctx context.Context) error {
+	if app.config.bootstrap.adminEmail == "" {
+		return nil
+	}
+
+	email, err := emailaddr.Normalize(app.config.bootstrap.adminEmail)
+	if err != nil {
+		return fmt.Errorf("BOOTSTRAP_ADMIN_EMAIL: %w", err)
+	}
+
+	user, err := app.store.UserRetrieveByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if user.Admin {
+			return nil
+		}
+
+		err = app.store.UserRoleGrant(ctx, user.ID, store.RoleAdmin)
+		if err != nil {
+			return err
+		}
+	case errors.Is(err, store.ErrUserNotFound):
+		if app.config.bootstrap.adminPassword == "" {
+			app.logger.Warn("bootstrap admin doesn't exist, set BOOTSTRAP_ADMIN_PASSWORD to create them", "email", email)
+			return nil
+		}
+		if len(app.config.bootstrap.adminPassword) < 8 {
+			return errors.New("BOOTSTRAP_ADMIN_PASSWORD is too short")
+		}
+
+		hashedPassword, err := password.Hash(app.config.bootstrap.adminPassword)
+		if err != nil {
+			return err
+		}
+
+		err = app.store.WithinTx(ctx, store.TxOptions{}, func(tx store.Tx) error {
+			user, err := tx.UserInsert(ctx, email, hashedPassword, uuid.New(), true)
+			if err != nil {
+				return err
+			}
+			return tx.UserVerifyEmail(ctx, user.ID)
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	app.logger.Info("granted the admin role to the bootstrap admin", "email", email)
+	return nil
+}
@@ -10,6 +10,11 @@ type contextKey string
 
 const (
 	authenticatedUserContextKey = contextKey("authenticatedUser")
+	apiKeyContextKey            = contextKey("apiKey")
+	sessionContextKey           = contextKey("session")
+	impersonatorContextKey      = contextKey("impersonator")
+	organizationContextKey      = contextKey("organization")
+	membershipContextKey        = contextKey("membership")
 )
 
 func contextSetAuthenticatedUser(r *http.Request, user *store.User) *http.Request {
@@ -25,3 +30,85 @@ func contextGetAuthenticatedUser(r *http.Request) *store.User {
 
 	return user
 }
+
+func contextSetAPIKey(r *http.Request, apiKey *store.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, apiKey)
+	return r.WithContext(ctx)
+}
+
+// contextGetAPIKey returns the API key used to authenticate the request, or
+// nil if the request was not authenticated with an API key.
+func contextGetAPIKey(r *http.Request) *store.APIKey {
+	apiKey, ok := r.Context().Value(apiKeyContextKey).(*store.APIKey)
+	if !ok {
+		return nil
+	}
+
+	return apiKey
+}
+
+func contextSetSession(r *http.Request, session *store.Session) *http.Request {
+	ctx := context.WithValue(r.Context(), sessionContextKey, session)
+	return r.WithContext(ctx)
+}
+
+// contextGetSession returns the session used to authenticate the request, or
+// nil if the request was not authenticated with a session cookie.
+func contextGetSession(r *http.Request) *store.Session {
+	session, ok := r.Context().Value(sessionContextKey).(*store.Session)
+	if !ok {
+		return nil
+	}
+
+	return session
+}
+
+func contextSetImpersonator(r *http.Request, actor *store.User) *http.Request {
+	ctx := context.WithValue(r.Context(), impersonatorContextKey, actor)
+	return r.WithContext(ctx)
+}
+
+// contextGetImpersonator returns the admin acting as the authenticated user,
+// or nil if the request is not impersonated. contextGetAuthenticatedUser
+// returns the user being impersonated.
+func contextGetImpersonator(r *http.Request) *store.User {
+	actor, ok := r.Context().Value(impersonatorContextKey).(*store.User)
+	if !ok {
+		return nil
+	}
+
+	return actor
+}
+
+func contextSetOrganization(r *http.Request, organization *store.Organization) *http.Request {
+	ctx := context.WithValue(r.Context(), organizationContextKey, organization)
+	return r.WithContext(ctx)
+}
+
+// contextGetOrganization returns the organization selected for the request,
+// or nil if no organization was selected.
+func contextGetOrganization(r *http.Request) *store.Organization {
+	organization, ok := r.Context().Value(organizationContextKey).(*store.Organization)
+	if !ok {
+		return nil
+	}
+
+	return organization
+}
+
+func contextSetMembership(r *http.Request, membership *store.Membership) *http.Request {
+	ctx := context.WithValue(r.Context(), membershipContextKey, membership)
+	return r.WithContext(ctx)
+}
+
+// contextGetMembership returns the authenticated user's membership of the
+// organization selected for the request, or nil if no organization was
+// selected.
+func contextGetMembership(r *http.Request) *store.Membership {
+	membership, ok := r.Context().Value(membershipContextKey).(*store.Membership)
+	if !ok {
+		return nil
+	}
+
+	return membership
+}
@@ -3,12 +3,16 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mrityunjaygr8/autostrada-test/internal/response"
 	"github.com/mrityunjaygr8/autostrada-test/internal/validator"
+	"github.com/mrityunjaygr8/autostrada-test/store"
 )
 
 func (app *application) reportServerError(r *http.Request, err error) {
@@ -71,3 +75,63 @@ func (app *application) invalidAuthenticationToken(w http.ResponseWriter, r *htt
 func (app *application) authenticationRequired(w http.ResponseWriter, r *http.Request) {
 	app.errorMessage(w, r, http.StatusUnauthorized, "You must be authenticated to access this resource", nil)
 }
+
+func (app *application) invalidCredentials(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusUnauthorized, "Invalid authentication credentials", nil)
+}
+
+func (app *application) invalidRefreshToken(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
+}
+
+func (app *application) loginLocked(w http.ResponseWriter, r *http.Request, lockedUntil time.Time) {
+	headers := make(http.Header)
+	headers.Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedUntil.Sub(app.now()).Seconds()))))
+
+	app.errorMessage(w, r, http.StatusTooManyRequests, "Too many failed login attempts, please try again later", headers)
+}
+
+func (app *application) notPermitted(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusForbidden, "Your user account doesn't have the necessary permissions to access this resource", nil)
+}
+
+func (app *application) emailNotVerified(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusForbidden, "You must verify your email address before you can log in", nil)
+}
+
+func (app *application) accountNotActive(w http.ResponseWriter, r *http.Request, user *store.User) {
+	app.errorMessage(w, r, http.StatusForbidden, fmt.Sprintf("Your account has been %s", user.Status), nil)
+}
+
+func (app *application) invalidMFAToken(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusUnauthorized, "Invalid or expired MFA token", nil)
+}
+
+func (app *application) invalidMFACode(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusUnauthorized, "Invalid authentication code", nil)
+}
+
+func (app *application) invalidMagicLink(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusUnauthorized, "Invalid or expired sign-in link", nil)
+}
+
+func (app *application) apiKeyNotPermitted(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusForbidden, "This action can't be performed using an API key", nil)
+}
+
+func (app *application) impersonationNotPermitted(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusForbidden, "This action can't be performed while impersonating a user", nil)
+}
+
+func (app *application) notOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusForbidden, "You aren't a member of the selected organization", nil)
+}
+
+func (app *application) invalidCSRFToken(w http.ResponseWriter, r *http.Request) {
+	app.errorMessage(w, r, http.StatusForbidden, "Missing or invalid CSRF token", nil)
+}
+
+func (app *application) singleSignOnFailed(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warn("single sign-on failed", "error", err.Error())
+	app.errorMessage(w, r, http.StatusUnauthorized, "Single sign-on failed", nil)
+}
@@ -3,14 +3,20 @@ package main
 import (
 	"errors"
 	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
 	"github.com/mrityunjaygr8/autostrada-test/internal/password"
 	"github.com/mrityunjaygr8/autostrada-test/internal/request"
 	"github.com/mrityunjaygr8/autostrada-test/internal/response"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
 	"github.com/mrityunjaygr8/autostrada-test/internal/validator"
 	"github.com/mrityunjaygr8/autostrada-test/store"
+	"golang.org/x/oauth2"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 func (app *application) status(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +44,22 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existingUser, err := app.store.UserRetrieveByEmail(input.Email)
+	input.Email = normalizeEmail(input.Email)
+
+	if app.config.signup.disabled {
+		permitted, err := app.userHasPermission(r, store.PermissionUsersWrite)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !permitted {
+			app.notPermitted(w, r)
+			return
+		}
+	}
+
+	existingUser, err := app.store.UserRetrieveByEmail(r.Context(), input.Email)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrUserNotFound):
@@ -49,14 +70,23 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	input.Validator.CheckField(input.Email != "", "email", "Email is required")
-	input.Validator.CheckField(validator.Matches(input.Email, validator.RgxEmail), "email", "Must be a valid email address")
+	if input.Admin {
+		permitted, err := app.userHasPermission(r, store.PermissionUsersAdmin)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !permitted {
+			app.notPermitted(w, r)
+			return
+		}
+	}
+
+	checkEmail(&input.Validator, input.Email)
 	input.Validator.CheckField(existingUser == nil, "email", "Email is already in use")
 
-	input.Validator.CheckField(input.Password != "", "password", "Password is required")
-	input.Validator.CheckField(len(input.Password) >= 8, "password", "Password is too short")
-	input.Validator.CheckField(len(input.Password) <= 72, "password", "Password is too long")
-	input.Validator.CheckField(validator.NotIn(input.Password, password.CommonPasswords...), "password", "Password is too common")
+	checkPassword(&input.Validator, "password", input.Password)
 
 	if input.Validator.HasErrors() {
 		app.failedValidation(w, r, input.Validator)
@@ -69,13 +99,36 @@ func (app *application) createUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := uuid.New()
-	user, err := app.store.UserInsert(input.Email, hashedPassword, id, input.Admin)
+	// The user and their activation token are created together, so a user
+	// is never left without a way to activate their account. The email is
+	// only sent once both have been committed.
+	var user *store.User
+	var plaintext string
+	var activationToken store.Token
+	err = app.auditStore(r).WithinTx(r.Context(), store.TxOptions{}, func(tx store.Tx) error {
+		var err error
+		user, err = tx.UserInsert(r.Context(), input.Email, hashedPassword, uuid.New(), input.Admin)
+		if err != nil {
+			return err
+		}
+
+		plaintext, activationToken, err = app.newScopedToken(r.Context(), tx, user.ID, store.ScopeActivation, app.config.activation.tokenTTL)
+		return err
+	})
 	if err != nil {
-		app.serverError(w, r, err)
+		switch {
+		// A deleted user keeps their email address until they are purged.
+		case errors.Is(err, store.ErrUserExists):
+			input.Validator.AddFieldError("email", "Email is already in use")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
 		return
 	}
 
+	app.mailActivationToken(r, user, plaintext, activationToken)
+
 	err = response.JSONWithHeaders(w, http.StatusCreated, map[string]interface{}{"Data": user}, nil)
 	if err != nil {
 		app.serverError(w, r, err)
@@ -101,6 +154,7 @@ func (app *application) listUsers(w http.ResponseWriter, r *http.Request) {
 	var pageSizeErr, pageNumberErr error
 	input.PageSize = r.URL.Query().Get("pageSize")
 	input.PageNumber = r.URL.Query().Get("pageNumber")
+	params.Status = r.URL.Query().Get("status")
 
 	if input.PageNumber == "" {
 		input.PageNumber = DefaultPageNumber
@@ -119,13 +173,19 @@ func (app *application) listUsers(w http.ResponseWriter, r *http.Request) {
 	input.Validator.CheckField(pageNumberErr == nil, "pageNumber", "pageNumber must be a positive integer")
 	input.Validator.CheckField(params.PageSize > 0, "pageSize", "pageSize must be a positive integer")
 	input.Validator.CheckField(params.PageNumber > 0, "pageNumber", "pageNumber must be a positive integer")
+	input.Validator.CheckField(params.Status == "" || validator.In(params.Status, store.UserStatusActive, store.UserStatusSuspended, store.UserStatusDeactivated), "status", "status must be active, suspended or deactivated")
 
 	if input.Validator.HasErrors() {
 		fmt.Println(input.Validator.FieldErrors)
 		app.failedValidation(w, r, input.Validator)
 		return
 	}
-	users, err := app.store.UserList(params)
+
+	if organization := contextGetOrganization(r); organization != nil {
+		params.OrganizationID = &organization.ID
+	}
+
+	users, err := app.store.UserList(r.Context(), params)
 	if err != nil {
 		app.serverError(w, r, err)
 		return
@@ -136,3 +196,2424 @@ func (app *application) listUsers(w http.ResponseWriter, r *http.Request) {
 		app.serverError(w, r, err)
 	}
 }
+
+func (app *application) createAuthenticationToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string              `json:"email"`
+		Password  string              `json:"password"`
+		Session   bool                `json:"session"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Email = normalizeEmail(input.Email)
+
+	input.Validator.CheckField(input.Email != "", "email", "Email is required")
+	input.Validator.CheckField(input.Password != "", "password", "Password is required")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	lockedUntil, err := app.loginLockedUntil(r.Context(), input.Email, clientIP(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if lockedUntil != nil {
+		app.loginLocked(w, r, *lockedUntil)
+		return
+	}
+
+	user, err := app.store.UserRetrieveByEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			err = app.recordLoginFailure(r, input.Email, nil)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			app.invalidCredentials(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	passwordMatches, err := password.Matches(input.Password, user.HashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !passwordMatches {
+		err = app.recordLoginFailure(r, input.Email, user)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		app.invalidCredentials(w, r)
+		return
+	}
+
+	app.continueLogin(w, r, user, input.Session)
+}
+
+func (app *application) completeMFAChallenge(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MFAToken     string              `json:"mfa_token"`
+		Code         string              `json:"code"`
+		RecoveryCode string              `json:"recovery_code"`
+		Session      bool                `json:"session"`
+		Validator    validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.MFAToken != "", "mfa_token", "MFA token is required")
+	input.Validator.CheckField(input.Code != "" || input.RecoveryCode != "", "code", "Code or recovery code is required")
+	input.Validator.CheckField(input.Code == "" || input.RecoveryCode == "", "code", "Provide either a code or a recovery code, not both")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	// The challenge is consumed on every attempt, so each password login
+	// allows a single guess at the second factor.
+	userID, err := app.store.TokenConsume(r.Context(), store.ScopeMFAChallenge, token.Hash(input.MFAToken))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTokenNotFound):
+			app.invalidMFAToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.invalidMFAToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if user.Status != store.UserStatusActive {
+		app.accountNotActive(w, r, user)
+		return
+	}
+
+	// Wrong codes count against the same throttles as wrong passwords, so a
+	// challenge issued before the account was locked can't be used after.
+	lockedUntil, err := app.loginLockedUntil(r.Context(), user.Email, clientIP(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if lockedUntil != nil {
+		app.loginLocked(w, r, *lockedUntil)
+		return
+	}
+
+	if input.RecoveryCode != "" {
+		err = app.store.RecoveryCodeConsume(r.Context(), user.ID, token.HashRecoveryCode(input.RecoveryCode))
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrRecoveryCodeNotFound):
+				err = app.recordLoginFailure(r, user.Email, user)
+				if err != nil {
+					app.serverError(w, r, err)
+					return
+				}
+				app.invalidMFACode(w, r)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+	} else {
+		userTOTP, err := app.store.TOTPRetrieve(r.Context(), user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrTOTPNotFound):
+				app.invalidMFAToken(w, r)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		valid, err := app.validateTOTPCode(userTOTP, input.Code)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if !valid {
+			err = app.recordLoginFailure(r, user.Email, user)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+			app.invalidMFACode(w, r)
+			return
+		}
+	}
+
+	err = app.store.LoginThrottleReset(r.Context(), store.LoginThrottleAccount, emailaddr.Key(user.Email))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.writeLogin(w, r, user, input.Session)
+}
+
+func (app *application) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	state, _, err := token.Generate()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	nonce, _, err := token.Generate()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	loginState := oidcState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		Session:  r.URL.Query().Get("session") == "true",
+	}
+
+	err = app.setOIDCStateCookie(w, loginState)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	authCodeURL := app.oidc.oauth2.AuthCodeURL(loginState.State, oidc.Nonce(loginState.Nonce), oauth2.S256ChallengeOption(loginState.Verifier))
+	http.Redirect(w, r, authCodeURL, http.StatusFound)
+}
+
+func (app *application) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if app.oidc == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	loginState, err := app.readOIDCStateCookie(r)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	clearOIDCStateCookie(w)
+
+	query := r.URL.Query()
+	if query.Get("state") != loginState.State {
+		app.badRequest(w, r, errInvalidOIDCState)
+		return
+	}
+
+	if providerError := query.Get("error"); providerError != "" {
+		app.singleSignOnFailed(w, r, fmt.Errorf("provider returned %s: %s", providerError, query.Get("error_description")))
+		return
+	}
+
+	oauth2Token, err := app.oidc.oauth2.Exchange(r.Context(), query.Get("code"), oauth2.VerifierOption(loginState.Verifier))
+	if err != nil {
+		app.singleSignOnFailed(w, r, err)
+		return
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		app.singleSignOnFailed(w, r, errors.New("token response did not include an id_token"))
+		return
+	}
+
+	idToken, err := app.oidc.verifier.Verify(r.Context(), rawIDToken)
+	if err != nil {
+		app.singleSignOnFailed(w, r, err)
+		return
+	}
+
+	if idToken.Nonce != loginState.Nonce {
+		app.singleSignOnFailed(w, r, errors.New("id_token nonce does not match"))
+		return
+	}
+
+	var claims oidcClaims
+	err = idToken.Claims(&claims)
+	if err != nil {
+		app.singleSignOnFailed(w, r, err)
+		return
+	}
+
+	user, err := app.resolveOIDCUser(r, idToken, claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, errUnverifiedEmail), errors.Is(err, errUnverifiedAccount), errors.Is(err, store.ErrUserNotFound):
+			app.singleSignOnFailed(w, r, err)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// The provider only stands in for the password, so users with TOTP
+	// enabled still have to complete the challenge.
+	app.continueLogin(w, r, user, loginState.Session)
+}
+
+func (app *application) refreshAuthenticationToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string              `json:"refresh_token"`
+		Validator    validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.RefreshToken != "", "refresh_token", "Refresh token is required")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	current, err := app.store.RefreshTokenRetrieve(r.Context(), token.Hash(input.RefreshToken))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRefreshTokenNotFound):
+			app.invalidRefreshToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if current.Used != nil {
+		app.revokeRefreshTokenFamily(w, r, current)
+		return
+	}
+
+	if current.Revoked != nil || !app.now().Before(current.Expires) {
+		app.invalidRefreshToken(w, r)
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), current.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.invalidRefreshToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if user.Status != store.UserStatusActive {
+		app.accountNotActive(w, r, user)
+		return
+	}
+
+	refreshToken, refreshTokenRecord, err := app.newRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	_, err = app.store.RefreshTokenRotate(r.Context(), current.ID, refreshTokenRecord)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRefreshTokenReused):
+			app.revokeRefreshTokenFamily(w, r, current)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	accessToken, accessTokenExpiry, err := app.newAuthenticationToken(user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := authenticationTokensData(accessToken, accessTokenExpiry, refreshToken, refreshTokenRecord.Expires)
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) revokeRefreshTokenFamily(w http.ResponseWriter, r *http.Request, reused *store.RefreshToken) {
+	app.logger.Warn("refresh token reuse detected", "user_id", reused.UserID, "family_id", reused.FamilyID)
+
+	err := app.store.RefreshTokenRevokeFamily(r.Context(), reused.FamilyID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.invalidRefreshToken(w, r)
+}
+
+func (app *application) retrieveUser(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	permitted, err := app.canManageUser(r, id, store.PermissionUsersRead)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !permitted {
+		app.notPermitted(w, r)
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": user})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var input struct {
+		Email     *string             `json:"email"`
+		Password  *string             `json:"password"`
+		Admin     *bool               `json:"admin"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err = request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Email != nil {
+		*input.Email = normalizeEmail(*input.Email)
+	}
+
+	// Like the /me routes, API keys and impersonation tokens can't change
+	// anyone's credentials, including the user they act as.
+	if input.Email != nil || input.Password != nil {
+		switch {
+		case contextGetAPIKey(r) != nil:
+			app.apiKeyNotPermitted(w, r)
+			return
+		case contextGetImpersonator(r) != nil:
+			app.impersonationNotPermitted(w, r)
+			return
+		}
+	}
+
+	permitted, err := app.canManageUser(r, id, store.PermissionUsersWrite)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if permitted && input.Admin != nil {
+		permitted, err = app.userHasPermission(r, store.PermissionUsersAdmin)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if !permitted {
+		app.notPermitted(w, r)
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	emailChanged := input.Email != nil && *input.Email != user.Email
+	if emailChanged {
+		existingUser, err := app.store.UserRetrieveByEmail(r.Context(), *input.Email)
+		if err != nil && !errors.Is(err, store.ErrUserNotFound) {
+			app.serverError(w, r, err)
+			return
+		}
+
+		checkEmail(&input.Validator, *input.Email)
+		input.Validator.CheckField(existingUser == nil || existingUser.ID == id, "email", "Email is already in use")
+	}
+
+	if input.Password != nil {
+		checkPassword(&input.Validator, "password", *input.Password)
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	var hashedPassword string
+	if input.Password != nil {
+		hashedPassword, err = password.Hash(*input.Password)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	// Apply every change or none of them, so a failure part way through
+	// doesn't leave the user half updated.
+	var activationPlaintext string
+	var activationToken store.Token
+	err = app.auditStore(r).WithinTx(r.Context(), store.TxOptions{}, func(tx store.Tx) error {
+		if emailChanged {
+			err := tx.UserUpdateEmail(r.Context(), id, *input.Email)
+			if err != nil {
+				return err
+			}
+		}
+
+		if input.Password != nil {
+			err := tx.UserUpdatePassword(r.Context(), id, hashedPassword)
+			if err != nil {
+				return err
+			}
+		}
+
+		if input.Admin != nil && *input.Admin != user.Admin {
+			err := tx.UserUpdateAdmin(r.Context(), id, *input.Admin)
+			if err != nil {
+				return err
+			}
+		}
+
+		user, err = tx.UserRetrieve(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		activationPlaintext = ""
+		if emailChanged && user.EmailVerified == nil {
+			activationPlaintext, activationToken, err = app.newScopedToken(r.Context(), tx, id, store.ScopeActivation, app.config.activation.tokenTTL)
+		}
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserExists):
+			input.Validator.AddFieldError("email", "Email is already in use")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if activationPlaintext != "" {
+		app.mailActivationToken(r, user, activationPlaintext, activationToken)
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": user})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	permitted, err := app.canManageUser(r, id, store.PermissionUsersWrite)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !permitted {
+		app.notPermitted(w, r)
+		return
+	}
+
+	err = app.auditStore(r).UserDelete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) retrieveMe(w http.ResponseWriter, r *http.Request) {
+	err := response.JSON(w, http.StatusOK, map[string]interface{}{"Data": contextGetAuthenticatedUser(r)})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) updateMe(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     *string             `json:"email"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	if input.Email != nil {
+		*input.Email = normalizeEmail(*input.Email)
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	emailChanged := input.Email != nil && *input.Email != user.Email
+	if emailChanged {
+		existingUser, err := app.store.UserRetrieveByEmail(r.Context(), *input.Email)
+		if err != nil && !errors.Is(err, store.ErrUserNotFound) {
+			app.serverError(w, r, err)
+			return
+		}
+
+		checkEmail(&input.Validator, *input.Email)
+		input.Validator.CheckField(existingUser == nil || existingUser.ID == user.ID, "email", "Email is already in use")
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	// As in updateUser, the new email address and the token for verifying
+	// it are saved together.
+	id := user.ID
+	var activationPlaintext string
+	var activationToken store.Token
+	err = app.auditStore(r).WithinTx(r.Context(), store.TxOptions{}, func(tx store.Tx) error {
+		if emailChanged {
+			err := tx.UserUpdateEmail(r.Context(), id, *input.Email)
+			if err != nil {
+				return err
+			}
+		}
+
+		user, err = tx.UserRetrieve(r.Context(), id)
+		if err != nil {
+			return err
+		}
+
+		activationPlaintext = ""
+		if emailChanged && user.EmailVerified == nil {
+			activationPlaintext, activationToken, err = app.newScopedToken(r.Context(), tx, id, store.ScopeActivation, app.config.activation.tokenTTL)
+		}
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserExists):
+			input.Validator.AddFieldError("email", "Email is already in use")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if activationPlaintext != "" {
+		app.mailActivationToken(r, user, activationPlaintext, activationToken)
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": user})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) changeMyPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		CurrentPassword string              `json:"current_password"`
+		NewPassword     string              `json:"new_password"`
+		Validator       validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	input.Validator.CheckField(input.CurrentPassword != "", "current_password", "Current password is required")
+	if input.CurrentPassword != "" {
+		passwordMatches, err := password.Matches(input.CurrentPassword, user.HashedPassword)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		input.Validator.CheckField(passwordMatches, "current_password", "Current password is incorrect")
+	}
+
+	checkPassword(&input.Validator, "new_password", input.NewPassword)
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	hashedPassword, err := password.Hash(input.NewPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.auditStore(r).UserUpdatePassword(r.Context(), user.ID, hashedPassword)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user, err = app.store.UserRetrieve(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.writeLogin(w, r, user, contextGetSession(r) != nil)
+}
+
+func (app *application) createPasswordResetToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string              `json:"email"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Email = normalizeEmail(input.Email)
+
+	checkEmail(&input.Validator, input.Email)
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	data := map[string]string{
+		"Message": "If an account exists for that email address, a password reset link has been sent to it",
+	}
+
+	user, err := app.store.UserRetrieveByEmail(r.Context(), input.Email)
+	if err != nil {
+		if !errors.Is(err, store.ErrUserNotFound) {
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = response.JSON(w, http.StatusAccepted, data)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	plaintext, resetToken, err := app.newScopedToken(r.Context(), app.store, user.ID, store.ScopePasswordReset, app.config.passwordReset.tokenTTL)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.backgroundTask(r, func() error {
+		emailData := app.newEmailData()
+		emailData["Token"] = plaintext
+		emailData["Expiry"] = resetToken.Expiry.Format(time.RFC1123)
+
+		return app.mailer.Send(user.Email, emailData, "password-reset.tmpl")
+	})
+
+	err = response.JSON(w, http.StatusAccepted, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createMagicLink(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string              `json:"email"`
+		Session   bool                `json:"session"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Email = normalizeEmail(input.Email)
+
+	checkEmail(&input.Validator, input.Email)
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	// The nonce cookie is set whether or not the account exists, so the
+	// response doesn't reveal which email addresses are registered.
+	nonce, err := app.setMagicLinkNonceCookie(w)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]string{
+		"Message": "If an account exists for that email address, a sign-in link has been sent to it",
+	}
+
+	user, err := app.store.UserRetrieveByEmail(r.Context(), input.Email)
+	if err != nil {
+		if !errors.Is(err, store.ErrUserNotFound) {
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = response.JSON(w, http.StatusAccepted, data)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.recordMagicLink(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if allowed {
+		plaintext, magicLink, err := app.newMagicLink(r.Context(), user.ID, nonce)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		app.backgroundTask(r, func() error {
+			emailData := app.newEmailData()
+			emailData["URL"] = app.magicLinkURL(plaintext, input.Session)
+			emailData["Expiry"] = magicLink.Expiry.Format(time.RFC1123)
+
+			return app.mailer.Send(user.Email, emailData, "magic-link.tmpl")
+		})
+	}
+
+	err = response.JSON(w, http.StatusAccepted, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) exchangeMagicLink(w http.ResponseWriter, r *http.Request) {
+	plaintext := r.URL.Query().Get("token")
+
+	nonce, err := r.Cookie(magicLinkNonceCookie)
+	if plaintext == "" || err != nil {
+		app.invalidMagicLink(w, r)
+		return
+	}
+
+	userID, err := app.store.TokenConsume(r.Context(), store.ScopeMagicLink, magicLinkHash(plaintext, nonce.Value))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTokenNotFound):
+			app.invalidMagicLink(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	clearMagicLinkNonceCookie(w)
+
+	user, err := app.store.UserRetrieve(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.invalidMagicLink(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Following the link proves the user controls the email address.
+	if user.EmailVerified == nil {
+		err = app.auditStore(r).UserVerifyEmail(r.Context(), user.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		user, err = app.store.UserRetrieve(r.Context(), user.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.continueLogin(w, r, user, r.URL.Query().Get("session") == "true")
+}
+
+func (app *application) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token     string              `json:"token"`
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Token != "", "token", "Token is required")
+	checkPassword(&input.Validator, "password", input.Password)
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	userID, err := app.store.TokenConsume(r.Context(), store.ScopePasswordReset, token.Hash(input.Token))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTokenNotFound):
+			input.Validator.AddFieldError("token", "Invalid or expired password reset token")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	hashedPassword, err := password.Hash(input.Password)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.auditStore(r).UserUpdatePassword(r.Context(), userID, hashedPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			input.Validator.AddFieldError("token", "Invalid or expired password reset token")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.store.TokenDeleteAllForUser(r.Context(), store.ScopePasswordReset, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]string{
+		"Message": "Your password was successfully reset",
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) activateUser(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token     string              `json:"token"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Token != "", "token", "Token is required")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	userID, err := app.store.TokenConsume(r.Context(), store.ScopeActivation, token.Hash(input.Token))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTokenNotFound):
+			input.Validator.AddFieldError("token", "Invalid or expired activation token")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.auditStore(r).UserVerifyEmail(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			input.Validator.AddFieldError("token", "Invalid or expired activation token")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.store.TokenDeleteAllForUser(r.Context(), store.ScopeActivation, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": user})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createActivationToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email     string              `json:"email"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Email = normalizeEmail(input.Email)
+
+	checkEmail(&input.Validator, input.Email)
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	data := map[string]string{
+		"Message": "If an unverified account exists for that email address, an activation link has been sent to it",
+	}
+
+	user, err := app.store.UserRetrieveByEmail(r.Context(), input.Email)
+	switch {
+	case errors.Is(err, store.ErrUserNotFound):
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	case user.EmailVerified == nil:
+		recent, err := app.store.TokenCountSince(r.Context(), store.ScopeActivation, user.ID, app.now().Add(-app.config.activation.resendInterval))
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		// Resends are throttled silently so the response doesn't reveal
+		// whether the account exists.
+		if recent == 0 {
+			err = app.sendActivationEmail(r, user)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+		}
+	}
+
+	err = response.JSON(w, http.StatusAccepted, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) enrollTOTP(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+
+	enabled, err := app.totpEnabled(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if enabled {
+		app.errorMessage(w, r, http.StatusConflict, "TOTP is already enabled for this account", nil)
+		return
+	}
+
+	key, encryptedSecret, err := app.newTOTPKey(user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	qrCode, err := qrCodePNG(key)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.store.TOTPEnroll(r.Context(), user.ID, encryptedSecret)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]string{
+		"secret":  key.Secret(),
+		"url":     key.URL(),
+		"qr_code": qrCode,
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": data})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) confirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code      string              `json:"code"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Code != "", "code", "Code is required")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	userTOTP, err := app.store.TOTPRetrieve(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, store.ErrTOTPNotFound) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if userTOTP == nil || userTOTP.Confirmed != nil {
+		app.errorMessage(w, r, http.StatusConflict, "There is no pending TOTP enrollment for this account", nil)
+		return
+	}
+
+	valid, err := app.validateTOTPCode(userTOTP, input.Code)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !valid {
+		input.Validator.AddFieldError("code", "Invalid authentication code")
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.store.TOTPConfirm(r.Context(), user.ID, hashes)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTOTPNotFound):
+			app.errorMessage(w, r, http.StatusConflict, "There is no pending TOTP enrollment for this account", nil)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": map[string]any{"recovery_codes": codes}})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	input.Validator.CheckField(input.Password != "", "password", "Password is required")
+	if input.Password != "" {
+		passwordMatches, err := password.Matches(input.Password, user.HashedPassword)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		input.Validator.CheckField(passwordMatches, "password", "Password is incorrect")
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	err = app.store.TOTPDelete(r.Context(), user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrTOTPNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) listAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+
+	apiKeys, err := app.store.APIKeyList(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": apiKeys})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createAPIKey(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string              `json:"name"`
+		Scopes    []string            `json:"scopes"`
+		Expires   *time.Time          `json:"expires"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+
+	permissions, err := app.store.UserPermissions(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Name != "", "name", "Name is required")
+	input.Validator.CheckField(len(input.Name) <= 100, "name", "Name is too long")
+	for _, scope := range input.Scopes {
+		input.Validator.CheckField(validator.In(scope, permissions...), "scopes", fmt.Sprintf("You can't grant the %q scope", scope))
+	}
+	if input.Expires != nil {
+		input.Validator.CheckField(input.Expires.After(app.now()), "expires", "Expiry must be in the future")
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	plaintext, prefix, hash, err := newAPIKey()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	apiKey, err := app.store.APIKeyInsert(r.Context(), store.APIKey{
+		ID:      uuid.New(),
+		UserID:  user.ID,
+		Name:    input.Name,
+		Prefix:  prefix,
+		Hash:    hash,
+		Scopes:  input.Scopes,
+		Expires: input.Expires,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// The plaintext key is only ever returned here.
+	data := struct {
+		*store.APIKey
+		Key string `json:"key"`
+	}{apiKey, plaintext}
+
+	err = response.JSON(w, http.StatusCreated, map[string]interface{}{"Data": data})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) retrieveAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	apiKey, err := app.store.APIKeyRetrieve(r.Context(), contextGetAuthenticatedUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAPIKeyNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": apiKey})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) updateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var input struct {
+		Name      string              `json:"name"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err = request.DecodeJSONStrict(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Name != "", "name", "Name is required")
+	input.Validator.CheckField(len(input.Name) <= 100, "name", "Name is too long")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	apiKey, err := app.store.APIKeyUpdateName(r.Context(), contextGetAuthenticatedUser(r).ID, id, input.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAPIKeyNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": apiKey})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.store.APIKeyDelete(r.Context(), contextGetAuthenticatedUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAPIKeyNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) listSessions(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+	current := contextGetSession(r)
+
+	sessions, err := app.store.SessionList(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	type sessionData struct {
+		store.Session
+		Current bool `json:"current"`
+	}
+
+	data := make([]sessionData, 0, len(sessions))
+	for _, session := range sessions {
+		data = append(data, sessionData{
+			Session: session,
+			Current: current != nil && current.ID == session.ID,
+		})
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": data})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteSession(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.store.SessionDelete(r.Context(), contextGetAuthenticatedUser(r).ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrSessionNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if current := contextGetSession(r); current != nil && current.ID == id {
+		clearSessionCookies(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) listRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := app.store.RoleList(r.Context())
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": roles})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listUserRoles(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	permitted, err := app.canManageUser(r, id, store.PermissionUsersRead)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !permitted {
+		app.notPermitted(w, r)
+		return
+	}
+
+	roles, err := app.store.UserRoles(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": roles})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) grantUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.auditStore(r).UserRoleGrant(r.Context(), id, chi.URLParam(r, "role"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound), errors.Is(err, store.ErrRoleNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) revokeUserRole(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.auditStore(r).UserRoleRevoke(r.Context(), id, chi.URLParam(r, "role"))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) unlockUser(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.store.LoginThrottleReset(r.Context(), store.LoginThrottleAccount, emailaddr.Key(user.Email))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) updateUserStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var input struct {
+		Status    string              `json:"status"`
+		Reason    string              `json:"reason"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err = request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(validator.In(input.Status, store.UserStatusActive, store.UserStatusSuspended, store.UserStatusDeactivated), "status", "Status must be active, suspended or deactivated")
+	input.Validator.CheckField(validator.MaxRunes(input.Reason, 500), "reason", "Reason must not be more than 500 characters")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	if contextGetAuthenticatedUser(r).ID == id {
+		app.badRequest(w, r, errors.New("you can't change your own status"))
+		return
+	}
+
+	err = app.auditStore(r).UserUpdateStatus(r.Context(), id, input.Status, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": user})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) restoreUser(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	err = app.auditStore(r).UserRestore(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": user})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) impersonateUser(w http.ResponseWriter, r *http.Request) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	actor := contextGetAuthenticatedUser(r)
+	if actor.ID == id {
+		app.badRequest(w, r, errors.New("you can't impersonate yourself"))
+		return
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if user.Status != store.UserStatusActive {
+		app.badRequest(w, r, fmt.Errorf("you can't impersonate a %s user", user.Status))
+		return
+	}
+
+	accessToken, accessTokenExpiry, err := app.newImpersonationToken(actor, user)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = app.auditStore(r).AuditRecord(r.Context(), store.AuditUserImpersonate, user.ID, map[string]store.AuditChange{
+		"expires": {New: accessTokenExpiry.Format(time.RFC3339)},
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]any{
+		"AuthenticationToken":       accessToken,
+		"AuthenticationTokenExpiry": accessTokenExpiry.Format(time.RFC3339),
+		"Data":                      user,
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listAuditEvents(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		PageSize   string
+		PageNumber string
+		Actor      string
+		Target     string
+		Since      string
+		Until      string
+		Validator  validator.Validator
+	}
+
+	params := store.AuditEventListParams{
+		Action: r.URL.Query().Get("action"),
+	}
+
+	input.PageSize = r.URL.Query().Get("pageSize")
+	input.PageNumber = r.URL.Query().Get("pageNumber")
+	input.Actor = r.URL.Query().Get("actor")
+	input.Target = r.URL.Query().Get("target")
+	input.Since = r.URL.Query().Get("since")
+	input.Until = r.URL.Query().Get("until")
+
+	if input.PageNumber == "" {
+		input.PageNumber = DefaultPageNumber
+	}
+
+	if input.PageSize == "" {
+		input.PageSize = DefaultPageSize
+	}
+
+	var pageSizeErr, pageNumberErr error
+	params.PageSize, pageSizeErr = strconv.Atoi(input.PageSize)
+	params.PageNumber, pageNumberErr = strconv.Atoi(input.PageNumber)
+
+	input.Validator.CheckField(pageSizeErr == nil, "pageSize", "pageSize must be a positive integer")
+	input.Validator.CheckField(pageNumberErr == nil, "pageNumber", "pageNumber must be a positive integer")
+	input.Validator.CheckField(params.PageSize > 0, "pageSize", "pageSize must be a positive integer")
+	input.Validator.CheckField(params.PageNumber > 0, "pageNumber", "pageNumber must be a positive integer")
+
+	if input.Actor != "" {
+		actorID, err := uuid.Parse(input.Actor)
+		input.Validator.CheckField(err == nil, "actor", "actor must be a valid UUID")
+		params.ActorID = &actorID
+	}
+
+	if input.Target != "" {
+		targetID, err := uuid.Parse(input.Target)
+		input.Validator.CheckField(err == nil, "target", "target must be a valid UUID")
+		params.TargetID = &targetID
+	}
+
+	if input.Since != "" {
+		since, err := time.Parse(time.RFC3339, input.Since)
+		input.Validator.CheckField(err == nil, "since", "since must be an RFC 3339 timestamp")
+		params.Since = &since
+	}
+
+	if input.Until != "" {
+		until, err := time.Parse(time.RFC3339, input.Until)
+		input.Validator.CheckField(err == nil, "until", "until must be an RFC 3339 timestamp")
+		params.Until = &until
+	}
+
+	if params.Since != nil && params.Until != nil {
+		input.Validator.CheckField(!params.Until.Before(*params.Since), "until", "until must not be before since")
+	}
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	events, err := app.store.AuditEventList(r.Context(), params)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, events)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createOrganization(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string              `json:"name"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Name != "", "name", "Name is required")
+	input.Validator.CheckField(len(input.Name) <= 100, "name", "Name is too long")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+	organization, err := app.auditStore(r).OrganizationInsert(r.Context(), store.Organization{ID: uuid.New(), Name: input.Name}, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusCreated, map[string]interface{}{"Data": organization})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listOrganizations(w http.ResponseWriter, r *http.Request) {
+	user := contextGetAuthenticatedUser(r)
+
+	organizations, err := app.store.OrganizationListForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": organizations})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// readOrganization returns the organization named by the id URL parameter and
+// the authenticated user's role in it. It writes an error response and
+// returns nil if the organization doesn't exist or the user can't see it.
+func (app *application) readOrganization(w http.ResponseWriter, r *http.Request) (*store.Organization, string) {
+	id, err := readUUIDParam(r, "id")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return nil, ""
+	}
+
+	role, err := app.organizationRole(r, id)
+	if err != nil {
+		app.serverError(w, r, err)
+		return nil, ""
+	}
+
+	if role == "" {
+		permitted, err := app.userHasGlobalPermission(r, store.PermissionUsersRead)
+		if err != nil {
+			app.serverError(w, r, err)
+			return nil, ""
+		}
+
+		if !permitted {
+			app.notFound(w, r)
+			return nil, ""
+		}
+	}
+
+	organization, err := app.store.OrganizationRetrieve(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrOrganizationNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return nil, ""
+	}
+
+	return organization, role
+}
+
+func (app *application) retrieveOrganization(w http.ResponseWriter, r *http.Request) {
+	organization, _ := app.readOrganization(w, r)
+	if organization == nil {
+		return
+	}
+
+	err := response.JSON(w, http.StatusOK, map[string]interface{}{"Data": organization})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listMembers(w http.ResponseWriter, r *http.Request) {
+	organization, _ := app.readOrganization(w, r)
+	if organization == nil {
+		return
+	}
+
+	memberships, err := app.store.MembershipList(r.Context(), organization.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": memberships})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) updateMember(w http.ResponseWriter, r *http.Request) {
+	organization, role := app.readOrganization(w, r)
+	if organization == nil {
+		return
+	}
+
+	userID, err := readUUIDParam(r, "userID")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	var input struct {
+		Role      string              `json:"role"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err = request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(validator.In(input.Role, store.OrganizationRoleOwner, store.OrganizationRoleAdmin, store.OrganizationRoleMember), "role", "Role must be owner, admin or member")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	var currentRole string
+	current, err := app.store.MembershipRetrieve(r.Context(), organization.ID, userID)
+	switch {
+	case err == nil:
+		currentRole = current.Role
+	case !errors.Is(err, store.ErrMembershipNotFound):
+		app.serverError(w, r, err)
+		return
+	}
+
+	if !canManageMember(role, currentRole, input.Role) {
+		app.notPermitted(w, r)
+		return
+	}
+
+	// Only existing members' roles can be changed. Users join by accepting an
+	// invitation, so they can't be added to an organization without consent.
+	if current == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	if currentRole == store.OrganizationRoleOwner && input.Role != store.OrganizationRoleOwner {
+		lastOwner, err := app.isLastOwner(r.Context(), organization.ID, userID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if lastOwner {
+			input.Validator.AddFieldError("role", "The organization's last owner can't be demoted")
+			app.failedValidation(w, r, input.Validator)
+			return
+		}
+	}
+
+	membership, err := app.auditStore(r).MembershipUpsert(r.Context(), organization.ID, userID, input.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": membership})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteMember(w http.ResponseWriter, r *http.Request) {
+	organization, role := app.readOrganization(w, r)
+	if organization == nil {
+		return
+	}
+
+	userID, err := readUUIDParam(r, "userID")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	current, err := app.store.MembershipRetrieve(r.Context(), organization.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrMembershipNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	leaving := contextGetAuthenticatedUser(r).ID == userID
+	if !leaving && !canManageMember(role, current.Role, "") {
+		app.notPermitted(w, r)
+		return
+	}
+
+	lastOwner, err := app.isLastOwner(r.Context(), organization.ID, userID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if lastOwner {
+		app.errorMessage(w, r, http.StatusConflict, "The organization's last owner can't be removed", nil)
+		return
+	}
+
+	err = app.auditStore(r).MembershipDelete(r.Context(), organization.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrMembershipNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) createOrganizationToken(w http.ResponseWriter, r *http.Request) {
+	organization, _ := app.readOrganization(w, r)
+	if organization == nil {
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+	membership, err := app.store.MembershipRetrieve(r.Context(), organization.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrMembershipNotFound):
+			app.notOrganizationMember(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	accessToken, accessTokenExpiry, err := app.newOrganizationToken(user, organization)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := map[string]any{
+		"AuthenticationToken":       accessToken,
+		"AuthenticationTokenExpiry": accessTokenExpiry.Format(time.RFC3339),
+		"Role":                      membership.Role,
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) createInvitation(w http.ResponseWriter, r *http.Request) {
+	organization, role := app.readOrganization(w, r)
+	if organization == nil {
+		return
+	}
+
+	var input struct {
+		Email     string              `json:"email"`
+		Role      string              `json:"role"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Email = normalizeEmail(input.Email)
+
+	if input.Role == "" {
+		input.Role = store.OrganizationRoleMember
+	}
+
+	checkEmail(&input.Validator, input.Email)
+	input.Validator.CheckField(validator.In(input.Role, store.OrganizationRoleOwner, store.OrganizationRoleAdmin, store.OrganizationRoleMember), "role", "Role must be owner, admin or member")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	if !canManageMember(role, "", input.Role) {
+		app.notPermitted(w, r)
+		return
+	}
+
+	existingUser, err := app.store.UserRetrieveByEmail(r.Context(), input.Email)
+	switch {
+	case err == nil:
+		_, err = app.store.MembershipRetrieve(r.Context(), organization.ID, existingUser.ID)
+		switch {
+		case err == nil:
+			input.Validator.AddFieldError("email", "User is already a member")
+			app.failedValidation(w, r, input.Validator)
+			return
+		case !errors.Is(err, store.ErrMembershipNotFound):
+			app.serverError(w, r, err)
+			return
+		}
+	case !errors.Is(err, store.ErrUserNotFound):
+		app.serverError(w, r, err)
+		return
+	}
+
+	plaintext, hash, err := token.Generate()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	user := contextGetAuthenticatedUser(r)
+	invitation, err := app.store.InvitationInsert(r.Context(), store.Invitation{
+		ID:             uuid.New(),
+		OrganizationID: organization.ID,
+		Email:          input.Email,
+		Role:           input.Role,
+		Hash:           hash,
+		InvitedBy:      &user.ID,
+		Expires:        app.now().Add(app.config.invitations.tokenTTL),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvitationExists):
+			input.Validator.AddFieldError("email", "Email has already been invited")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sendInvitationEmail(r, organization, invitation, plaintext)
+
+	err = response.JSON(w, http.StatusCreated, map[string]interface{}{"Data": invitation})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) listInvitations(w http.ResponseWriter, r *http.Request) {
+	organization, role := app.readOrganization(w, r)
+	if organization == nil {
+		return
+	}
+
+	if !canManageInvitations(role) {
+		app.notPermitted(w, r)
+		return
+	}
+
+	invitations, err := app.store.InvitationList(r.Context(), organization.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": invitations})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// readInvitation returns the invitation named by the invitationID URL
+// parameter, if the authenticated user can manage it. It writes an error
+// response and returns nil otherwise.
+func (app *application) readInvitation(w http.ResponseWriter, r *http.Request) (*store.Organization, *store.Invitation) {
+	organization, role := app.readOrganization(w, r)
+	if organization == nil {
+		return nil, nil
+	}
+
+	if !canManageInvitations(role) {
+		app.notPermitted(w, r)
+		return nil, nil
+	}
+
+	id, err := readUUIDParam(r, "invitationID")
+	if err != nil {
+		app.badRequest(w, r, err)
+		return nil, nil
+	}
+
+	invitation, err := app.store.InvitationRetrieve(r.Context(), organization.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvitationNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return nil, nil
+	}
+
+	if !canManageMember(role, "", invitation.Role) {
+		app.notPermitted(w, r)
+		return nil, nil
+	}
+
+	return organization, invitation
+}
+
+func (app *application) resendInvitation(w http.ResponseWriter, r *http.Request) {
+	organization, invitation := app.readInvitation(w, r)
+	if invitation == nil {
+		return
+	}
+
+	plaintext, hash, err := token.Generate()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	invitation, err = app.store.InvitationRenew(r.Context(), organization.ID, invitation.ID, hash, app.now().Add(app.config.invitations.tokenTTL))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvitationNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sendInvitationEmail(r, organization, invitation, plaintext)
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": invitation})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+func (app *application) deleteInvitation(w http.ResponseWriter, r *http.Request) {
+	organization, invitation := app.readInvitation(w, r)
+	if invitation == nil {
+		return
+	}
+
+	err := app.store.InvitationDelete(r.Context(), organization.ID, invitation.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvitationNotFound):
+			app.notFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (app *application) acceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token     string              `json:"token"`
+		Password  string              `json:"password"`
+		Validator validator.Validator `json:"-"`
+	}
+
+	err := request.DecodeJSON(w, r, &input)
+	if err != nil {
+		app.badRequest(w, r, err)
+		return
+	}
+
+	input.Validator.CheckField(input.Token != "", "token", "Token is required")
+
+	if input.Validator.HasErrors() {
+		app.failedValidation(w, r, input.Validator)
+		return
+	}
+
+	hash := token.Hash(input.Token)
+
+	invitation, err := app.store.InvitationRetrieveByHash(r.Context(), hash)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvitationNotFound):
+			input.Validator.AddFieldError("token", "Invalid or expired invitation token")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	var hashedPassword string
+	_, err = app.store.UserRetrieveByEmail(r.Context(), invitation.Email)
+	switch {
+	case errors.Is(err, store.ErrUserNotFound):
+		checkPassword(&input.Validator, "password", input.Password)
+
+		if input.Validator.HasErrors() {
+			app.failedValidation(w, r, input.Validator)
+			return
+		}
+
+		hashedPassword, err = password.Hash(input.Password)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	case err != nil:
+		app.serverError(w, r, err)
+		return
+	}
+
+	membership, err := app.auditStore(r).InvitationAccept(r.Context(), hash, uuid.New(), hashedPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrInvitationNotFound):
+			input.Validator.AddFieldError("token", "Invalid or expired invitation token")
+			app.failedValidation(w, r, input.Validator)
+		case errors.Is(err, store.ErrUserExists):
+			input.Validator.AddFieldError("token", "The invited email address belongs to a deleted account")
+			app.failedValidation(w, r, input.Validator)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = response.JSON(w, http.StatusOK, map[string]interface{}{"Data": membership})
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
@@ -2,17 +2,32 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/assets"
+	"github.com/mrityunjaygr8/autostrada-test/internal/funcs"
+	memstore "github.com/mrityunjaygr8/autostrada-test/internal/memory/store"
+	"github.com/mrityunjaygr8/autostrada-test/internal/password"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
 	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/require"
+	"image/png"
+	"io"
+	"log/slog"
 	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	textTemplate "text/template"
 )
 
 type resp struct {
@@ -33,9 +48,10 @@ type createUserProps struct {
 }
 
 func TestCreateUser(t *testing.T) {
-	stubStore := NewStubStore()
+	memStore := memstore.NewMemoryStore()
 	app := &application{
-		store: &stubStore,
+		store:  memStore,
+		mailer: &fakeMailer{},
 	}
 	t.Run("TestCreateUser happy path", func(t *testing.T) {
 		data := createUserProps{
@@ -127,6 +143,44 @@ func TestCreateUser(t *testing.T) {
 		require.Nil(t, err)
 		require.Equal(t, "Email is already in use", res.FieldErrors["email"])
 	})
+	t.Run("TestCreateUser email differing only by case", func(t *testing.T) {
+		data := createUserProps{
+			Email:    " MSYT@Gmail.COM ",
+			Password: "qweqweqwe",
+			Admin:    false,
+		}
+		bData, err := json.Marshal(data)
+		require.Nil(t, err)
+		request := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(bData))
+		response := httptest.NewRecorder()
+
+		app.createUser(response, request)
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Email is already in use", res.FieldErrors["email"])
+	})
+	t.Run("TestCreateUser normalizes email", func(t *testing.T) {
+		data := createUserProps{
+			Email:    " Jane.Doe@Bücher.Example. ",
+			Password: "qweqweqwe",
+			Admin:    false,
+		}
+		bData, err := json.Marshal(data)
+		require.Nil(t, err)
+		request := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(bData))
+		response := httptest.NewRecorder()
+
+		app.createUser(response, request)
+
+		require.Equal(t, http.StatusCreated, response.Code)
+		var res resp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Jane.Doe@xn--bcher-kva.example", res.Data["email"])
+	})
 
 	t.Run("CreateUser bad JSON", func(t *testing.T) {
 		request := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"asd"`))
@@ -146,9 +200,10 @@ func TestCreateUser(t *testing.T) {
 
 func TestListUsers(t *testing.T) {
 	t.Run("ListUsers basic case", func(t *testing.T) {
-		stubStore := NewStubStore()
+		memStore := memstore.NewMemoryStore()
 		app := &application{
-			store: &stubStore,
+			store:  memStore,
+			mailer: &fakeMailer{},
 		}
 		for x := 0; x < 34; x++ {
 			data := createUserProps{
@@ -180,9 +235,10 @@ func TestListUsers(t *testing.T) {
 		require.Equal(t, 34, res.TotalObjects)
 	})
 	t.Run("ListUsers query params", func(t *testing.T) {
-		stubStore := NewStubStore()
+		memStore := memstore.NewMemoryStore()
 		app := &application{
-			store: &stubStore,
+			store:  memStore,
+			mailer: &fakeMailer{},
 		}
 		for x := 0; x < 4; x++ {
 			data := createUserProps{
@@ -217,9 +273,10 @@ func TestListUsers(t *testing.T) {
 		require.Equal(t, 2, len(res.Data))
 	})
 	t.Run("ListUsers query params bad page size", func(t *testing.T) {
-		stubStore := NewStubStore()
+		memStore := memstore.NewMemoryStore()
 		app := &application{
-			store: &stubStore,
+			store:  memStore,
+			mailer: &fakeMailer{},
 		}
 		request := httptest.NewRequest(http.MethodGet, "/users?pageSize=qwe", nil)
 		response := httptest.NewRecorder()
@@ -236,9 +293,10 @@ func TestListUsers(t *testing.T) {
 		require.Equal(t, out, response.Body.String())
 	})
 	t.Run("ListUsers query params bad page number", func(t *testing.T) {
-		stubStore := NewStubStore()
+		memStore := memstore.NewMemoryStore()
 		app := &application{
-			store: &stubStore,
+			store:  memStore,
+			mailer: &fakeMailer{},
 		}
 		request := httptest.NewRequest(http.MethodGet, "/users?pageNumber=-123", nil)
 		response := httptest.NewRecorder()
@@ -258,9 +316,10 @@ func TestListUsers(t *testing.T) {
 }
 
 func TestStatus(t *testing.T) {
-	stubStore := NewStubStore()
+	memStore := memstore.NewMemoryStore()
 	app := &application{
-		store: &stubStore,
+		store:  memStore,
+		mailer: &fakeMailer{},
 	}
 
 	t.Run("Status check", func(t *testing.T) {
@@ -279,70 +338,2832 @@ func TestStatus(t *testing.T) {
 	})
 }
 
-type StubStore struct {
-	userStore []store.User
+type authenticationTokenProps struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
 }
 
-func NewStubStore() StubStore {
-	userStore := make([]store.User, 0)
-	return StubStore{userStore}
+type authenticationTokenResp struct {
+	AuthenticationToken       string
+	AuthenticationTokenExpiry string
+	RefreshToken              string
+	RefreshTokenExpiry        string
 }
 
-func (s *StubStore) UserInsert(email, password string, id uuid.UUID, admin bool) (*store.User, error) {
-	u := store.User{
-		Email:          email,
-		ID:             id,
-		Admin:          admin,
-		Created:        time.Now(),
-		HashedPassword: password,
+func newAuthTestApplication() (*application, *memstore.MemoryStore) {
+	memStore := memstore.NewMemoryStore()
+	app := &application{
+		store:  memStore,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		mailer: &fakeMailer{},
 	}
-	s.userStore = append(s.userStore, u)
-	return &u, nil
+	app.config.baseURL = "http://localhost:4444"
+	app.config.jwt.secretKey = "xl3e7tqjfreubzdnjlomzqr7q6x6sfni"
+	app.config.jwt.accessTokenTTL = time.Hour
+	app.config.jwt.refreshTokenTTL = 24 * time.Hour
+	app.config.jwt.leeway = time.Minute
+	app.config.passwordReset.tokenTTL = 45 * time.Minute
+	app.config.activation.tokenTTL = 72 * time.Hour
+	app.config.activation.resendInterval = 5 * time.Minute
+	app.config.mfa.issuer = "Guzei"
+	app.config.mfa.encryptionKey = "7ncm2c5oa4ufdvoqbyxgbjr3ezmvoswh"
+	app.config.mfa.challengeTTL = 5 * time.Minute
+	app.config.magicLink.tokenTTL = 15 * time.Minute
+	app.config.magicLink.url = app.config.baseURL + "/magic-links/exchange"
+	app.config.magicLink.rateLimit = 3
+	app.config.magicLink.rateInterval = time.Hour
+	app.config.sessions.ttl = 7 * 24 * time.Hour
+	app.config.impersonation.tokenTTL = 15 * time.Minute
+	app.config.lockout.accountThreshold = 5
+	app.config.lockout.ipThreshold = 20
+	app.config.lockout.baseDelay = time.Minute
+	app.config.lockout.maxDelay = time.Hour
+	app.config.lockout.failureWindow = 24 * time.Hour
+	return app, memStore
 }
 
-func (s *StubStore) UserList(userListParams store.UserListParams) (*store.UsersList, error) {
-	users := make([]store.User, 0)
-	count := len(s.userStore)
-	start := userListParams.PageSize * (userListParams.PageNumber - 1)
-	for _, val := range s.userStore[start : start+userListParams.PageSize] {
-		users = append(users, val)
-	}
-	res := store.UsersList{
-		Data:         users,
-		TotalObjects: count,
-		TotalPages:   int(math.Ceil(float64(count) / float64(userListParams.PageSize))),
-		Page:         userListParams.PageNumber,
-		PageSize:     userListParams.PageSize,
-	}
+func createTestUser(t *testing.T, app *application, email, password string) {
+	bData, err := json.Marshal(createUserProps{Email: email, Password: password})
+	require.Nil(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(bData))
+	response := httptest.NewRecorder()
+
+	app.createUser(response, request)
+
+	require.Equal(t, http.StatusCreated, response.Code)
+}
+
+func TestCreateAuthenticationToken(t *testing.T) {
+	app, _ := newAuthTestApplication()
+	createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+	t.Run("CreateAuthenticationToken happy path", func(t *testing.T) {
+		bData, err := json.Marshal(authenticationTokenProps{Email: "msyt@gmail.com", Password: "qweqweqwe"})
+		require.Nil(t, err)
+		request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", bytes.NewReader(bData))
+		response := httptest.NewRecorder()
+
+		app.createAuthenticationToken(response, request)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res authenticationTokenResp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotEmpty(t, res.AuthenticationToken)
+
+		expiry, err := time.Parse(time.RFC3339, res.AuthenticationTokenExpiry)
+		require.Nil(t, err)
+		require.WithinDuration(t, time.Now().Add(time.Hour), expiry, 5*time.Second)
+
+		claims, err := app.checkAuthenticationToken(res.AuthenticationToken)
+		require.Nil(t, err)
+		user, err := app.store.UserRetrieveByEmail(context.Background(), "msyt@gmail.com")
+		require.Nil(t, err)
+		require.Equal(t, user.ID.String(), claims.Subject)
+		require.Equal(t, app.config.baseURL, claims.Issuer)
+		require.True(t, claims.AcceptAudience(app.config.baseURL))
+
+		require.NotEmpty(t, res.RefreshToken)
+		refreshToken, err := app.store.RefreshTokenRetrieve(context.Background(), token.Hash(res.RefreshToken))
+		require.Nil(t, err)
+		require.Equal(t, user.ID, refreshToken.UserID)
+	})
+
+	t.Run("CreateAuthenticationToken email in different case", func(t *testing.T) {
+		bData, err := json.Marshal(authenticationTokenProps{Email: "MSYT@GMAIL.COM", Password: "qweqweqwe"})
+		require.Nil(t, err)
+		request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", bytes.NewReader(bData))
+		response := httptest.NewRecorder()
+
+		app.createAuthenticationToken(response, request)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("CreateAuthenticationToken wrong password", func(t *testing.T) {
+		bData, err := json.Marshal(authenticationTokenProps{Email: "msyt@gmail.com", Password: "asdasdasd"})
+		require.Nil(t, err)
+		request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", bytes.NewReader(bData))
+		response := httptest.NewRecorder()
+
+		app.createAuthenticationToken(response, request)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		var res resp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Invalid authentication credentials", res.Errors)
+	})
+
+	t.Run("CreateAuthenticationToken unknown email", func(t *testing.T) {
+		bData, err := json.Marshal(authenticationTokenProps{Email: "nobody@gmail.com", Password: "qweqweqwe"})
+		require.Nil(t, err)
+		request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", bytes.NewReader(bData))
+		response := httptest.NewRecorder()
+
+		app.createAuthenticationToken(response, request)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		var res resp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Invalid authentication credentials", res.Errors)
+	})
+
+	t.Run("CreateAuthenticationToken missing fields", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", strings.NewReader(`{}`))
+		response := httptest.NewRecorder()
+
+		app.createAuthenticationToken(response, request)
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Email is required", res.FieldErrors["email"])
+		require.Equal(t, "Password is required", res.FieldErrors["password"])
+	})
+
+	t.Run("CreateAuthenticationToken bad JSON", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", strings.NewReader(`{"asd"`))
+		response := httptest.NewRecorder()
+
+		app.createAuthenticationToken(response, request)
+
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func loginTestUser(t *testing.T, app *application, email, password string) authenticationTokenResp {
+	bData, err := json.Marshal(authenticationTokenProps{Email: email, Password: password})
+	require.Nil(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", bytes.NewReader(bData))
+	response := httptest.NewRecorder()
+
+	app.createAuthenticationToken(response, request)
+
+	require.Equal(t, http.StatusOK, response.Code)
+	var res authenticationTokenResp
+	err = json.Unmarshal(response.Body.Bytes(), &res)
+	require.Nil(t, err)
+	return res
+}
+
+func refreshTestToken(app *application, refreshToken string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"refresh_token": %q}`, refreshToken)
+	request := httptest.NewRequest(http.MethodPost, "/authentication-tokens/refresh", strings.NewReader(body))
+	response := httptest.NewRecorder()
+
+	app.refreshAuthenticationToken(response, request)
+
+	return response
+}
+
+func TestRefreshAuthenticationToken(t *testing.T) {
+	app, _ := newAuthTestApplication()
+	createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+	t.Run("RefreshAuthenticationToken happy path", func(t *testing.T) {
+		login := loginTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		response := refreshTestToken(app, login.RefreshToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res authenticationTokenResp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotEmpty(t, res.AuthenticationToken)
+		require.NotEmpty(t, res.RefreshToken)
+		require.NotEqual(t, login.RefreshToken, res.RefreshToken)
+
+		_, err = app.checkAuthenticationToken(res.AuthenticationToken)
+		require.Nil(t, err)
+
+		previous, err := app.store.RefreshTokenRetrieve(context.Background(), token.Hash(login.RefreshToken))
+		require.Nil(t, err)
+		rotated, err := app.store.RefreshTokenRetrieve(context.Background(), token.Hash(res.RefreshToken))
+		require.Nil(t, err)
+		require.NotNil(t, previous.Used)
+		require.Equal(t, previous.FamilyID, rotated.FamilyID)
+	})
+
+	t.Run("RefreshAuthenticationToken reuse revokes family", func(t *testing.T) {
+		login := loginTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		first := refreshTestToken(app, login.RefreshToken)
+		require.Equal(t, http.StatusOK, first.Code)
+		var rotated authenticationTokenResp
+		err := json.Unmarshal(first.Body.Bytes(), &rotated)
+		require.Nil(t, err)
+
+		replay := refreshTestToken(app, login.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, replay.Code)
+
+		afterReplay := refreshTestToken(app, rotated.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, afterReplay.Code)
+
+		revoked, err := app.store.RefreshTokenRetrieve(context.Background(), token.Hash(rotated.RefreshToken))
+		require.Nil(t, err)
+		require.NotNil(t, revoked.Revoked)
+	})
+
+	t.Run("RefreshAuthenticationToken does not revoke other families", func(t *testing.T) {
+		first := loginTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		second := loginTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		require.Equal(t, http.StatusOK, refreshTestToken(app, first.RefreshToken).Code)
+		require.Equal(t, http.StatusUnauthorized, refreshTestToken(app, first.RefreshToken).Code)
+
+		require.Equal(t, http.StatusOK, refreshTestToken(app, second.RefreshToken).Code)
+	})
+
+	t.Run("RefreshAuthenticationToken expired", func(t *testing.T) {
+		login := loginTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		app.clock = func() time.Time { return time.Now().Add(25 * time.Hour) }
+		response := refreshTestToken(app, login.RefreshToken)
+		app.clock = nil
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Invalid or expired refresh token", res.Errors)
+	})
+
+	t.Run("RefreshAuthenticationToken unknown token", func(t *testing.T) {
+		response := refreshTestToken(app, "not-a-real-token")
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("RefreshAuthenticationToken missing token", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/authentication-tokens/refresh", strings.NewReader(`{}`))
+		response := httptest.NewRecorder()
+
+		app.refreshAuthenticationToken(response, request)
 
-	return &res, nil
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Refresh token is required", res.FieldErrors["refresh_token"])
+	})
 }
 
-func (s *StubStore) UserRetrieveByEmail(email string) (*store.User, error) {
-	for _, item := range s.userStore {
-		if item.Email == email {
-			return &item, nil
+func TestAuthenticate(t *testing.T) {
+	app, _ := newAuthTestApplication()
+	createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+	user, err := app.store.UserRetrieveByEmail(context.Background(), "msyt@gmail.com")
+	require.Nil(t, err)
+
+	var authenticatedUser *store.User
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authenticatedUser = contextGetAuthenticatedUser(r)
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := app.authenticate(next)
+
+	serve := func(authorizationHeader string) *httptest.ResponseRecorder {
+		authenticatedUser = nil
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		if authorizationHeader != "" {
+			request.Header.Set("Authorization", authorizationHeader)
 		}
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
 	}
-	return nil, store.ErrUserNotFound
+
+	t.Run("Authenticate anonymous request", func(t *testing.T) {
+		response := serve("")
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Nil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate valid token", func(t *testing.T) {
+		token, _, err := app.newAuthenticationToken(user)
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NotNil(t, authenticatedUser)
+		require.Equal(t, user.ID, authenticatedUser.ID)
+		require.Equal(t, user.Email, authenticatedUser.Email)
+	})
+
+	t.Run("Authenticate malformed header", func(t *testing.T) {
+		token, _, err := app.newAuthenticationToken(user)
+		require.Nil(t, err)
+
+		response := serve("Token " + token)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Equal(t, "Bearer", response.Header().Get("WWW-Authenticate"))
+		require.Nil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate bad signature", func(t *testing.T) {
+		other, _ := newAuthTestApplication()
+		other.config.jwt.secretKey = "some-other-secret-key-entirely!!"
+		token, _, err := other.newAuthenticationToken(user)
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Nil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate wrong issuer", func(t *testing.T) {
+		other, _ := newAuthTestApplication()
+		other.config.baseURL = "http://example.org"
+		token, _, err := other.newAuthenticationToken(user)
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Nil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate expired token", func(t *testing.T) {
+		issued := time.Now().Add(-2 * time.Hour)
+		app.clock = func() time.Time { return issued }
+		token, _, err := app.newAuthenticationToken(user)
+		app.clock = nil
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Nil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate expired token within leeway", func(t *testing.T) {
+		issued := time.Now().Add(-time.Hour - 30*time.Second)
+		app.clock = func() time.Time { return issued }
+		token, _, err := app.newAuthenticationToken(user)
+		app.clock = nil
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NotNil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate token issued in the future within leeway", func(t *testing.T) {
+		issued := time.Now().Add(30 * time.Second)
+		app.clock = func() time.Time { return issued }
+		token, _, err := app.newAuthenticationToken(user)
+		app.clock = nil
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NotNil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate token not yet valid", func(t *testing.T) {
+		issued := time.Now().Add(5 * time.Minute)
+		app.clock = func() time.Time { return issued }
+		token, _, err := app.newAuthenticationToken(user)
+		app.clock = nil
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Nil(t, authenticatedUser)
+	})
+
+	t.Run("Authenticate unknown user", func(t *testing.T) {
+		token, _, err := app.newAuthenticationToken(&store.User{ID: uuid.New()})
+		require.Nil(t, err)
+
+		response := serve("Bearer " + token)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Nil(t, authenticatedUser)
+	})
+}
+
+func TestRequireAuthenticatedUser(t *testing.T) {
+	app, _ := newAuthTestApplication()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := app.requireAuthenticatedUser(next)
+
+	t.Run("RequireAuthenticatedUser anonymous request", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "You must be authenticated to access this resource", res.Errors)
+	})
+
+	t.Run("RequireAuthenticatedUser authenticated request", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request = contextSetAuthenticatedUser(request, &store.User{ID: uuid.New()})
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	})
 }
 
-func (s *StubStore) UserRetrieve(id uuid.UUID) (*store.User, error) {
-	//TODO implement me
-	panic("implement me")
+func createTestAdmin(t *testing.T, app *application, memStore *memstore.MemoryStore, email, password string) *store.User {
+	createTestUser(t, app, email, password)
+	user, err := memStore.UserRetrieveByEmail(context.Background(), email)
+	require.Nil(t, err)
+	err = memStore.UserUpdateAdmin(context.Background(), user.ID, true)
+	require.Nil(t, err)
+	return user
 }
 
-func (s *StubStore) UserUpdatePassword(id uuid.UUID, newPassword string) error {
-	//TODO implement me
-	panic("implement me")
+func serveTestRequest(app *application, method, target, body, authenticationToken string) *httptest.ResponseRecorder {
+	var request *http.Request
+	if body == "" {
+		request = httptest.NewRequest(method, target, nil)
+	} else {
+		request = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	if authenticationToken != "" {
+		request.Header.Set("Authorization", "Bearer "+authenticationToken)
+	}
+	response := httptest.NewRecorder()
+
+	app.routes().ServeHTTP(response, request)
+
+	return response
 }
 
-func (s *StubStore) UserUpdateAdmin(id uuid.UUID, newAdminValue bool) error {
-	//TODO implement me
-	panic("implement me")
+func TestRequirePermission(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	admin := createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := app.requirePermission(store.PermissionUsersRead)(next)
+
+	t.Run("RequirePermission anonymous request", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("RequirePermission missing permission", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request = contextSetAuthenticatedUser(request, user)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		require.Equal(t, http.StatusForbidden, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Your user account doesn't have the necessary permissions to access this resource", res.Errors)
+	})
+
+	t.Run("RequirePermission granted by role", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/users", nil)
+		request = contextSetAuthenticatedUser(request, admin)
+		response := httptest.NewRecorder()
+
+		handler.ServeHTTP(response, request)
+
+		require.Equal(t, http.StatusOK, response.Code)
+	})
 }
 
-func (s *StubStore) UserDelete(id uuid.UUID) error {
-	//TODO implement me
-	panic("implement me")
+func TestRoleEnforcement(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	userToken := loginTestUser(t, app, "user@gmail.com", "qweqweqwe").AuthenticationToken
+	adminToken := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe").AuthenticationToken
+
+	t.Run("ListUsers requires authentication", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users", "", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("ListUsers requires users:read", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users", "", userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/users", "", adminToken)
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("CreateUser admin requires users:admin", func(t *testing.T) {
+		body := `{"email": "new-admin@gmail.com", "password": "qweqweqwe", "admin": true}`
+
+		response := serveTestRequest(app, http.MethodPost, "/users", body, "")
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, "/users", body, userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, "/users", body, adminToken)
+		require.Equal(t, http.StatusCreated, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, true, res.Data["admin"])
+	})
+
+	t.Run("ListRoles", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/roles", "", adminToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			Data []store.Role
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		roles, err := memStore.RoleList(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, roles, res.Data)
+	})
+
+	t.Run("GrantUserRole and RevokeUserRole", func(t *testing.T) {
+		target := fmt.Sprintf("/users/%s/roles/user-manager", user.ID)
+
+		response := serveTestRequest(app, http.MethodPut, target, "", userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, target, "", adminToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/users", "", userToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, fmt.Sprintf("/users/%s/roles", user.ID), "", adminToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			Data []string
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, []string{"user-manager"}, res.Data)
+
+		response = serveTestRequest(app, http.MethodDelete, target, "", adminToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/users", "", userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("GrantUserRole unknown role", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPut, fmt.Sprintf("/users/%s/roles/wizard", user.ID), "", adminToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("GrantUserRole invalid id", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPut, "/users/not-a-uuid/roles/admin", "", adminToken)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func TestUserResource(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "other@gmail.com", "qweqweqwe")
+	createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	other, err := memStore.UserRetrieveByEmail(context.Background(), "other@gmail.com")
+	require.Nil(t, err)
+	userToken := loginTestUser(t, app, "user@gmail.com", "qweqweqwe").AuthenticationToken
+	adminToken := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe").AuthenticationToken
+
+	t.Run("RetrieveUser self", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users/"+user.ID.String(), "", userToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, user.Email, res.Data["email"])
+		require.Equal(t, user.ID.String(), res.Data["id"])
+		require.Nil(t, res.Data["HashedPassword"])
+	})
+
+	t.Run("RetrieveUser other user without permission", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users/"+other.ID.String(), "", userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("RetrieveUser other user as admin", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users/"+other.ID.String(), "", adminToken)
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("RetrieveUser not found", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users/"+uuid.NewString(), "", adminToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("RetrieveUser invalid id", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users/not-a-uuid", "", adminToken)
+
+		require.Equal(t, http.StatusBadRequest, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Invalid id parameter", res.Errors)
+	})
+
+	t.Run("RetrieveUser requires authentication", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users/"+user.ID.String(), "", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("UpdateUser self email", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+user.ID.String(), `{"email": "renamed@gmail.com"}`, userToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "renamed@gmail.com", res.Data["email"])
+	})
+
+	t.Run("UpdateUser email in use", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+user.ID.String(), `{"email": "other@gmail.com"}`, userToken)
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Email is already in use", res.FieldErrors["email"])
+	})
+
+	t.Run("UpdateUser invalid password", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+user.ID.String(), `{"password": "qwe"}`, userToken)
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Password is too short", res.FieldErrors["password"])
+	})
+
+	t.Run("UpdateUser self password is re-hashed", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+user.ID.String(), `{"password": "asdasdasd"}`, userToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		updated, err := memStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.NotEqual(t, "asdasdasd", updated.HashedPassword)
+		matches, err := password.Matches("asdasdasd", updated.HashedPassword)
+		require.Nil(t, err)
+		require.True(t, matches)
+
+		response = serveTestRequest(app, http.MethodGet, "/users/"+user.ID.String(), "", userToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		userToken = loginTestUser(t, app, "renamed@gmail.com", "asdasdasd").AuthenticationToken
+	})
+
+	t.Run("UpdateUser self admin is forbidden", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+user.ID.String(), `{"admin": true}`, userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("UpdateUser other user without permission", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+other.ID.String(), `{"email": "hijacked@gmail.com"}`, userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("UpdateUser admin promotes other user", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+other.ID.String(), `{"admin": true}`, adminToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, true, res.Data["admin"])
+	})
+
+	t.Run("UpdateUser not found", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+uuid.NewString(), `{"email": "ghost@gmail.com"}`, adminToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("UpdateUser bad JSON", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+user.ID.String(), `{"email"`, userToken)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("DeleteUser other user without permission", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, "/users/"+other.ID.String(), "", userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("DeleteUser as admin", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, "/users/"+other.ID.String(), "", adminToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, "/users/"+other.ID.String(), "", adminToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("DeleteUser self", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, "/users/"+user.ID.String(), "", userToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		_, err := memStore.UserRetrieve(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
+
+func TestMe(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "other@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	login := loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+
+	t.Run("RetrieveMe", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/me", "", login.AuthenticationToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, user.ID.String(), res.Data["id"])
+		require.Equal(t, "user@gmail.com", res.Data["email"])
+	})
+
+	t.Run("RetrieveMe requires authentication", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/me", "", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("UpdateMe email", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/me", `{"email": "renamed@gmail.com"}`, login.AuthenticationToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "renamed@gmail.com", res.Data["email"])
+	})
+
+	t.Run("UpdateMe email in use", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/me", `{"email": "other@gmail.com"}`, login.AuthenticationToken)
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Email is already in use", res.FieldErrors["email"])
+	})
+
+	t.Run("UpdateMe ignores admin", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/me", `{"admin": true}`, login.AuthenticationToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		updated, err := memStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.False(t, updated.Admin)
+	})
+
+	t.Run("ChangeMyPassword validation", func(t *testing.T) {
+		body := `{"current_password": "wrongwrong", "new_password": "password"}`
+		response := serveTestRequest(app, http.MethodPost, "/me/password", body, login.AuthenticationToken)
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Current password is incorrect", res.FieldErrors["current_password"])
+		require.Equal(t, "Password is too common", res.FieldErrors["new_password"])
+	})
+
+	t.Run("ChangeMyPassword invalidates other sessions", func(t *testing.T) {
+		otherSession := loginTestUser(t, app, "renamed@gmail.com", "qweqweqwe")
+
+		body := `{"current_password": "qweqweqwe", "new_password": "asdasdasd"}`
+		response := serveTestRequest(app, http.MethodPost, "/me/password", body, login.AuthenticationToken)
+
+		require.Equal(t, http.StatusOK, response.Code)
+		var res authenticationTokenResp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotEmpty(t, res.AuthenticationToken)
+		require.NotEmpty(t, res.RefreshToken)
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", otherSession.AuthenticationToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		response = serveTestRequest(app, http.MethodGet, "/me", "", login.AuthenticationToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Equal(t, http.StatusUnauthorized, refreshTestToken(app, otherSession.RefreshToken).Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", res.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, http.StatusOK, refreshTestToken(app, res.RefreshToken).Code)
+
+		loginTestUser(t, app, "renamed@gmail.com", "asdasdasd")
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	mailer := app.mailer.(*fakeMailer)
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	app.wg.Wait()
+	mailer.reset()
+
+	resetTokenCount := func(t *testing.T) int {
+		count, err := memStore.TokenCountSince(context.Background(), store.ScopePasswordReset, user.ID, time.Time{})
+		require.Nil(t, err)
+		return count
+	}
+	deleteResetTokens := func(t *testing.T) {
+		err := memStore.TokenDeleteAllForUser(context.Background(), store.ScopePasswordReset, user.ID)
+		require.Nil(t, err)
+	}
+
+	requestReset := func(t *testing.T, email string) string {
+		response := serveTestRequest(app, http.MethodPost, "/password-reset-tokens", fmt.Sprintf(`{"email": %q}`, email), "")
+		app.wg.Wait()
+		require.Equal(t, http.StatusAccepted, response.Code)
+		return response.Body.String()
+	}
+
+	t.Run("CreatePasswordResetToken unknown email", func(t *testing.T) {
+		body := requestReset(t, "missing@gmail.com")
+
+		require.Empty(t, mailer.sent)
+		require.Zero(t, resetTokenCount(t))
+		require.Equal(t, requestReset(t, "user@gmail.com"), body)
+		mailer.reset()
+		deleteResetTokens(t)
+	})
+
+	t.Run("CreatePasswordResetToken validation", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, "/password-reset-tokens", `{"email": "not-an-email"}`, "")
+
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		require.Empty(t, mailer.sent)
+	})
+
+	t.Run("ResetPassword happy path", func(t *testing.T) {
+		login := loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+		requestReset(t, "user@gmail.com")
+		requestReset(t, "user@gmail.com")
+
+		require.Len(t, mailer.sent, 2)
+		require.Equal(t, "user@gmail.com", mailer.sent[0].recipient)
+		require.Equal(t, []string{"password-reset.tmpl"}, mailer.sent[0].patterns)
+		resetToken := mailer.sent[0].data["Token"].(string)
+		require.Equal(t, 2, resetTokenCount(t))
+		_, err := memStore.TokenConsume(context.Background(), store.ScopePasswordReset, []byte(resetToken))
+		require.ErrorIs(t, err, store.ErrTokenNotFound)
+
+		body := fmt.Sprintf(`{"token": %q, "password": "asdasdasd"}`, resetToken)
+		response := serveTestRequest(app, http.MethodPut, "/users/password", body, "")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		updated, err := memStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		matches, err := password.Matches("asdasdasd", updated.HashedPassword)
+		require.Nil(t, err)
+		require.True(t, matches)
+		require.Zero(t, resetTokenCount(t))
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", login.AuthenticationToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		response = refreshTestToken(app, login.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, "/users/password", body, "")
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Invalid or expired password reset token", res.FieldErrors["token"])
+
+		loginTestUser(t, app, "user@gmail.com", "asdasdasd")
+		mailer.reset()
+	})
+
+	t.Run("ResetPassword invalid password keeps token", func(t *testing.T) {
+		requestReset(t, "user@gmail.com")
+		resetToken := mailer.sent[0].data["Token"].(string)
+
+		body := fmt.Sprintf(`{"token": %q, "password": "password"}`, resetToken)
+		response := serveTestRequest(app, http.MethodPut, "/users/password", body, "")
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Password is too common", res.FieldErrors["password"])
+		require.Equal(t, 1, resetTokenCount(t))
+
+		deleteResetTokens(t)
+		mailer.reset()
+	})
+
+	t.Run("ResetPassword expired token", func(t *testing.T) {
+		resetToken, hash, err := token.Generate()
+		require.Nil(t, err)
+		err = memStore.TokenInsert(context.Background(), store.Token{Hash: hash, UserID: user.ID, Scope: store.ScopePasswordReset, Expiry: time.Now().Add(-time.Minute)})
+		require.Nil(t, err)
+
+		body := fmt.Sprintf(`{"token": %q, "password": "zxczxczxc"}`, resetToken)
+		response := serveTestRequest(app, http.MethodPut, "/users/password", body, "")
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	mailer := app.mailer.(*fakeMailer)
+
+	activate := func(t *testing.T, activationToken string) *httptest.ResponseRecorder {
+		return serveTestRequest(app, http.MethodPut, "/users/activated", fmt.Sprintf(`{"token": %q}`, activationToken), "")
+	}
+
+	resend := func(t *testing.T, email string) {
+		response := serveTestRequest(app, http.MethodPost, "/users/activation-tokens", fmt.Sprintf(`{"email": %q}`, email), "")
+		app.wg.Wait()
+		require.Equal(t, http.StatusAccepted, response.Code)
+	}
+
+	t.Run("CreateUser sends activation email", func(t *testing.T) {
+		createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+		app.wg.Wait()
+
+		user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+		require.Nil(t, err)
+		require.Nil(t, user.EmailVerified)
+		require.Len(t, mailer.sent, 1)
+		require.Equal(t, "user@gmail.com", mailer.sent[0].recipient)
+		require.Equal(t, []string{"activation.tmpl"}, mailer.sent[0].patterns)
+	})
+
+	t.Run("Login blocked until verified when required", func(t *testing.T) {
+		app.config.activation.required = true
+		defer func() { app.config.activation.required = false }()
+
+		bData, err := json.Marshal(authenticationTokenProps{Email: "user@gmail.com", Password: "qweqweqwe"})
+		require.Nil(t, err)
+		response := serveTestRequest(app, http.MethodPost, "/authentication-tokens", string(bData), "")
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		app.config.activation.required = false
+		loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	})
+
+	t.Run("ResendActivation throttled", func(t *testing.T) {
+		resend(t, "user@gmail.com")
+		require.Len(t, mailer.sent, 1)
+
+		app.clock = func() time.Time { return time.Now().Add(10 * time.Minute) }
+		defer func() { app.clock = nil }()
+
+		resend(t, "user@gmail.com")
+		require.Len(t, mailer.sent, 2)
+		require.NotEqual(t, mailer.sent[0].data["Token"], mailer.sent[1].data["Token"])
+
+		resend(t, "missing@gmail.com")
+		require.Len(t, mailer.sent, 2)
+	})
+
+	t.Run("ActivateUser happy path", func(t *testing.T) {
+		activationToken := mailer.sent[0].data["Token"].(string)
+		response := activate(t, activationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotNil(t, res.Data["email_verified_at"])
+		userID := uuid.MustParse(res.Data["id"].(string))
+		count, err := memStore.TokenCountSince(context.Background(), store.ScopeActivation, userID, time.Time{})
+		require.Nil(t, err)
+		require.Zero(t, count)
+
+		response = activate(t, mailer.sent[1].data["Token"].(string))
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Invalid or expired activation token", res.FieldErrors["token"])
+
+		app.config.activation.required = true
+		defer func() { app.config.activation.required = false }()
+		loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	})
+
+	t.Run("ResendActivation verified user", func(t *testing.T) {
+		mailer.reset()
+		app.clock = func() time.Time { return time.Now().Add(time.Hour) }
+		defer func() { app.clock = nil }()
+
+		resend(t, "user@gmail.com")
+		require.Empty(t, mailer.sent)
+	})
+
+	t.Run("UpdateMe email requires verification", func(t *testing.T) {
+		mailer.reset()
+		login := loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+
+		response := serveTestRequest(app, http.MethodPatch, "/me", `{"email": "renamed@gmail.com"}`, login.AuthenticationToken)
+		app.wg.Wait()
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Nil(t, res.Data["email_verified_at"])
+		require.Len(t, mailer.sent, 1)
+		require.Equal(t, "renamed@gmail.com", mailer.sent[0].recipient)
+
+		response = activate(t, mailer.sent[0].data["Token"].(string))
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("ActivateUser validation", func(t *testing.T) {
+		response := activate(t, "")
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+}
+
+type mfaChallengeResp struct {
+	MFARequired    bool
+	MFAToken       string
+	MFATokenExpiry string
+}
+
+func TestTOTP(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	now := time.Now().Truncate(30 * time.Second)
+	app.clock = func() time.Time { return now }
+	createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	admin, err := memStore.UserRetrieveByEmail(context.Background(), "admin@gmail.com")
+	require.Nil(t, err)
+	login := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+
+	var totpSecret string
+	var recoveryCodes []string
+
+	startLogin := func(t *testing.T) mfaChallengeResp {
+		bData, err := json.Marshal(authenticationTokenProps{Email: "admin@gmail.com", Password: "qweqweqwe"})
+		require.Nil(t, err)
+		response := serveTestRequest(app, http.MethodPost, "/authentication-tokens", string(bData), "")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res mfaChallengeResp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.True(t, res.MFARequired)
+		require.NotEmpty(t, res.MFAToken)
+		return res
+	}
+
+	completeLogin := func(t *testing.T, body string) *httptest.ResponseRecorder {
+		return serveTestRequest(app, http.MethodPost, "/authentication-tokens/mfa", body, "")
+	}
+
+	t.Run("EnrollTOTP", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, "/me/mfa/totp", "", login.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		totpSecret = res.Data["secret"].(string)
+		require.NotEmpty(t, totpSecret)
+		require.True(t, strings.HasPrefix(res.Data["url"].(string), "otpauth://totp/Guzei:admin@gmail.com?"))
+		qrCode := res.Data["qr_code"].(string)
+		require.True(t, strings.HasPrefix(qrCode, "data:image/png;base64,"))
+		_, err = png.Decode(base64.NewDecoder(base64.StdEncoding, strings.NewReader(strings.TrimPrefix(qrCode, "data:image/png;base64,"))))
+		require.Nil(t, err)
+
+		stored, err := memStore.TOTPRetrieve(context.Background(), admin.ID)
+		require.Nil(t, err)
+		require.Nil(t, stored.Confirmed)
+		require.NotContains(t, string(stored.Secret), totpSecret)
+
+		// Unconfirmed enrollment doesn't change login.
+		loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+	})
+
+	t.Run("ConfirmTOTP invalid code", func(t *testing.T) {
+		code, err := totp.GenerateCode(totpSecret, now.Add(-5*time.Minute))
+		require.Nil(t, err)
+		response := serveTestRequest(app, http.MethodPost, "/me/mfa/totp/confirm", fmt.Sprintf(`{"code": %q}`, code), login.AuthenticationToken)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		var res resp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Invalid authentication code", res.FieldErrors["code"])
+	})
+
+	t.Run("ConfirmTOTP happy path", func(t *testing.T) {
+		code, err := totp.GenerateCode(totpSecret, now)
+		require.Nil(t, err)
+		response := serveTestRequest(app, http.MethodPost, "/me/mfa/totp/confirm", fmt.Sprintf(`{"code": %q}`, code), login.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res resp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		for _, code := range res.Data["recovery_codes"].([]any) {
+			recoveryCodes = append(recoveryCodes, code.(string))
+		}
+		require.Len(t, recoveryCodes, 10)
+		err = memStore.RecoveryCodeConsume(context.Background(), admin.ID, token.HashRecoveryCode(recoveryCodes[9]))
+		require.Nil(t, err)
+
+		response = serveTestRequest(app, http.MethodPost, "/me/mfa/totp", "", login.AuthenticationToken)
+		require.Equal(t, http.StatusConflict, response.Code)
+		response = serveTestRequest(app, http.MethodPost, "/me/mfa/totp/confirm", fmt.Sprintf(`{"code": %q}`, code), login.AuthenticationToken)
+		require.Equal(t, http.StatusConflict, response.Code)
+	})
+
+	t.Run("Login with TOTP code", func(t *testing.T) {
+		challenge := startLogin(t)
+
+		now = now.Add(time.Minute)
+		code, err := totp.GenerateCode(totpSecret, now)
+		require.Nil(t, err)
+		response := completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, challenge.MFAToken, code))
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res authenticationTokenResp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		response = serveTestRequest(app, http.MethodGet, "/me", "", res.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, challenge.MFAToken, code))
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Login with wrong code burns challenge", func(t *testing.T) {
+		challenge := startLogin(t)
+
+		code, err := totp.GenerateCode(totpSecret, now.Add(time.Hour))
+		require.Nil(t, err)
+		response := completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, challenge.MFAToken, code))
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		code, err = totp.GenerateCode(totpSecret, now)
+		require.Nil(t, err)
+		response = completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, challenge.MFAToken, code))
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Login with recovery code", func(t *testing.T) {
+		challenge := startLogin(t)
+		response := completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "recovery_code": %q}`, challenge.MFAToken, strings.ToUpper(recoveryCodes[0])))
+		require.Equal(t, http.StatusOK, response.Code)
+		err := memStore.RecoveryCodeConsume(context.Background(), admin.ID, token.HashRecoveryCode(recoveryCodes[0]))
+		require.ErrorIs(t, err, store.ErrRecoveryCodeNotFound)
+
+		challenge = startLogin(t)
+		response = completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "recovery_code": %q}`, challenge.MFAToken, recoveryCodes[0]))
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Login validation", func(t *testing.T) {
+		response := completeLogin(t, `{"mfa_token": "token"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+		response = completeLogin(t, `{"mfa_token": "token", "code": "123456"}`)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Wrong codes lock the account", func(t *testing.T) {
+		// The correct password doesn't clear earlier failures, so guessing
+		// codes behind a known password is throttled like guessing passwords.
+		err := memStore.LoginThrottleReset(context.Background(), store.LoginThrottleAccount, "admin@gmail.com")
+		require.Nil(t, err)
+		code, err := totp.GenerateCode(totpSecret, now.Add(time.Hour))
+		require.Nil(t, err)
+		for i := 0; i < 5; i++ {
+			challenge := startLogin(t)
+			response := completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, challenge.MFAToken, code))
+			require.Equal(t, http.StatusUnauthorized, response.Code)
+		}
+
+		bData, err := json.Marshal(authenticationTokenProps{Email: "admin@gmail.com", Password: "qweqweqwe"})
+		require.Nil(t, err)
+		response := serveTestRequest(app, http.MethodPost, "/authentication-tokens", string(bData), "")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+
+		now = now.Add(2 * time.Minute)
+		challenge := startLogin(t)
+		code, err = totp.GenerateCode(totpSecret, now)
+		require.Nil(t, err)
+		response = completeLogin(t, fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, challenge.MFAToken, code))
+		require.Equal(t, http.StatusOK, response.Code)
+
+		_, err = memStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleAccount, "admin@gmail.com")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+	})
+
+	t.Run("DisableTOTP", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, "/me/mfa/totp", `{"password": "wrongwrong"}`, login.AuthenticationToken)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, "/me/mfa/totp", `{"password": "qweqweqwe"}`, login.AuthenticationToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+		err := memStore.RecoveryCodeConsume(context.Background(), admin.ID, token.HashRecoveryCode(recoveryCodes[1]))
+		require.ErrorIs(t, err, store.ErrRecoveryCodeNotFound)
+
+		loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+
+		response = serveTestRequest(app, http.MethodDelete, "/me/mfa/totp", `{"password": "qweqweqwe"}`, login.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	userLogin := loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	adminLogin := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+
+	createKey := func(t *testing.T, authenticationToken, body string) map[string]any {
+		response := serveTestRequest(app, http.MethodPost, "/me/api-keys", body, authenticationToken)
+		require.Equal(t, http.StatusCreated, response.Code)
+
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		return res.Data
+	}
+
+	var userKey map[string]any
+
+	t.Run("CreateAPIKey happy path", func(t *testing.T) {
+		userKey = createKey(t, userLogin.AuthenticationToken, `{"name": "ci"}`)
+
+		key := userKey["key"].(string)
+		require.True(t, strings.HasPrefix(key, "gz_"))
+		require.Equal(t, key[:11], userKey["prefix"])
+		require.Equal(t, "ci", userKey["name"])
+		require.Empty(t, userKey["scopes"])
+		require.Nil(t, userKey["last_used"])
+		require.NotContains(t, userKey, "hash")
+	})
+
+	t.Run("CreateAPIKey validation", func(t *testing.T) {
+		body := fmt.Sprintf(`{"scopes": ["users:read"], "expires": %q}`, time.Now().Add(-time.Hour).Format(time.RFC3339))
+		response := serveTestRequest(app, http.MethodPost, "/me/api-keys", body, userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Name is required", res.FieldErrors["name"])
+		require.Equal(t, `You can't grant the "users:read" scope`, res.FieldErrors["scopes"])
+		require.Equal(t, "Expiry must be in the future", res.FieldErrors["expires"])
+	})
+
+	t.Run("Authenticate with API key", func(t *testing.T) {
+		key := userKey["key"].(string)
+		response := serveTestRequest(app, http.MethodGet, "/me", "", key)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, user.ID.String(), res.Data["id"])
+
+		response = serveTestRequest(app, http.MethodGet, "/me/api-keys/"+userKey["id"].(string), "", userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotNil(t, res.Data["last_used"])
+		require.NotContains(t, res.Data, "key")
+	})
+
+	t.Run("API key restrictions", func(t *testing.T) {
+		key := userKey["key"].(string)
+
+		response := serveTestRequest(app, http.MethodPatch, "/me", `{"email": "renamed@gmail.com"}`, key)
+		require.Equal(t, http.StatusForbidden, response.Code)
+		response = serveTestRequest(app, http.MethodPost, "/me/api-keys", `{"name": "escalate"}`, key)
+		require.Equal(t, http.StatusForbidden, response.Code)
+		response = serveTestRequest(app, http.MethodGet, "/me/api-keys", "", key)
+		require.Equal(t, http.StatusForbidden, response.Code)
+		response = serveTestRequest(app, http.MethodDelete, "/users/"+user.ID.String(), "", key)
+		require.Equal(t, http.StatusForbidden, response.Code)
+		response = serveTestRequest(app, http.MethodGet, "/users/"+user.ID.String(), "", key)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("API key scopes", func(t *testing.T) {
+		unscoped := createKey(t, adminLogin.AuthenticationToken, `{"name": "unscoped"}`)
+		response := serveTestRequest(app, http.MethodGet, "/users", "", unscoped["key"].(string))
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		readOnly := createKey(t, adminLogin.AuthenticationToken, `{"name": "read-only", "scopes": ["users:read"]}`)
+		response = serveTestRequest(app, http.MethodGet, "/users", "", readOnly["key"].(string))
+		require.Equal(t, http.StatusOK, response.Code)
+		response = serveTestRequest(app, http.MethodGet, "/users/"+user.ID.String(), "", readOnly["key"].(string))
+		require.Equal(t, http.StatusOK, response.Code)
+		response = serveTestRequest(app, http.MethodDelete, "/users/"+user.ID.String(), "", readOnly["key"].(string))
+		require.Equal(t, http.StatusForbidden, response.Code)
+		response = serveTestRequest(app, http.MethodPut, "/users/"+user.ID.String()+"/roles/admin", "", readOnly["key"].(string))
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("API keys can't change credentials", func(t *testing.T) {
+		admin, err := memStore.UserRetrieveByEmail(context.Background(), "admin@gmail.com")
+		require.Nil(t, err)
+		writeKey := createKey(t, adminLogin.AuthenticationToken, `{"name": "write", "scopes": ["users:write"]}`)
+
+		response := serveTestRequest(app, http.MethodPatch, "/users/"+admin.ID.String(), `{"password": "asdasdasd"}`, writeKey["key"].(string))
+		require.Equal(t, http.StatusForbidden, response.Code)
+		response = serveTestRequest(app, http.MethodPatch, "/users/"+admin.ID.String(), `{"email": "taken-over@gmail.com"}`, writeKey["key"].(string))
+		require.Equal(t, http.StatusForbidden, response.Code)
+		response = serveTestRequest(app, http.MethodPatch, "/users/"+user.ID.String(), `{"password": "asdasdasd"}`, writeKey["key"].(string))
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		retrieved, err := memStore.UserRetrieve(context.Background(), admin.ID)
+		require.Nil(t, err)
+		require.Equal(t, "admin@gmail.com", retrieved.Email)
+		loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+	})
+
+	t.Run("UpdateAPIKey", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPatch, "/me/api-keys/"+userKey["id"].(string), `{"name": "renamed"}`, userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "renamed", res.Data["name"])
+
+		response = serveTestRequest(app, http.MethodPatch, "/me/api-keys/"+userKey["id"].(string), `{"name": "renamed"}`, adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("ListAPIKeys", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/me/api-keys", "", userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res struct {
+			Data []map[string]any
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Len(t, res.Data, 1)
+		require.Equal(t, userKey["id"], res.Data[0]["id"])
+	})
+
+	t.Run("Expired API key", func(t *testing.T) {
+		expires := time.Now().Add(time.Hour).Format(time.RFC3339)
+		expiring := createKey(t, userLogin.AuthenticationToken, fmt.Sprintf(`{"name": "expiring", "expires": %q}`, expires))
+		require.Equal(t, http.StatusOK, serveTestRequest(app, http.MethodGet, "/me", "", expiring["key"].(string)).Code)
+
+		plaintext, prefix, hash, err := newAPIKey()
+		require.Nil(t, err)
+		past := time.Now().Add(-time.Minute)
+		_, err = memStore.APIKeyInsert(context.Background(), store.APIKey{ID: uuid.New(), UserID: user.ID, Name: "expired", Prefix: prefix, Hash: hash, Expires: &past})
+		require.Nil(t, err)
+		require.Equal(t, http.StatusUnauthorized, serveTestRequest(app, http.MethodGet, "/me", "", plaintext).Code)
+	})
+
+	t.Run("DeleteAPIKey revokes immediately", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, "/me/api-keys/"+userKey["id"].(string), "", userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", userKey["key"].(string))
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, "/me/api-keys/"+userKey["id"].(string), "", userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("Unknown API key", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/me", "", "gz_UNKNOWN")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+}
+
+func loginFromIP(app *application, email, password, ip string) *httptest.ResponseRecorder {
+	bData, _ := json.Marshal(authenticationTokenProps{Email: email, Password: password})
+	request := httptest.NewRequest(http.MethodPost, "/authentication-tokens", bytes.NewReader(bData))
+	request.Header.Set("X-Real-IP", ip)
+	response := httptest.NewRecorder()
+
+	app.routes().ServeHTTP(response, request)
+
+	return response
+}
+
+func TestLoginLockout(t *testing.T) {
+	newLockoutTestApplication := func(t *testing.T) (*application, *memstore.MemoryStore, *time.Time) {
+		app, memStore := newAuthTestApplication()
+		app.config.lockout.accountThreshold = 3
+		app.config.lockout.ipThreshold = 5
+		now := time.Now()
+		app.clock = func() time.Time { return now }
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		app.wg.Wait()
+		app.mailer.(*fakeMailer).reset()
+		return app, memStore, &now
+	}
+
+	t.Run("Account locked after repeated failures", func(t *testing.T) {
+		app, memStore, now := newLockoutTestApplication(t)
+		mailer := app.mailer.(*fakeMailer)
+
+		for i := 0; i < 3; i++ {
+			response := loginFromIP(app, "msyt@gmail.com", "wrong-password", "203.0.113.1")
+			require.Equal(t, http.StatusUnauthorized, response.Code)
+		}
+
+		app.wg.Wait()
+		require.Len(t, mailer.sent, 1)
+		require.Equal(t, "msyt@gmail.com", mailer.sent[0].recipient)
+		require.Equal(t, []string{"account-locked.tmpl"}, mailer.sent[0].patterns)
+		require.Equal(t, 3, mailer.sent[0].data["Failures"])
+		require.Equal(t, "203.0.113.1", mailer.sent[0].data["IP"])
+
+		// The correct password is rejected while the account is locked, from
+		// any address.
+		response := loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.2")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+		require.Equal(t, "60", response.Header().Get("Retry-After"))
+
+		*now = now.Add(61 * time.Second)
+		response = loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.2")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		_, err := memStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleAccount, "msyt@gmail.com")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+	})
+
+	t.Run("Lockout backs off exponentially", func(t *testing.T) {
+		app, _, now := newLockoutTestApplication(t)
+
+		for i := 0; i < 3; i++ {
+			loginFromIP(app, "msyt@gmail.com", "wrong-password", "203.0.113.1")
+		}
+
+		*now = now.Add(61 * time.Second)
+		response := loginFromIP(app, "msyt@gmail.com", "wrong-password", "203.0.113.1")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.2")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+		require.Equal(t, "120", response.Header().Get("Retry-After"))
+
+		app.wg.Wait()
+		require.Len(t, app.mailer.(*fakeMailer).sent, 1)
+
+		require.Equal(t, time.Hour, app.lockoutDelay(100, 3))
+	})
+
+	t.Run("Failures outside the window are forgotten", func(t *testing.T) {
+		app, _, now := newLockoutTestApplication(t)
+
+		for i := 0; i < 2; i++ {
+			loginFromIP(app, "msyt@gmail.com", "wrong-password", "203.0.113.1")
+		}
+
+		*now = now.Add(25 * time.Hour)
+		response := loginFromIP(app, "msyt@gmail.com", "wrong-password", "203.0.113.1")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.1")
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("Unknown accounts are locked the same way", func(t *testing.T) {
+		app, _, _ := newLockoutTestApplication(t)
+
+		for i := 0; i < 3; i++ {
+			response := loginFromIP(app, "nobody@gmail.com", "wrong-password", "203.0.113.1")
+			require.Equal(t, http.StatusUnauthorized, response.Code)
+		}
+
+		response := loginFromIP(app, "nobody@gmail.com", "wrong-password", "203.0.113.2")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+
+		app.wg.Wait()
+		require.Empty(t, app.mailer.(*fakeMailer).sent)
+	})
+
+	t.Run("Client IP locked after failures across accounts", func(t *testing.T) {
+		app, _, _ := newLockoutTestApplication(t)
+
+		for i := 0; i < 5; i++ {
+			response := loginFromIP(app, fmt.Sprintf("user%d@gmail.com", i), "wrong-password", "203.0.113.1")
+			require.Equal(t, http.StatusUnauthorized, response.Code)
+		}
+
+		response := loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.1")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+
+		response = loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.2")
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("Admin unlocks account", func(t *testing.T) {
+		app, memStore, _ := newLockoutTestApplication(t)
+		createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+		adminTokens := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+		user, err := memStore.UserRetrieveByEmail(context.Background(), "msyt@gmail.com")
+		require.Nil(t, err)
+
+		for i := 0; i < 3; i++ {
+			loginFromIP(app, "msyt@gmail.com", "wrong-password", "203.0.113.1")
+		}
+		response := loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.2")
+		require.Equal(t, http.StatusTooManyRequests, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, "/users/"+user.ID.String()+"/lockout", "", adminTokens.AuthenticationToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = loginFromIP(app, "msyt@gmail.com", "qweqweqwe", "203.0.113.2")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		userTokens := loginTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		response = serveTestRequest(app, http.MethodDelete, "/users/"+user.ID.String()+"/lockout", "", userTokens.AuthenticationToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, "/users/"+uuid.New().String()+"/lockout", "", adminTokens.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+}
+
+type sessionLoginResp struct {
+	Session   store.Session
+	CSRFToken string
+}
+
+func serveSessionRequest(app *application, method, target, body string, cookies []*http.Cookie, csrfToken string) *httptest.ResponseRecorder {
+	var request *http.Request
+	if body == "" {
+		request = httptest.NewRequest(method, target, nil)
+	} else {
+		request = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	request.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0")
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	if csrfToken != "" {
+		request.Header.Set("X-CSRF-Token", csrfToken)
+	}
+	response := httptest.NewRecorder()
+
+	app.routes().ServeHTTP(response, request)
+
+	return response
+}
+
+func responseCookie(response *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range response.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func sessionTestLogin(t *testing.T, app *application, email, password string) (sessionLoginResp, []*http.Cookie) {
+	response := serveSessionRequest(app, http.MethodPost, "/authentication-tokens", fmt.Sprintf(`{"email": %q, "password": %q, "session": true}`, email, password), nil, "")
+	require.Equal(t, http.StatusOK, response.Code)
+
+	var res sessionLoginResp
+	err := json.Unmarshal(response.Body.Bytes(), &res)
+	require.Nil(t, err)
+
+	sessionCookie := responseCookie(response, "session")
+	require.NotNil(t, sessionCookie)
+	csrfCookie := responseCookie(response, "csrf_token")
+	require.NotNil(t, csrfCookie)
+
+	return res, []*http.Cookie{sessionCookie, csrfCookie}
+}
+
+func listTestSessions(t *testing.T, memStore *memstore.MemoryStore, email string) []store.Session {
+	user, err := memStore.UserRetrieveByEmail(context.Background(), email)
+	require.Nil(t, err)
+	sessions, err := memStore.SessionList(context.Background(), user.ID)
+	require.Nil(t, err)
+	return sessions
+}
+
+func TestSessions(t *testing.T) {
+	t.Run("Login issues session cookies", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		res, cookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+		require.True(t, cookies[0].HttpOnly)
+		require.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		require.False(t, cookies[1].HttpOnly)
+		require.Equal(t, res.CSRFToken, cookies[1].Value)
+		require.Equal(t, "Firefox on Linux", res.Session.Device)
+		require.Equal(t, "192.0.2.1", res.Session.IP)
+
+		response := serveSessionRequest(app, http.MethodGet, "/me", "", cookies, "")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		// Bearer tokens keep working alongside sessions.
+		tokens := loginTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		response = serveTestRequest(app, http.MethodGet, "/me", "", tokens.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("Session expiry slides on use", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		_, cookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		later := time.Now().Add(time.Hour)
+		app.clock = func() time.Time { return later }
+		defer func() { app.clock = nil }()
+
+		response := serveSessionRequest(app, http.MethodGet, "/me", "", cookies, "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, later.Add(app.config.sessions.ttl), listTestSessions(t, memStore, "msyt@gmail.com")[0].Expires)
+		require.NotNil(t, responseCookie(response, "session"))
+	})
+
+	t.Run("Unsafe methods require CSRF token", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		res, cookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+		body := `{"email": "new@gmail.com"}`
+
+		response := serveSessionRequest(app, http.MethodPatch, "/me", body, cookies, "")
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveSessionRequest(app, http.MethodPatch, "/me", body, cookies, "forged")
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		// A matching cookie and header that weren't issued for the session
+		// are rejected too.
+		forged := []*http.Cookie{cookies[0], {Name: "csrf_token", Value: "forged"}}
+		response = serveSessionRequest(app, http.MethodPatch, "/me", body, forged, "forged")
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveSessionRequest(app, http.MethodPatch, "/me", body, cookies[:1], res.CSRFToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveSessionRequest(app, http.MethodPatch, "/me", body, cookies, res.CSRFToken)
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("List and revoke sessions", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		first, firstCookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+		second, secondCookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		response := serveSessionRequest(app, http.MethodGet, "/me/sessions", "", firstCookies, "")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res struct {
+			Data []struct {
+				ID        string `json:"id"`
+				Device    string `json:"device"`
+				IP        string `json:"ip"`
+				UserAgent string `json:"user_agent"`
+				Current   bool   `json:"current"`
+			}
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Len(t, res.Data, 2)
+		for _, session := range res.Data {
+			require.Equal(t, session.ID == first.Session.ID.String(), session.Current)
+			require.Contains(t, session.UserAgent, "Firefox")
+		}
+
+		response = serveSessionRequest(app, http.MethodDelete, "/me/sessions/"+second.Session.ID.String(), "", firstCookies, first.CSRFToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+		require.Positive(t, responseCookie(response, "session").MaxAge)
+
+		// The revoked session is treated as anonymous and its cookies cleared.
+		response = serveSessionRequest(app, http.MethodGet, "/me", "", secondCookies, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Equal(t, -1, responseCookie(response, "session").MaxAge)
+
+		response = serveSessionRequest(app, http.MethodDelete, "/me/sessions/"+second.Session.ID.String(), "", firstCookies, first.CSRFToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		// Revoking the current session logs out.
+		response = serveSessionRequest(app, http.MethodDelete, "/me/sessions/"+first.Session.ID.String(), "", firstCookies, first.CSRFToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+		require.Equal(t, -1, responseCookie(response, "session").MaxAge)
+
+		response = serveSessionRequest(app, http.MethodGet, "/me", "", firstCookies, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Stale session cookie doesn't block login", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		_, cookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+		for _, session := range listTestSessions(t, memStore, "msyt@gmail.com") {
+			err := memStore.SessionDelete(context.Background(), session.UserID, session.ID)
+			require.Nil(t, err)
+		}
+
+		response := serveSessionRequest(app, http.MethodPost, "/authentication-tokens", `{"email": "msyt@gmail.com", "password": "qweqweqwe"}`, cookies, "")
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("Password change replaces sessions", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		res, cookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+		_, otherCookies := sessionTestLogin(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		response := serveSessionRequest(app, http.MethodPost, "/me/password", `{"current_password": "qweqweqwe", "new_password": "asdasdasd"}`, cookies, res.CSRFToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Len(t, listTestSessions(t, memStore, "msyt@gmail.com"), 1)
+		newCookies := []*http.Cookie{responseCookie(response, "session"), responseCookie(response, "csrf_token")}
+		require.NotEqual(t, cookies[0].Value, newCookies[0].Value)
+
+		response = serveSessionRequest(app, http.MethodGet, "/me", "", otherCookies, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveSessionRequest(app, http.MethodGet, "/me", "", newCookies, "")
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+}
+
+func requestTestMagicLink(t *testing.T, app *application, body string) (*http.Cookie, string) {
+	// Wait for earlier emails, like the activation email sent on signup, so
+	// they aren't mistaken for the magic link.
+	app.wg.Wait()
+	mailer := app.mailer.(*fakeMailer)
+	mailer.reset()
+
+	response := serveTestRequest(app, http.MethodPost, "/magic-links", body, "")
+	require.Equal(t, http.StatusAccepted, response.Code)
+
+	nonce := responseCookie(response, "magic_link_nonce")
+	require.NotNil(t, nonce)
+	require.True(t, nonce.HttpOnly)
+
+	app.wg.Wait()
+	if len(mailer.sent) == 0 {
+		return nonce, ""
+	}
+	require.Equal(t, []string{"magic-link.tmpl"}, mailer.sent[0].patterns)
+
+	link, err := url.Parse(mailer.sent[0].data["URL"].(string))
+	require.Nil(t, err)
+	require.Equal(t, "/magic-links/exchange", link.Path)
+
+	return nonce, link.RequestURI()
+}
+
+func TestMagicLinks(t *testing.T) {
+	t.Run("Magic link happy path", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		nonce, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+		require.NotEmpty(t, link)
+
+		response := serveSessionRequest(app, http.MethodGet, link, "", []*http.Cookie{nonce}, "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, -1, responseCookie(response, "magic_link_nonce").MaxAge)
+
+		var res authenticationTokenResp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotEmpty(t, res.AuthenticationToken)
+
+		user, err := memStore.UserRetrieveByEmail(context.Background(), "msyt@gmail.com")
+		require.Nil(t, err)
+		require.NotNil(t, user.EmailVerified)
+
+		// Links are single use.
+		response = serveSessionRequest(app, http.MethodGet, link, "", []*http.Cookie{nonce}, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Magic link bound to browser", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		nonce, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+
+		response := serveSessionRequest(app, http.MethodGet, link, "", nil, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		otherBrowser := &http.Cookie{Name: "magic_link_nonce", Value: "other"}
+		response = serveSessionRequest(app, http.MethodGet, link, "", []*http.Cookie{otherBrowser}, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		// Failed attempts from other browsers don't use up the link.
+		response = serveSessionRequest(app, http.MethodGet, link, "", []*http.Cookie{nonce}, "")
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+
+	t.Run("Magic link expired", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		app.clock = func() time.Time { return time.Now().Add(-time.Hour) }
+		nonce, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+		app.clock = nil
+
+		response := serveSessionRequest(app, http.MethodGet, link, "", []*http.Cookie{nonce}, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Magic link unknown email", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+
+		_, link := requestTestMagicLink(t, app, `{"email": "nobody@gmail.com"}`)
+		require.Empty(t, link)
+	})
+
+	t.Run("Magic link rate limited", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		for i := 0; i < 3; i++ {
+			_, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+			require.NotEmpty(t, link)
+		}
+
+		_, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+		require.Empty(t, link)
+	})
+
+	t.Run("Used magic links still count towards the rate limit", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		now := time.Now()
+		app.clock = func() time.Time { return now }
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+
+		for i := 0; i < 3; i++ {
+			nonce, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+			require.NotEmpty(t, link)
+
+			response := serveSessionRequest(app, http.MethodGet, link, "", []*http.Cookie{nonce}, "")
+			require.Equal(t, http.StatusOK, response.Code)
+		}
+
+		_, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+		require.Empty(t, link)
+
+		now = now.Add(61 * time.Minute)
+		_, link = requestTestMagicLink(t, app, `{"email": "msyt@gmail.com"}`)
+		require.NotEmpty(t, link)
+	})
+
+	t.Run("Magic link with session", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		createTestUser(t, app, "msyt@gmail.com", "qweqweqwe")
+		nonce, link := requestTestMagicLink(t, app, `{"email": "msyt@gmail.com", "session": true}`)
+		require.Contains(t, link, "session=true")
+
+		response := serveSessionRequest(app, http.MethodGet, link, "", []*http.Cookie{nonce}, "")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.NotNil(t, responseCookie(response, "session"))
+	})
+
+	t.Run("Magic link invalid input", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+
+		response := serveTestRequest(app, http.MethodPost, "/magic-links", `{"email": "not-an-email"}`, "")
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/magic-links/exchange", "", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+}
+
+func TestAuditEvents(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	admin := createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	userToken := loginTestUser(t, app, "user@gmail.com", "qweqweqwe").AuthenticationToken
+	adminToken := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe").AuthenticationToken
+
+	listAuditEvents := func(t *testing.T, query string) store.AuditEventsList {
+		response := serveTestRequest(app, http.MethodGet, "/audit-events"+query, "", adminToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res store.AuditEventsList
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		return res
+	}
+
+	t.Run("Requires users:admin", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/audit-events", "", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/audit-events", "", userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("Records signup without an actor", func(t *testing.T) {
+		res := listAuditEvents(t, fmt.Sprintf("?action=%s&target=%s", store.AuditUserCreate, user.ID))
+		require.Equal(t, 1, res.TotalObjects)
+		event := res.Data[0]
+		require.Nil(t, event.ActorID)
+		require.Equal(t, "user@gmail.com", event.Diff["email"].New)
+		require.Equal(t, false, event.Diff["admin"].New)
+		require.Equal(t, "192.0.2.1", event.IP)
+	})
+
+	t.Run("Records role changes against the admin", func(t *testing.T) {
+		target := fmt.Sprintf("/users/%s/roles/user-manager", user.ID)
+		response := serveTestRequest(app, http.MethodPut, target, "", adminToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+		response = serveTestRequest(app, http.MethodPut, target, "", adminToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+		response = serveTestRequest(app, http.MethodDelete, target, "", adminToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		res := listAuditEvents(t, fmt.Sprintf("?actor=%s", admin.ID))
+		require.Equal(t, 2, res.TotalObjects)
+		require.Equal(t, store.AuditUserRoleRevoke, res.Data[0].Action)
+		require.Equal(t, "user-manager", res.Data[0].Diff["role"].Old)
+		require.Equal(t, store.AuditUserRoleGrant, res.Data[1].Action)
+		require.Equal(t, "user-manager", res.Data[1].Diff["role"].New)
+		require.Equal(t, user.ID, *res.Data[1].TargetID)
+		require.NotEmpty(t, res.Data[1].RequestID)
+		require.NotEqual(t, res.Data[0].RequestID, res.Data[1].RequestID)
+	})
+
+	t.Run("Redacts passwords", func(t *testing.T) {
+		body := `{"current_password": "qweqweqwe", "new_password": "asdasdasd"}`
+		response := serveTestRequest(app, http.MethodPost, "/me/password", body, userToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		res := listAuditEvents(t, fmt.Sprintf("?action=%s", store.AuditUserUpdatePassword))
+		require.Equal(t, 1, res.TotalObjects)
+		require.Equal(t, user.ID, *res.Data[0].ActorID)
+		require.Equal(t, store.AuditRedacted, res.Data[0].Diff["password"].New)
+	})
+
+	t.Run("Records deletes", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, fmt.Sprintf("/users/%s", user.ID), "", adminToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		res := listAuditEvents(t, fmt.Sprintf("?action=%s", store.AuditUserDelete))
+		require.Equal(t, 1, res.TotalObjects)
+		require.Equal(t, "user@gmail.com", res.Data[0].Diff["email"].Old)
+	})
+
+	t.Run("Filters by time range", func(t *testing.T) {
+		since := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+		res := listAuditEvents(t, "?since="+since)
+		require.Equal(t, 0, res.TotalObjects)
+
+		until := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+		res = listAuditEvents(t, "?until="+until+"&pageSize=2")
+		all, err := memStore.AuditEventList(context.Background(), store.AuditEventListParams{PageNumber: 1, PageSize: 1})
+		require.Nil(t, err)
+		require.Equal(t, all.TotalObjects, res.TotalObjects)
+		require.Len(t, res.Data, 2)
+	})
+
+	t.Run("Invalid filters", func(t *testing.T) {
+		for _, query := range []string{"?actor=nope", "?target=nope", "?since=yesterday", "?pageSize=0", "?since=2024-02-01T00:00:00Z&until=2024-01-01T00:00:00Z"} {
+			response := serveTestRequest(app, http.MethodGet, "/audit-events"+query, "", adminToken)
+			require.Equal(t, http.StatusUnprocessableEntity, response.Code, query)
+		}
+	})
+}
+
+func TestImpersonation(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "manager@gmail.com", "qweqweqwe")
+	admin := createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	manager, err := memStore.UserRetrieveByEmail(context.Background(), "manager@gmail.com")
+	require.Nil(t, err)
+	err = memStore.UserRoleGrant(context.Background(), manager.ID, "user-manager")
+	require.Nil(t, err)
+	userToken := loginTestUser(t, app, "user@gmail.com", "qweqweqwe").AuthenticationToken
+	managerToken := loginTestUser(t, app, "manager@gmail.com", "qweqweqwe").AuthenticationToken
+	adminToken := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe").AuthenticationToken
+	target := fmt.Sprintf("/users/%s/impersonate", user.ID)
+
+	impersonate := func(t *testing.T) string {
+		response := serveTestRequest(app, http.MethodPost, target, "", adminToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			AuthenticationToken string
+			Data                store.User
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, user.ID, res.Data.ID)
+		require.NotEmpty(t, res.AuthenticationToken)
+		return res.AuthenticationToken
+	}
+
+	t.Run("Requires users:admin", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, target, "", "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, target, "", userToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, target, "", managerToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("Acts as the user", func(t *testing.T) {
+		impersonationToken := impersonate(t)
+
+		response := serveTestRequest(app, http.MethodGet, "/me", "", impersonationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, admin.ID.String(), response.Header().Get(impersonatedByHeader))
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "user@gmail.com", res.Data["email"])
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", userToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Empty(t, response.Header().Get(impersonatedByHeader))
+	})
+
+	t.Run("Forbids sensitive actions", func(t *testing.T) {
+		impersonationToken := impersonate(t)
+
+		requests := []struct {
+			method string
+			target string
+			body   string
+		}{
+			{http.MethodPost, "/me/password", `{"current_password": "qweqweqwe", "new_password": "asdasdasd"}`},
+			{http.MethodPost, "/me/mfa/totp", ""},
+			{http.MethodPost, "/me/mfa/totp/confirm", `{"code": "123456"}`},
+			{http.MethodDelete, "/me/mfa/totp", `{"password": "qweqweqwe"}`},
+			{http.MethodPost, "/me/api-keys", `{"name": "ci", "scopes": ["users:read"]}`},
+			{http.MethodPost, fmt.Sprintf("/users/%s/impersonate", manager.ID), ""},
+			{http.MethodPatch, fmt.Sprintf("/users/%s", user.ID), `{"password": "asdasdasd"}`},
+			{http.MethodPatch, fmt.Sprintf("/users/%s", user.ID), `{"email": "taken-over@gmail.com"}`},
+		}
+		for _, request := range requests {
+			response := serveTestRequest(app, request.method, request.target, request.body, impersonationToken)
+			require.Equal(t, http.StatusForbidden, response.Code, request.target)
+		}
+
+		retrieved, err := memStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, user.HashedPassword, retrieved.HashedPassword)
+	})
+
+	t.Run("Audits impersonated changes against the admin", func(t *testing.T) {
+		impersonationToken := impersonate(t)
+
+		response := serveTestRequest(app, http.MethodPatch, "/me", `{"email": "renamed@gmail.com"}`, impersonationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		events, err := memStore.AuditEventList(context.Background(), store.AuditEventListParams{TargetID: &user.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, store.AuditUserUpdateEmail, events.Data[0].Action)
+		require.Equal(t, admin.ID, *events.Data[0].ActorID)
+		require.Equal(t, store.AuditUserImpersonate, events.Data[1].Action)
+		require.Equal(t, admin.ID, *events.Data[1].ActorID)
+	})
+
+	t.Run("Expires quickly", func(t *testing.T) {
+		impersonationToken := impersonate(t)
+
+		app.clock = func() time.Time { return time.Now().Add(20 * time.Minute) }
+		response := serveTestRequest(app, http.MethodGet, "/me", "", impersonationToken)
+		app.clock = nil
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Revoked when the admin loses users:admin", func(t *testing.T) {
+		impersonationToken := impersonate(t)
+
+		err := memStore.UserRoleRevoke(context.Background(), admin.ID, store.RoleAdmin)
+		require.Nil(t, err)
+		defer func() {
+			err := memStore.UserRoleGrant(context.Background(), admin.ID, store.RoleAdmin)
+			require.Nil(t, err)
+		}()
+
+		response := serveTestRequest(app, http.MethodGet, "/me", "", impersonationToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+	})
+
+	t.Run("Invalid targets", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, fmt.Sprintf("/users/%s/impersonate", admin.ID), "", adminToken)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, fmt.Sprintf("/users/%s/impersonate", uuid.New()), "", adminToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, "/users/not-a-uuid/impersonate", "", adminToken)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+}
+
+func serveOrganizationRequest(app *application, method, target, body, authenticationToken, organizationID string) *httptest.ResponseRecorder {
+	var request *http.Request
+	if body == "" {
+		request = httptest.NewRequest(method, target, nil)
+	} else {
+		request = httptest.NewRequest(method, target, strings.NewReader(body))
+	}
+	request.Header.Set("Authorization", "Bearer "+authenticationToken)
+	request.Header.Set(organizationHeader, organizationID)
+	response := httptest.NewRecorder()
+
+	app.routes().ServeHTTP(response, request)
+
+	return response
+}
+
+func TestOrganizations(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	app.config.invitations.tokenTTL = 7 * 24 * time.Hour
+	createTestUser(t, app, "owner@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "manager@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "member@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "outsider@gmail.com", "qweqweqwe")
+	createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	users := make(map[string]*store.User)
+	tokens := make(map[string]string)
+	for _, name := range []string{"owner", "manager", "member", "outsider", "admin"} {
+		user, err := memStore.UserRetrieveByEmail(context.Background(), name+"@gmail.com")
+		require.Nil(t, err)
+		users[name] = user
+		tokens[name] = loginTestUser(t, app, name+"@gmail.com", "qweqweqwe").AuthenticationToken
+	}
+
+	var organization store.Organization
+	t.Run("CreateOrganization", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, "/organizations", `{"name": ""}`, tokens["owner"])
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, "/organizations", `{"name": "Acme"}`, tokens["owner"])
+		require.Equal(t, http.StatusCreated, response.Code)
+		var res struct {
+			Data store.Organization
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "Acme", res.Data.Name)
+		organization = res.Data
+
+		membership, err := memStore.MembershipRetrieve(context.Background(), organization.ID, users["owner"].ID)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleOwner, membership.Role)
+	})
+	members := fmt.Sprintf("/organizations/%s/members", organization.ID)
+
+	t.Run("ListOrganizations", func(t *testing.T) {
+		var res struct {
+			Data []store.Organization
+		}
+		response := serveTestRequest(app, http.MethodGet, "/organizations", "", tokens["owner"])
+		require.Equal(t, http.StatusOK, response.Code)
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Len(t, res.Data, 1)
+
+		response = serveTestRequest(app, http.MethodGet, "/organizations", "", tokens["outsider"])
+		require.Equal(t, http.StatusOK, response.Code)
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Len(t, res.Data, 0)
+
+		response = serveTestRequest(app, http.MethodGet, "/organizations/"+organization.ID.String(), "", tokens["outsider"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("Members join by invitation", func(t *testing.T) {
+		mailer := app.mailer.(*fakeMailer)
+		for _, name := range []string{"manager", "member"} {
+			mailer.reset()
+			body := fmt.Sprintf(`{"email": %q}`, users[name].Email)
+			response := serveTestRequest(app, http.MethodPost, "/organizations/"+organization.ID.String()+"/invitations", body, tokens["owner"])
+			require.Equal(t, http.StatusCreated, response.Code)
+
+			app.wg.Wait()
+			require.Len(t, mailer.sent, 1)
+			body = fmt.Sprintf(`{"token": %q}`, mailer.sent[0].data["Token"].(string))
+			response = serveTestRequest(app, http.MethodPost, "/invitations/accept", body, "")
+			require.Equal(t, http.StatusOK, response.Code)
+		}
+	})
+
+	t.Run("Manage members", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPut, members+"/"+users["manager"].ID.String(), `{"role": "admin"}`, tokens["outsider"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		// Users can't be added without accepting an invitation, so they can't
+		// be made visible to an organization without their consent.
+		response = serveTestRequest(app, http.MethodPut, members+"/"+users["outsider"].ID.String(), `{"role": "member"}`, tokens["owner"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+		_, err := memStore.MembershipRetrieve(context.Background(), organization.ID, users["outsider"].ID)
+		require.Equal(t, store.ErrMembershipNotFound, err)
+
+		response = serveTestRequest(app, http.MethodPut, members+"/"+users["outsider"].ID.String(), `{"role": "member"}`, tokens["admin"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, members+"/"+users["manager"].ID.String(), `{"role": "admin"}`, tokens["owner"])
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, members+"/"+users["member"].ID.String(), `{"role": "owner"}`, tokens["manager"])
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, members+"/"+users["member"].ID.String(), `{"role": "wizard"}`, tokens["manager"])
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, members+"/"+users["member"].ID.String(), `{"role": "member"}`, tokens["manager"])
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, members+"/"+users["outsider"].ID.String(), `{"role": "member"}`, tokens["member"])
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, members, "", tokens["member"])
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			Data []store.Membership
+		}
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Len(t, res.Data, 3)
+
+		events, err := memStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditMembershipUpdate, TargetID: &users["manager"].ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 2)
+		require.Equal(t, users["owner"].ID, *events.Data[0].ActorID)
+		require.Nil(t, events.Data[1].ActorID)
+	})
+
+	t.Run("Scopes users to the selected organization", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users", "", tokens["manager"])
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users", "", tokens["manager"], organization.ID.String())
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			Data store.UsersList
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res.Data)
+		require.Nil(t, err)
+		require.Equal(t, 3, res.Data.TotalObjects)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users/"+users["member"].ID.String(), "", tokens["manager"], organization.ID.String())
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users/"+users["outsider"].ID.String(), "", tokens["manager"], organization.ID.String())
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users/"+users["member"].ID.String()+"/roles", "", tokens["manager"], organization.ID.String())
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users/"+users["outsider"].ID.String()+"/roles", "", tokens["manager"], organization.ID.String())
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users/"+users["outsider"].ID.String(), "", tokens["admin"], "")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users", "", tokens["member"], organization.ID.String())
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("Rejects organizations the user isn't a member of", func(t *testing.T) {
+		response := serveOrganizationRequest(app, http.MethodGet, "/me", "", tokens["outsider"], organization.ID.String())
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/me", "", tokens["outsider"], "not-a-uuid")
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("Organization token", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, "/organizations/"+organization.ID.String()+"/authentication-tokens", "", tokens["outsider"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, "/organizations/"+organization.ID.String()+"/authentication-tokens", "", tokens["manager"])
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			AuthenticationToken string
+			Role                string
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleAdmin, res.Role)
+
+		response = serveTestRequest(app, http.MethodGet, "/users", "", res.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveOrganizationRequest(app, http.MethodGet, "/users", "", res.AuthenticationToken, uuid.NewString())
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("Last owner", func(t *testing.T) {
+		owner := members + "/" + users["owner"].ID.String()
+
+		response := serveTestRequest(app, http.MethodPut, owner, `{"role": "member"}`, tokens["owner"])
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, owner, "", tokens["owner"])
+		require.Equal(t, http.StatusConflict, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, owner, "", tokens["manager"])
+		require.Equal(t, http.StatusForbidden, response.Code)
+	})
+
+	t.Run("Leave and remove members", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, members+"/"+users["member"].ID.String(), "", tokens["member"])
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, members+"/"+users["member"].ID.String(), "", tokens["owner"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, members+"/"+users["manager"].ID.String(), "", tokens["admin"])
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		memberships, err := memStore.MembershipList(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Len(t, memberships, 1)
+	})
+}
+
+func TestInvitations(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	app.config.invitations.tokenTTL = 7 * 24 * time.Hour
+	mailer := app.mailer.(*fakeMailer)
+	createTestUser(t, app, "owner@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "member@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "existing@gmail.com", "qweqweqwe")
+	users := make(map[string]*store.User)
+	tokens := make(map[string]string)
+	for _, name := range []string{"owner", "member", "existing"} {
+		user, err := memStore.UserRetrieveByEmail(context.Background(), name+"@gmail.com")
+		require.Nil(t, err)
+		users[name] = user
+		tokens[name] = loginTestUser(t, app, name+"@gmail.com", "qweqweqwe").AuthenticationToken
+	}
+
+	organization, err := memStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, users["owner"].ID)
+	require.Nil(t, err)
+	_, err = memStore.MembershipUpsert(context.Background(), organization.ID, users["member"].ID, store.OrganizationRoleMember)
+	require.Nil(t, err)
+	invitations := fmt.Sprintf("/organizations/%s/invitations", organization.ID)
+
+	invite := func(t *testing.T, email, role string) (store.Invitation, string) {
+		t.Helper()
+		mailer.reset()
+		body := fmt.Sprintf(`{"email": %q, "role": %q}`, email, role)
+		response := serveTestRequest(app, http.MethodPost, invitations, body, tokens["owner"])
+		require.Equal(t, http.StatusCreated, response.Code)
+		var res struct {
+			Data store.Invitation
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+
+		app.wg.Wait()
+		require.Len(t, mailer.sent, 1)
+		require.Equal(t, email, mailer.sent[0].recipient)
+		require.Equal(t, []string{"organization-invitation.tmpl"}, mailer.sent[0].patterns)
+		require.Equal(t, "Acme", mailer.sent[0].data["OrganizationName"])
+		require.Equal(t, "owner@gmail.com", mailer.sent[0].data["InvitedBy"])
+		return res.Data, mailer.sent[0].data["Token"].(string)
+	}
+
+	accept := func(body string) *httptest.ResponseRecorder {
+		return serveTestRequest(app, http.MethodPost, "/invitations/accept", body, "")
+	}
+
+	t.Run("CreateInvitation", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, invitations, `{"email": "new@gmail.com"}`, tokens["member"])
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, invitations, `{"email": "not-an-email"}`, tokens["owner"])
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, invitations, `{"email": "new@gmail.com", "role": "wizard"}`, tokens["owner"])
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, invitations, `{"email": "member@gmail.com"}`, tokens["owner"])
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		invitation, _ := invite(t, "new@gmail.com", "")
+		require.Equal(t, store.OrganizationRoleMember, invitation.Role)
+		require.Equal(t, users["owner"].ID, *invitation.InvitedBy)
+
+		response = serveTestRequest(app, http.MethodPost, invitations, `{"email": "new@gmail.com"}`, tokens["owner"])
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+
+	t.Run("ListInvitations", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, invitations, "", tokens["member"])
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, invitations, "", tokens["existing"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, invitations, "", tokens["owner"])
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			Data []store.Invitation
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Len(t, res.Data, 1)
+		require.Equal(t, "new@gmail.com", res.Data[0].Email)
+	})
+
+	t.Run("Resend and revoke", func(t *testing.T) {
+		invitation, firstToken := invite(t, "revoked@gmail.com", store.OrganizationRoleAdmin)
+
+		mailer.reset()
+		response := serveTestRequest(app, http.MethodPost, invitations+"/"+invitation.ID.String()+"/resend", "", tokens["owner"])
+		require.Equal(t, http.StatusOK, response.Code)
+		app.wg.Wait()
+		require.Len(t, mailer.sent, 1)
+		secondToken := mailer.sent[0].data["Token"].(string)
+		require.NotEqual(t, firstToken, secondToken)
+
+		response = accept(fmt.Sprintf(`{"token": %q, "password": "asdasdasd"}`, firstToken))
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, invitations+"/"+invitation.ID.String(), "", tokens["member"])
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, invitations+"/"+invitation.ID.String(), "", tokens["owner"])
+		require.Equal(t, http.StatusNoContent, response.Code)
+
+		response = serveTestRequest(app, http.MethodDelete, invitations+"/"+invitation.ID.String(), "", tokens["owner"])
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = accept(fmt.Sprintf(`{"token": %q, "password": "asdasdasd"}`, secondToken))
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+
+	t.Run("Accept as a new user", func(t *testing.T) {
+		_, plaintext := invite(t, "newcomer@gmail.com", store.OrganizationRoleAdmin)
+
+		response := accept(fmt.Sprintf(`{"token": %q}`, plaintext))
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = accept(fmt.Sprintf(`{"token": %q, "password": "asdasdasd"}`, plaintext))
+		require.Equal(t, http.StatusOK, response.Code)
+		var res struct {
+			Data store.Membership
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, organization.ID, res.Data.OrganizationID)
+		require.Equal(t, store.OrganizationRoleAdmin, res.Data.Role)
+
+		user, err := memStore.UserRetrieveByEmail(context.Background(), "newcomer@gmail.com")
+		require.Nil(t, err)
+		require.NotNil(t, user.EmailVerified)
+		loginTestUser(t, app, "newcomer@gmail.com", "asdasdasd")
+
+		response = accept(fmt.Sprintf(`{"token": %q, "password": "asdasdasd"}`, plaintext))
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+
+	t.Run("Accept as an existing user", func(t *testing.T) {
+		_, plaintext := invite(t, "existing@gmail.com", "")
+
+		response := accept(fmt.Sprintf(`{"token": %q}`, plaintext))
+		require.Equal(t, http.StatusOK, response.Code)
+
+		membership, err := memStore.MembershipRetrieve(context.Background(), organization.ID, users["existing"].ID)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleMember, membership.Role)
+		loginTestUser(t, app, "existing@gmail.com", "qweqweqwe")
+	})
+
+	t.Run("Expired and invalid tokens", func(t *testing.T) {
+		invitation, plaintext := invite(t, "late@gmail.com", "")
+		_, err := memStore.InvitationRenew(context.Background(), invitation.OrganizationID, invitation.ID, token.Hash(plaintext), time.Now().Add(-time.Minute))
+		require.Nil(t, err)
+
+		response := accept(fmt.Sprintf(`{"token": %q, "password": "asdasdasd"}`, plaintext))
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = accept(`{"token": "", "password": "asdasdasd"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = accept(`{"token": "not-a-token", "password": "asdasdasd"}`)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		_, err = memStore.UserRetrieveByEmail(context.Background(), "late@gmail.com")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("Signup disabled", func(t *testing.T) {
+		app.config.signup.disabled = true
+		defer func() { app.config.signup.disabled = false }()
+
+		response := serveTestRequest(app, http.MethodPost, "/users", `{"email": "walkin@gmail.com", "password": "qweqweqwe"}`, "")
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		_, plaintext := invite(t, "invited@gmail.com", "")
+		response = accept(fmt.Sprintf(`{"token": %q, "password": "asdasdasd"}`, plaintext))
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+}
+
+func TestUserStatus(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	createTestUser(t, app, "other@gmail.com", "qweqweqwe")
+	admin := createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	status := fmt.Sprintf("/users/%s/status", user.ID)
+
+	userLogin := loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	adminLogin := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+	_, sessionCookies := sessionTestLogin(t, app, "user@gmail.com", "qweqweqwe")
+
+	response := serveTestRequest(app, http.MethodPost, "/me/api-keys", `{"name": "ci"}`, userLogin.AuthenticationToken)
+	require.Equal(t, http.StatusCreated, response.Code)
+	var keyRes resp
+	err = json.Unmarshal(response.Body.Bytes(), &keyRes)
+	require.Nil(t, err)
+	apiKey := keyRes.Data["key"].(string)
+
+	t.Run("UpdateUserStatus validation", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPut, status, `{"status": "suspended"}`, userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusForbidden, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, status, `{"status": "banished"}`, adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, fmt.Sprintf("/users/%s/status", admin.ID), `{"status": "suspended"}`, adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+
+		response = serveTestRequest(app, http.MethodPut, fmt.Sprintf("/users/%s/status", uuid.New()), `{"status": "suspended"}`, adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("Suspend", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPut, status, `{"status": "suspended", "reason": "Chargeback"}`, adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, store.UserStatusSuspended, res.Data["status"])
+		require.Equal(t, "Chargeback", res.Data["status_reason"])
+		require.NotNil(t, res.Data["status_changed_at"])
+
+		events, err := memStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserUpdateStatus, TargetID: &user.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Equal(t, admin.ID, *events.Data[0].ActorID)
+		require.Equal(t, store.AuditChange{Old: store.UserStatusActive, New: store.UserStatusSuspended}, events.Data[0].Diff["status"])
+	})
+
+	t.Run("Suspension revokes existing logins", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/me", "", userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = refreshTestToken(app, userLogin.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveSessionRequest(app, http.MethodGet, "/me", "", sessionCookies, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", apiKey)
+		require.Equal(t, http.StatusForbidden, response.Code)
+		require.Contains(t, response.Body.String(), "Your account has been suspended")
+	})
+
+	t.Run("Suspended users can't log in", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, "/authentication-tokens", `{"email": "user@gmail.com", "password": "qweqweqwe"}`, "")
+		require.Equal(t, http.StatusForbidden, response.Code)
+		require.Contains(t, response.Body.String(), "Your account has been suspended")
+
+		response = serveTestRequest(app, http.MethodPost, fmt.Sprintf("/users/%s/impersonate", user.ID), "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("ListUsers by status", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodGet, "/users?status=suspended", "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res store.UsersList
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, 1, res.TotalObjects)
+		require.Equal(t, user.ID, res.Data[0].ID)
+
+		response = serveTestRequest(app, http.MethodGet, "/users?status=active", "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, 2, res.TotalObjects)
+
+		response = serveTestRequest(app, http.MethodGet, "/users?status=banished", "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+
+	t.Run("Reactivate", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPut, status, `{"status": "active"}`, adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		login := loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+		response = serveTestRequest(app, http.MethodGet, "/me", "", login.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", apiKey)
+		require.Equal(t, http.StatusOK, response.Code)
+	})
+}
+
+func TestUserDeletion(t *testing.T) {
+	app, memStore := newAuthTestApplication()
+	app.config.userDeletion.retention = 30 * 24 * time.Hour
+	app.config.userDeletion.purgeInterval = time.Hour
+	createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+	user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+	require.Nil(t, err)
+	userLogin := loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	adminLogin := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+	restore := fmt.Sprintf("/users/%s/restore", user.ID)
+
+	deleteUser := func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodDelete, "/users/"+user.ID.String(), "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNoContent, response.Code)
+	}
+
+	t.Run("Deleted users are hidden", func(t *testing.T) {
+		deleteUser(t)
+
+		response := serveTestRequest(app, http.MethodGet, "/users/"+user.ID.String(), "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveTestRequest(app, http.MethodGet, "/users", "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res store.UsersList
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, 1, res.TotalObjects)
+
+		response = serveTestRequest(app, http.MethodGet, "/me", "", userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = refreshTestToken(app, userLogin.RefreshToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, "/authentication-tokens", `{"email": "user@gmail.com", "password": "qweqweqwe"}`, "")
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, "/users", `{"email": "user@gmail.com", "password": "qweqweqwe"}`, "")
+		require.Equal(t, http.StatusUnprocessableEntity, response.Code)
+	})
+
+	t.Run("RestoreUser", func(t *testing.T) {
+		response := serveTestRequest(app, http.MethodPost, restore, "", userLogin.AuthenticationToken)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, fmt.Sprintf("/users/%s/restore", uuid.New()), "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		response = serveTestRequest(app, http.MethodPost, restore, "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var res resp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.Equal(t, "user@gmail.com", res.Data["email"])
+
+		response = serveTestRequest(app, http.MethodPost, restore, "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		events, err := memStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserRestore, TargetID: &user.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+
+		loginTestUser(t, app, "user@gmail.com", "qweqweqwe")
+	})
+
+	t.Run("Purge after the retention period", func(t *testing.T) {
+		deleteUser(t)
+
+		purges := func() int {
+			events, err := memStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserPurge, TargetID: &user.ID, PageNumber: 1, PageSize: 10})
+			require.Nil(t, err)
+			return events.TotalObjects
+		}
+
+		app.purgeDeletedUsers(context.Background())
+		require.Zero(t, purges())
+
+		app.clock = func() time.Time { return time.Now().Add(31 * 24 * time.Hour) }
+		app.purgeDeletedUsers(context.Background())
+		app.clock = nil
+		require.Equal(t, 1, purges())
+
+		response := serveTestRequest(app, http.MethodPost, restore, "", adminLogin.AuthenticationToken)
+		require.Equal(t, http.StatusNotFound, response.Code)
+
+		createTestUser(t, app, "user@gmail.com", "asdasdasd")
+	})
+
+	t.Run("Purge stops with its context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		done := make(chan struct{})
+		go func() {
+			app.runUserPurge(ctx)
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("runUserPurge didn't return after its context was cancelled")
+		}
+	})
+}
+
+type sentEmail struct {
+	recipient string
+	data      map[string]any
+	patterns  []string
+}
+
+type fakeMailer struct {
+	mu   sync.Mutex
+	sent []sentEmail
+}
+
+// Send renders the templates like smtp.Mailer does, so broken templates
+// still fail the tests, but records the message instead of delivering it.
+func (m *fakeMailer) Send(recipient string, data any, patterns ...string) error {
+	fullPatterns := make([]string, len(patterns))
+	for i := range patterns {
+		fullPatterns[i] = "emails/" + patterns[i]
+	}
+
+	ts, err := textTemplate.New("").Funcs(funcs.TemplateFuncs).ParseFS(assets.EmbeddedFiles, fullPatterns...)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"subject", "plainBody"} {
+		err = ts.ExecuteTemplate(io.Discard, name, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	emailData, _ := data.(map[string]any)
+	m.sent = append(m.sent, sentEmail{recipient: recipient, data: emailData, patterns: patterns})
+	return nil
+}
+
+func (m *fakeMailer) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sent = nil
 }
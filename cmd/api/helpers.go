@@ -1,10 +1,103 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/internal/password"
+	"github.com/mrityunjaygr8/autostrada-test/internal/response"
+	"github.com/mrityunjaygr8/autostrada-test/internal/validator"
+	"github.com/mrityunjaygr8/autostrada-test/store"
 )
 
+func (app *application) now() time.Time {
+	if app.clock != nil {
+		return app.clock()
+	}
+
+	return time.Now()
+}
+
+// auditStore returns the store to use for changes made while handling the
+// request, so that they are recorded in the audit log against the
+// authenticated user, if there is one. Changes made while impersonating a
+// user are recorded against the admin doing so.
+func (app *application) auditStore(r *http.Request) store.GuzeiStore {
+	audit := store.AuditContext{
+		IP:        clientIP(r),
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+
+	user := contextGetAuthenticatedUser(r)
+	if actor := contextGetImpersonator(r); actor != nil {
+		user = actor
+	}
+	if user != nil {
+		audit.ActorID = &user.ID
+	}
+
+	return app.store.WithAudit(audit)
+}
+
+// continueLogin finishes a login once the user has proven their password or
+// equivalent. It rejects users who aren't active, enforces email
+// verification, responds with an MFA challenge if TOTP is enabled and
+// otherwise resets the account's failed login count and logs the user in.
+func (app *application) continueLogin(w http.ResponseWriter, r *http.Request, user *store.User, useSession bool) {
+	if user.Status != store.UserStatusActive {
+		app.accountNotActive(w, r, user)
+		return
+	}
+
+	if app.config.activation.required && user.EmailVerified == nil {
+		app.emailNotVerified(w, r)
+		return
+	}
+
+	totpEnabled, err := app.totpEnabled(r.Context(), user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if totpEnabled {
+		challenge, challengeToken, err := app.newScopedToken(r.Context(), app.store, user.ID, store.ScopeMFAChallenge, app.config.mfa.challengeTTL)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		data := map[string]any{
+			"MFARequired":    true,
+			"MFAToken":       challenge,
+			"MFATokenExpiry": challengeToken.Expiry.Format(time.RFC3339),
+		}
+
+		err = response.JSON(w, http.StatusOK, data)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Failures are only forgotten once the whole login has succeeded;
+	// completeMFAChallenge does the same after the second factor.
+	err = app.store.LoginThrottleReset(r.Context(), store.LoginThrottleAccount, emailaddr.Key(user.Email))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.writeLogin(w, r, user, useSession)
+}
+
 func (app *application) newEmailData() map[string]any {
 	data := map[string]any{
 		"BaseURL": app.config.baseURL,
@@ -13,6 +106,28 @@ func (app *application) newEmailData() map[string]any {
 	return data
 }
 
+func (app *application) sendActivationEmail(r *http.Request, user *store.User) error {
+	plaintext, activationToken, err := app.newScopedToken(r.Context(), app.store, user.ID, store.ScopeActivation, app.config.activation.tokenTTL)
+	if err != nil {
+		return err
+	}
+
+	app.mailActivationToken(r, user, plaintext, activationToken)
+	return nil
+}
+
+// mailActivationToken emails an activation token in the background. A token
+// created in a transaction should only be mailed once it has been committed.
+func (app *application) mailActivationToken(r *http.Request, user *store.User, plaintext string, activationToken store.Token) {
+	app.backgroundTask(r, func() error {
+		data := app.newEmailData()
+		data["Token"] = plaintext
+		data["Expiry"] = activationToken.Expiry.Format(time.RFC1123)
+
+		return app.mailer.Send(user.Email, data, "activation.tmpl")
+	})
+}
+
 func (app *application) backgroundTask(r *http.Request, fn func() error) {
 	app.wg.Add(1)
 
@@ -32,3 +147,94 @@ func (app *application) backgroundTask(r *http.Request, fn func() error) {
 		}
 	}()
 }
+
+// userHasPermission reports whether the authenticated user holds the
+// permission, either through their global roles or through their role in the
+// selected organization. Requests authenticated with an API key are further
+// limited to the key's scopes.
+func (app *application) userHasPermission(r *http.Request, permission string) (bool, error) {
+	membership := contextGetMembership(r)
+	if membership == nil || !validator.In(permission, organizationRolePermissions[membership.Role]...) {
+		return app.userHasGlobalPermission(r, permission)
+	}
+
+	apiKey := contextGetAPIKey(r)
+	return apiKey == nil || validator.In(permission, apiKey.Scopes...), nil
+}
+
+// userHasGlobalPermission is like userHasPermission but ignores the selected
+// organization.
+func (app *application) userHasGlobalPermission(r *http.Request, permission string) (bool, error) {
+	user := contextGetAuthenticatedUser(r)
+	if user == nil {
+		return false, nil
+	}
+
+	apiKey := contextGetAPIKey(r)
+	if apiKey != nil && !validator.In(permission, apiKey.Scopes...) {
+		return false, nil
+	}
+
+	permissions, err := app.store.UserPermissions(r.Context(), user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return validator.In(permission, permissions...), nil
+}
+
+// canManageUser reports whether the authenticated user can act on the user
+// with the permission. When an organization is selected, only its members
+// can be managed.
+func (app *application) canManageUser(r *http.Request, id uuid.UUID, permission string) (bool, error) {
+	user := contextGetAuthenticatedUser(r)
+	if user != nil && user.ID == id {
+		apiKey := contextGetAPIKey(r)
+		return apiKey == nil || validator.In(permission, apiKey.Scopes...), nil
+	}
+
+	organization := contextGetOrganization(r)
+	if organization != nil {
+		_, err := app.store.MembershipRetrieve(r.Context(), organization.ID, id)
+		if err != nil {
+			if errors.Is(err, store.ErrMembershipNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+
+	return app.userHasPermission(r, permission)
+}
+
+// normalizeEmail puts an email address from a request into its canonical
+// form. Addresses that can't be normalized are only trimmed, and are left for
+// checkEmail to reject.
+func normalizeEmail(email string) string {
+	normalized, err := emailaddr.Normalize(email)
+	if err != nil {
+		return strings.TrimSpace(email)
+	}
+	return normalized
+}
+
+func checkEmail(v *validator.Validator, email string) {
+	v.CheckField(email != "", "email", "Email is required")
+	v.CheckField(validator.Matches(email, validator.RgxEmail), "email", "Must be a valid email address")
+}
+
+func checkPassword(v *validator.Validator, key, plaintextPassword string) {
+	v.CheckField(plaintextPassword != "", key, "Password is required")
+	v.CheckField(len(plaintextPassword) >= 8, key, "Password is too short")
+	v.CheckField(len(plaintextPassword) <= 72, key, "Password is too long")
+	v.CheckField(validator.NotIn(plaintextPassword, password.CommonPasswords...), key, "Password is too common")
+}
+
+func readUUIDParam(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, name))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid " + name + " parameter")
+	}
+
+	return id, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/validator"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/pascaldekloe/jwt"
+)
+
+// impersonationActorClaim holds the admin acting as the token's subject,
+// following the actor claim of RFC 8693.
+const impersonationActorClaim = "act"
+
+// impersonatedByHeader is set on every response to an impersonated request,
+// so clients can make it obvious that someone is acting as the user.
+const impersonatedByHeader = "X-Impersonated-By"
+
+var errInvalidImpersonation = errors.New("invalid impersonation token")
+
+// newImpersonationToken issues a short-lived access token for the user that
+// records the admin acting as them. No refresh token is issued.
+func (app *application) newImpersonationToken(actor, user *store.User) (string, time.Time, error) {
+	extraClaims := map[string]any{
+		impersonationActorClaim: map[string]any{
+			"sub":             actor.ID.String(),
+			tokenVersionClaim: actor.TokenVersion,
+		},
+	}
+
+	return app.signAuthenticationToken(user, app.config.impersonation.tokenTTL, extraClaims)
+}
+
+// impersonationActor returns the admin named by an impersonation token's
+// actor claim, or nil if the token is an ordinary access token. The token is
+// rejected if the admin has since changed their password or lost the
+// users:admin permission.
+func (app *application) impersonationActor(ctx context.Context, claims *jwt.Claims) (*store.User, error) {
+	value, ok := claims.Set[impersonationActorClaim]
+	if !ok {
+		return nil, nil
+	}
+
+	act, ok := value.(map[string]any)
+	if !ok {
+		return nil, errInvalidImpersonation
+	}
+
+	subject, _ := act["sub"].(string)
+	actorID, err := uuid.Parse(subject)
+	if err != nil {
+		return nil, errInvalidImpersonation
+	}
+
+	actor, err := app.store.UserRetrieve(ctx, actorID)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			return nil, errInvalidImpersonation
+		}
+		return nil, err
+	}
+
+	version, ok := act[tokenVersionClaim].(float64)
+	if !ok || int(version) != actor.TokenVersion {
+		return nil, errInvalidImpersonation
+	}
+
+	permissions, err := app.store.UserPermissions(ctx, actor.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validator.In(store.PermissionUsersAdmin, permissions...) {
+		return nil, errInvalidImpersonation
+	}
+
+	return actor, nil
+}
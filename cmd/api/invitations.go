@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// sendInvitationEmail emails the invitation's token in the background.
+func (app *application) sendInvitationEmail(r *http.Request, organization *store.Organization, invitation *store.Invitation, plaintext string) {
+	invitedBy := contextGetAuthenticatedUser(r).Email
+
+	app.backgroundTask(r, func() error {
+		data := app.newEmailData()
+		data["Token"] = plaintext
+		data["Expiry"] = invitation.Expires.Format(time.RFC1123)
+		data["OrganizationName"] = organization.Name
+		data["Role"] = invitation.Role
+		data["InvitedBy"] = invitedBy
+
+		return app.mailer.Send(invitation.Email, data, "organization-invitation.tmpl")
+	})
+}
+
+// canManageInvitations reports whether a member with the given role can see
+// and send the organization's invitations.
+func canManageInvitations(role string) bool {
+	return role == store.OrganizationRoleOwner || role == store.OrganizationRoleAdmin
+}
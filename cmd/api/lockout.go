@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// clientIP returns the client's address without the port. middleware.RealIP
+// has already replaced RemoteAddr with the forwarded address if there is one.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// lockoutDelay doubles the lockout for every failure past the threshold, up
+// to the configured maximum.
+func (app *application) lockoutDelay(failures, threshold int) time.Duration {
+	delay := app.config.lockout.baseDelay
+	for i := threshold; i < failures && delay < app.config.lockout.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, app.config.lockout.maxDelay)
+}
+
+// loginLockedUntil returns when logins for the email address and client IP
+// are allowed again, or nil if neither is locked. Accounts are throttled by
+// emailaddr.Key, so changing the case of the address doesn't reset the count.
+func (app *application) loginLockedUntil(ctx context.Context, email, ip string) (*time.Time, error) {
+	var lockedUntil *time.Time
+
+	for kind, subject := range map[string]string{store.LoginThrottleAccount: emailaddr.Key(email), store.LoginThrottleIP: ip} {
+		throttle, err := app.store.LoginThrottleRetrieve(ctx, kind, subject)
+		switch {
+		case errors.Is(err, store.ErrLoginThrottleNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(app.now()) {
+			if lockedUntil == nil || throttle.LockedUntil.After(*lockedUntil) {
+				lockedUntil = throttle.LockedUntil
+			}
+		}
+	}
+
+	return lockedUntil, nil
+}
+
+// recordLoginFailure counts a failed login against the email address and the
+// client IP, locking either once it reaches its threshold. The user, if the
+// account exists, is emailed when their account is first locked.
+func (app *application) recordLoginFailure(r *http.Request, email string, user *store.User) error {
+	now := app.now()
+	windowStart := now.Add(-app.config.lockout.failureWindow)
+
+	thresholds := []struct {
+		kind      string
+		subject   string
+		threshold int
+	}{
+		{store.LoginThrottleAccount, emailaddr.Key(email), app.config.lockout.accountThreshold},
+		{store.LoginThrottleIP, clientIP(r), app.config.lockout.ipThreshold},
+	}
+
+	for _, t := range thresholds {
+		throttle, err := app.store.LoginFailureRecord(r.Context(), t.kind, t.subject, now, windowStart)
+		if err != nil {
+			return err
+		}
+
+		if throttle.Failures < t.threshold {
+			continue
+		}
+
+		lockedUntil := now.Add(app.lockoutDelay(throttle.Failures, t.threshold))
+		err = app.store.LoginThrottleLock(r.Context(), t.kind, t.subject, lockedUntil)
+		if err != nil {
+			return err
+		}
+
+		if t.kind == store.LoginThrottleAccount && throttle.Failures == t.threshold && user != nil {
+			app.logger.Warn("account locked", "user", user.ID.String(), "ip", clientIP(r))
+
+			app.backgroundTask(r, func() error {
+				data := app.newEmailData()
+				data["Failures"] = throttle.Failures
+				data["IP"] = clientIP(r)
+				data["LockedUntil"] = lockedUntil.Format(time.RFC1123)
+
+				return app.mailer.Send(user.Email, data, "account-locked.tmpl")
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const magicLinkNonceCookie = "magic_link_nonce"
+
+// magicLinkHash binds a magic link token to the nonce of the browser that
+// requested it. Only the hash is stored, so the link can't be used without
+// the nonce cookie, and link scanners that follow it can't consume it.
+func magicLinkHash(plaintext, nonce string) []byte {
+	return token.Hash(plaintext + ":" + nonce)
+}
+
+// setMagicLinkNonceCookie gives the browser a new nonce, replacing the one
+// from any earlier request.
+func (app *application) setMagicLinkNonceCookie(w http.ResponseWriter) (string, error) {
+	nonce, _, err := token.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkNonceCookie,
+		Value:    nonce,
+		Path:     "/magic-links",
+		MaxAge:   int(app.config.magicLink.tokenTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(app.config.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nonce, nil
+}
+
+func clearMagicLinkNonceCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkNonceCookie,
+		Value:    "",
+		Path:     "/magic-links",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// recordMagicLink counts a magic link sent to the user and reports whether
+// it's within the rate limit. Links are counted in their own throttle rather
+// than by their tokens, because using a link deletes its token.
+func (app *application) recordMagicLink(ctx context.Context, userID uuid.UUID) (bool, error) {
+	now := app.now()
+	windowStart := now.Add(-app.config.magicLink.rateInterval)
+
+	throttle, err := app.store.LoginThrottleRetrieve(ctx, store.LoginThrottleMagicLink, userID.String())
+	switch {
+	case errors.Is(err, store.ErrLoginThrottleNotFound):
+	case err != nil:
+		return false, err
+	case throttle.Failures >= app.config.magicLink.rateLimit && !throttle.LastFailure.Before(windowStart):
+		return false, nil
+	}
+
+	_, err = app.store.LoginFailureRecord(ctx, store.LoginThrottleMagicLink, userID.String(), now, windowStart)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (app *application) newMagicLink(ctx context.Context, userID uuid.UUID, nonce string) (string, store.Token, error) {
+	plaintext, _, err := token.Generate()
+	if err != nil {
+		return "", store.Token{}, err
+	}
+
+	magicLink := store.Token{
+		Hash:   magicLinkHash(plaintext, nonce),
+		UserID: userID,
+		Scope:  store.ScopeMagicLink,
+		Expiry: app.now().Add(app.config.magicLink.tokenTTL),
+	}
+
+	err = app.store.TokenInsert(ctx, magicLink)
+	if err != nil {
+		return "", store.Token{}, err
+	}
+
+	return plaintext, magicLink, nil
+}
+
+func (app *application) magicLinkURL(plaintext string, useSession bool) string {
+	query := url.Values{"token": {plaintext}}
+	if useSession {
+		query.Set("session", "true")
+	}
+
+	return app.config.magicLink.url + "?" + query.Encode()
+}
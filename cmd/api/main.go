@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/mrityunjaygr8/autostrada-test/store"
@@ -8,10 +10,13 @@ import (
 	"os"
 	"runtime/debug"
 	"sync"
+	"time"
 
 	"github.com/mrityunjaygr8/autostrada-test/internal/env"
+	memstore "github.com/mrityunjaygr8/autostrada-test/internal/memory/store"
 	pgstore "github.com/mrityunjaygr8/autostrada-test/internal/postgres/store"
 	"github.com/mrityunjaygr8/autostrada-test/internal/smtp"
+	sqlitestore "github.com/mrityunjaygr8/autostrada-test/internal/sqlite/store"
 	"github.com/mrityunjaygr8/autostrada-test/internal/version"
 
 	"github.com/lmittmann/tint"
@@ -31,12 +36,74 @@ func main() {
 type config struct {
 	baseURL  string
 	httpPort int
-	db       struct {
+	store    struct {
+		driver string
+	}
+	db struct {
 		dsn         string
 		automigrate bool
 	}
+	sqlite struct {
+		path string
+	}
 	jwt struct {
-		secretKey string
+		secretKey       string
+		accessTokenTTL  time.Duration
+		refreshTokenTTL time.Duration
+		leeway          time.Duration
+	}
+	passwordReset struct {
+		tokenTTL time.Duration
+	}
+	activation struct {
+		tokenTTL       time.Duration
+		resendInterval time.Duration
+		required       bool
+	}
+	mfa struct {
+		issuer        string
+		encryptionKey string
+		challengeTTL  time.Duration
+	}
+	oidc struct {
+		issuerURL      string
+		clientID       string
+		clientSecret   string
+		redirectURL    string
+		provisionUsers bool
+	}
+	magicLink struct {
+		tokenTTL     time.Duration
+		url          string
+		rateLimit    int
+		rateInterval time.Duration
+	}
+	sessions struct {
+		ttl time.Duration
+	}
+	impersonation struct {
+		tokenTTL time.Duration
+	}
+	invitations struct {
+		tokenTTL time.Duration
+	}
+	signup struct {
+		disabled bool
+	}
+	bootstrap struct {
+		adminEmail    string
+		adminPassword string
+	}
+	userDeletion struct {
+		retention     time.Duration
+		purgeInterval time.Duration
+	}
+	lockout struct {
+		accountThreshold int
+		ipThreshold      int
+		baseDelay        time.Duration
+		maxDelay         time.Duration
+		failureWindow    time.Duration
 	}
 	smtp struct {
 		host     string
@@ -47,11 +114,17 @@ type config struct {
 	}
 }
 
+type emailSender interface {
+	Send(recipient string, data any, patterns ...string) error
+}
+
 type application struct {
 	config config
 	store  store.GuzeiStore
 	logger *slog.Logger
-	mailer *smtp.Mailer
+	mailer emailSender
+	oidc   *oidcClient
+	clock  func() time.Time
 	wg     sync.WaitGroup
 }
 
@@ -60,9 +133,43 @@ func run(logger *slog.Logger) error {
 
 	cfg.baseURL = env.GetString("BASE_URL", "http://localhost:4444")
 	cfg.httpPort = env.GetInt("HTTP_PORT", 4444)
+	cfg.store.driver = env.GetString("STORE_DRIVER", "postgres")
 	cfg.db.dsn = env.GetString("DB_DSN", "user:pass@localhost:5432/db")
 	cfg.db.automigrate = env.GetBool("DB_AUTOMIGRATE", true)
+	cfg.sqlite.path = env.GetString("SQLITE_PATH", "guzei.db")
 	cfg.jwt.secretKey = env.GetString("JWT_SECRET_KEY", "xl3e7tqjfreubzdnjlomzqr7q6x6sfni")
+	cfg.jwt.accessTokenTTL = env.GetDuration("JWT_ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.jwt.refreshTokenTTL = env.GetDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	cfg.jwt.leeway = env.GetDuration("JWT_LEEWAY", time.Minute)
+	cfg.passwordReset.tokenTTL = env.GetDuration("PASSWORD_RESET_TOKEN_TTL", 45*time.Minute)
+	cfg.activation.tokenTTL = env.GetDuration("ACTIVATION_TOKEN_TTL", 3*24*time.Hour)
+	cfg.activation.resendInterval = env.GetDuration("ACTIVATION_RESEND_INTERVAL", 5*time.Minute)
+	cfg.activation.required = env.GetBool("REQUIRE_VERIFIED_EMAIL", false)
+	cfg.mfa.issuer = env.GetString("MFA_ISSUER", "Guzei")
+	cfg.mfa.encryptionKey = env.GetString("MFA_ENCRYPTION_KEY", defaultMFAEncryptionKey)
+	cfg.mfa.challengeTTL = env.GetDuration("MFA_CHALLENGE_TTL", 5*time.Minute)
+	cfg.oidc.issuerURL = env.GetString("OIDC_ISSUER_URL", "")
+	cfg.oidc.clientID = env.GetString("OIDC_CLIENT_ID", "")
+	cfg.oidc.clientSecret = env.GetString("OIDC_CLIENT_SECRET", "")
+	cfg.oidc.redirectURL = env.GetString("OIDC_REDIRECT_URL", cfg.baseURL+"/oidc/callback")
+	cfg.oidc.provisionUsers = env.GetBool("OIDC_PROVISION_USERS", true)
+	cfg.magicLink.tokenTTL = env.GetDuration("MAGIC_LINK_TTL", 15*time.Minute)
+	cfg.magicLink.url = env.GetString("MAGIC_LINK_URL", cfg.baseURL+"/magic-links/exchange")
+	cfg.magicLink.rateLimit = env.GetInt("MAGIC_LINK_RATE_LIMIT", 3)
+	cfg.magicLink.rateInterval = env.GetDuration("MAGIC_LINK_RATE_INTERVAL", time.Hour)
+	cfg.sessions.ttl = env.GetDuration("SESSION_TTL", 7*24*time.Hour)
+	cfg.impersonation.tokenTTL = env.GetDuration("IMPERSONATION_TOKEN_TTL", 15*time.Minute)
+	cfg.invitations.tokenTTL = env.GetDuration("INVITATION_TTL", 7*24*time.Hour)
+	cfg.signup.disabled = env.GetBool("DISABLE_SIGNUP", false)
+	cfg.bootstrap.adminEmail = env.GetString("BOOTSTRAP_ADMIN_EMAIL", "")
+	cfg.bootstrap.adminPassword = env.GetString("BOOTSTRAP_ADMIN_PASSWORD", "")
+	cfg.userDeletion.retention = env.GetDuration("USER_DELETION_RETENTION", 30*24*time.Hour)
+	cfg.userDeletion.purgeInterval = env.GetDuration("USER_PURGE_INTERVAL", time.Hour)
+	cfg.lockout.accountThreshold = env.GetInt("LOGIN_LOCKOUT_ACCOUNT_THRESHOLD", 5)
+	cfg.lockout.ipThreshold = env.GetInt("LOGIN_LOCKOUT_IP_THRESHOLD", 20)
+	cfg.lockout.baseDelay = env.GetDuration("LOGIN_LOCKOUT_BASE_DELAY", time.Minute)
+	cfg.lockout.maxDelay = env.GetDuration("LOGIN_LOCKOUT_MAX_DELAY", time.Hour)
+	cfg.lockout.failureWindow = env.GetDuration("LOGIN_FAILURE_WINDOW", 24*time.Hour)
 	cfg.smtp.host = env.GetString("SMTP_HOST", "example.smtp.host")
 	cfg.smtp.port = env.GetInt("SMTP_PORT", 25)
 	cfg.smtp.username = env.GetString("SMTP_USERNAME", "example_username")
@@ -78,7 +185,17 @@ func run(logger *slog.Logger) error {
 		return nil
 	}
 
-	pgStore, closer, err := pgstore.NewPostgresStore(cfg.db.dsn, cfg.db.automigrate)
+	err := checkMFAEncryptionKey(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = checkUserDeletion(cfg)
+	if err != nil {
+		return err
+	}
+
+	guzeiStore, closer, err := newStore(cfg, logger)
 	if err != nil {
 		return err
 	}
@@ -86,12 +203,75 @@ func run(logger *slog.Logger) error {
 
 	mailer := smtp.NewMailer(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.from)
 
+	var sso *oidcClient
+	if cfg.oidc.issuerURL != "" {
+		sso, err = newOIDCClient(context.Background(), cfg.oidc.issuerURL, cfg.oidc.clientID, cfg.oidc.clientSecret, cfg.oidc.redirectURL)
+		if err != nil {
+			return err
+		}
+	}
+
 	app := &application{
 		config: cfg,
-		store:  pgStore,
+		store:  guzeiStore,
 		logger: logger,
 		mailer: mailer,
+		oidc:   sso,
+	}
+
+	err = app.bootstrapAdmin(context.Background())
+	if err != nil {
+		return err
 	}
 
 	return app.serveHTTP()
 }
+
+// defaultMFAEncryptionKey is published with the code, so it only protects
+// TOTP secrets held by the in-memory store, which never leave the process.
+const defaultMFAEncryptionKey = "7ncm2c5oa4ufdvoqbyxgbjr3ezmvoswh"
+
+// checkMFAEncryptionKey refuses to start with a key that can't encrypt TOTP
+// secrets, rather than failing when a user enrolls, or with the default key
+// for a store that keeps the secrets on disk.
+func checkMFAEncryptionKey(cfg config) error {
+	switch len(cfg.mfa.encryptionKey) {
+	case 16, 24, 32:
+	default:
+		return fmt.Errorf("MFA_ENCRYPTION_KEY must be 16, 24 or 32 bytes long, not %d", len(cfg.mfa.encryptionKey))
+	}
+
+	if cfg.mfa.encryptionKey == defaultMFAEncryptionKey && cfg.store.driver != "memory" {
+		return errors.New("MFA_ENCRYPTION_KEY must be set to your own random key")
+	}
+	return nil
+}
+
+// checkUserDeletion refuses to start with settings the purge job can't use.
+// A retention of zero purges deleted users on the next run.
+func checkUserDeletion(cfg config) error {
+	if cfg.userDeletion.purgeInterval <= 0 {
+		return fmt.Errorf("USER_PURGE_INTERVAL must be positive, not %s", cfg.userDeletion.purgeInterval)
+	}
+
+	if cfg.userDeletion.retention < 0 {
+		return fmt.Errorf("USER_DELETION_RETENTION can't be negative, not %s", cfg.userDeletion.retention)
+	}
+	return nil
+}
+
+// newStore opens the store selected by STORE_DRIVER. The returned function
+// releases it when the application exits.
+func newStore(cfg config, logger *slog.Logger) (store.GuzeiStore, func(), error) {
+	switch cfg.store.driver {
+	case "postgres":
+		return pgstore.NewPostgresStore(cfg.db.dsn, cfg.db.automigrate)
+	case "sqlite":
+		return sqlitestore.NewSQLiteStore(cfg.sqlite.path, cfg.db.automigrate)
+	case "memory":
+		logger.Warn("using the in-memory store, all data will be lost when the application exits")
+		return memstore.NewMemoryStore(), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown store driver %q", cfg.store.driver)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMFAEncryptionKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		key    string
+		valid  bool
+	}{
+		{"own key", "postgres", "b8xvy2u9h6jqk3dw5r4tzp7mfn1ca0se", true},
+		{"16-byte key", "sqlite", "b8xvy2u9h6jqk3dw", true},
+		{"default key with the memory store", "memory", defaultMFAEncryptionKey, true},
+		{"default key with postgres", "postgres", defaultMFAEncryptionKey, false},
+		{"default key with sqlite", "sqlite", defaultMFAEncryptionKey, false},
+		{"empty key", "memory", "", false},
+		{"short key", "postgres", "b8xvy2u9h6jqk3d", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config
+			cfg.store.driver = tt.driver
+			cfg.mfa.encryptionKey = tt.key
+
+			err := checkMFAEncryptionKey(cfg)
+			if tt.valid {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckUserDeletion(t *testing.T) {
+	tests := []struct {
+		name          string
+		retention     time.Duration
+		purgeInterval time.Duration
+		valid         bool
+	}{
+		{"defaults", 30 * 24 * time.Hour, time.Hour, true},
+		{"no retention", 0, time.Hour, true},
+		{"negative retention", -time.Hour, time.Hour, false},
+		{"zero interval", 30 * 24 * time.Hour, 0, false},
+		{"negative interval", 30 * 24 * time.Hour, -time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config
+			cfg.userDeletion.retention = tt.retention
+			cfg.userDeletion.purgeInterval = tt.purgeInterval
+
+			err := checkUserDeletion(cfg)
+			if tt.valid {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	t.Run("Creates the admin", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+		app.config.bootstrap.adminEmail = "Admin@Gmail.com"
+		app.config.bootstrap.adminPassword = "qweqweqwe"
+
+		err := app.bootstrapAdmin(context.Background())
+		require.Nil(t, err)
+
+		admin, err := memStore.UserRetrieveByEmail(context.Background(), "admin@gmail.com")
+		require.Nil(t, err)
+		require.True(t, admin.Admin)
+		require.NotNil(t, admin.EmailVerified)
+		loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+
+		// Restarting leaves the admin alone.
+		err = app.bootstrapAdmin(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, 1, countTestUsers(t, memStore))
+	})
+
+	t.Run("Grants the existing user admin", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+		createTestUser(t, app, "user@gmail.com", "qweqweqwe")
+		app.config.bootstrap.adminEmail = "user@gmail.com"
+
+		err := app.bootstrapAdmin(context.Background())
+		require.Nil(t, err)
+
+		user, err := memStore.UserRetrieveByEmail(context.Background(), "user@gmail.com")
+		require.Nil(t, err)
+		require.True(t, user.Admin)
+	})
+
+	t.Run("Unknown user without a password", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+		app.config.bootstrap.adminEmail = "admin@gmail.com"
+
+		err := app.bootstrapAdmin(context.Background())
+		require.Nil(t, err)
+		require.Zero(t, countTestUsers(t, memStore))
+	})
+
+	t.Run("Invalid settings", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+		app.config.bootstrap.adminEmail = "admin"
+		require.NotNil(t, app.bootstrapAdmin(context.Background()))
+
+		app.config.bootstrap.adminEmail = "admin@gmail.com"
+		app.config.bootstrap.adminPassword = "short"
+		require.NotNil(t, app.bootstrapAdmin(context.Background()))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		app, memStore := newAuthTestApplication()
+
+		err := app.bootstrapAdmin(context.Background())
+		require.Nil(t, err)
+		require.Zero(t, countTestUsers(t, memStore))
+	})
+}
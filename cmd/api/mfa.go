@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image/png"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/secret"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const recoveryCodeCount = 10
+
+const qrCodeSize = 256
+
+var totpValidateOpts = totp.ValidateOpts{
+	Period:    30,
+	Skew:      1,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+func (app *application) newTOTPKey(user *store.User) (*otp.Key, []byte, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      app.config.mfa.issuer,
+		AccountName: user.Email,
+		Period:      totpValidateOpts.Period,
+		Digits:      totpValidateOpts.Digits,
+		Algorithm:   totpValidateOpts.Algorithm,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryptedSecret, err := secret.Encrypt([]byte(app.config.mfa.encryptionKey), []byte(key.Secret()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, encryptedSecret, nil
+}
+
+func (app *application) validateTOTPCode(userTOTP *store.TOTP, code string) (bool, error) {
+	totpSecret, err := secret.Decrypt([]byte(app.config.mfa.encryptionKey), userTOTP.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	return totp.ValidateCustom(code, string(totpSecret), app.now(), totpValidateOpts)
+}
+
+// totpEnabled reports whether the user has a confirmed TOTP enrollment.
+func (app *application) totpEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	userTOTP, err := app.store.TOTPRetrieve(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrTOTPNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return userTOTP.Confirmed != nil, nil
+}
+
+func qrCodePNG(key *otp.Key) (string, error) {
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return "", err
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([][]byte, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, hash, err := token.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+
+	return codes, hashes, nil
+}
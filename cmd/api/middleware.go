@@ -1,7 +1,13 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
 )
 
 func (app *application) recoverPanic(next http.Handler) http.Handler {
@@ -19,54 +25,98 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//w.Header().Add("Vary", "Authorization")
-		//
-		//authorizationHeader := r.Header.Get("Authorization")
-		//
-		//if authorizationHeader != "" {
-		//	headerParts := strings.Split(authorizationHeader, " ")
-		//
-		//	if len(headerParts) == 2 && headerParts[0] == "Bearer" {
-		//		token := headerParts[1]
-		//
-		//		claims, err := jwt.HMACCheck([]byte(token), []byte(app.config.jwt.secretKey))
-		//		if err != nil {
-		//			app.invalidAuthenticationToken(w, r)
-		//			return
-		//		}
-		//
-		//		if !claims.Valid(time.Now()) {
-		//			app.invalidAuthenticationToken(w, r)
-		//			return
-		//		}
-		//
-		//		if claims.Issuer != app.config.baseURL {
-		//			app.invalidAuthenticationToken(w, r)
-		//			return
-		//		}
-		//
-		//		if !claims.AcceptAudience(app.config.baseURL) {
-		//			app.invalidAuthenticationToken(w, r)
-		//			return
-		//		}
-		//
-		//		userID, err := strconv.Atoi(claims.Subject)
-		//		if err != nil {
-		//			app.serverError(w, r, err)
-		//			return
-		//		}
-		//
-		//		user, err := app.store.UserRetrieve(userID)
-		//		if err != nil {
-		//			app.serverError(w, r, err)
-		//			return
-		//		}
-		//
-		//		if user != nil {
-		//			r = contextSetAuthenticatedUser(r, user)
-		//		}
-		//	}
-		//}
+		w.Header().Add("Vary", "Authorization")
+		w.Header().Add("Vary", "Cookie")
+		w.Header().Add("Vary", organizationHeader)
+
+		authorizationHeader := r.Header.Get("Authorization")
+
+		if authorizationHeader != "" {
+			headerParts := strings.Split(authorizationHeader, " ")
+
+			if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+				app.invalidAuthenticationToken(w, r)
+				return
+			}
+
+			if strings.HasPrefix(headerParts[1], apiKeyPrefix) {
+				r, ok := app.authenticateAPIKey(w, r, headerParts[1])
+				if ok {
+					r, ok = app.authenticateOrganization(w, r, "")
+				}
+				if ok {
+					next.ServeHTTP(w, r)
+				}
+				return
+			}
+
+			claims, err := app.checkAuthenticationToken(headerParts[1])
+			if err != nil {
+				app.invalidAuthenticationToken(w, r)
+				return
+			}
+
+			userID, err := uuid.Parse(claims.Subject)
+			if err != nil {
+				app.invalidAuthenticationToken(w, r)
+				return
+			}
+
+			user, err := app.store.UserRetrieve(r.Context(), userID)
+			if err != nil {
+				switch {
+				case errors.Is(err, store.ErrUserNotFound):
+					app.invalidAuthenticationToken(w, r)
+				default:
+					app.serverError(w, r, err)
+				}
+				return
+			}
+
+			if !tokenVersionMatches(claims, user) {
+				app.invalidAuthenticationToken(w, r)
+				return
+			}
+
+			if user.Status != store.UserStatusActive {
+				app.accountNotActive(w, r, user)
+				return
+			}
+
+			actor, err := app.impersonationActor(r.Context(), claims)
+			if err != nil {
+				switch {
+				case errors.Is(err, errInvalidImpersonation):
+					app.invalidAuthenticationToken(w, r)
+				default:
+					app.serverError(w, r, err)
+				}
+				return
+			}
+
+			if actor != nil {
+				w.Header().Set(impersonatedByHeader, actor.ID.String())
+				r = contextSetImpersonator(r, actor)
+			}
+
+			r = contextSetAuthenticatedUser(r, user)
+
+			claimedOrganization, _ := claims.String(organizationClaim)
+			var ok bool
+			r, ok = app.authenticateOrganization(w, r, claimedOrganization)
+			if !ok {
+				return
+			}
+		} else if cookie, err := r.Cookie(sessionCookie); err == nil {
+			r, ok := app.authenticateSession(w, r, cookie.Value)
+			if ok && contextGetAuthenticatedUser(r) != nil {
+				r, ok = app.authenticateOrganization(w, r, "")
+			}
+			if ok {
+				next.ServeHTTP(w, r)
+			}
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	})
@@ -74,12 +124,144 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 
 func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		//authenticatedUser := contextGetAuthenticatedUser(r)
-		//
-		//if authenticatedUser == nil {
-		//	app.authenticationRequired(w, r)
-		//	return
-		//}
+		authenticatedUser := contextGetAuthenticatedUser(r)
+
+		if authenticatedUser == nil {
+			app.authenticationRequired(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authenticatedUser := contextGetAuthenticatedUser(r)
+
+			if authenticatedUser == nil {
+				app.authenticationRequired(w, r)
+				return
+			}
+
+			permitted, err := app.userHasPermission(r, permission)
+			if err != nil {
+				app.serverError(w, r, err)
+				return
+			}
+
+			if !permitted {
+				app.notPermitted(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authenticateAPIKey resolves an API key presented as a bearer token. It
+// writes an error response and returns false if the key is not valid.
+func (app *application) authenticateAPIKey(w http.ResponseWriter, r *http.Request, plaintext string) (*http.Request, bool) {
+	apiKey, err := app.store.APIKeyUse(r.Context(), token.Hash(plaintext))
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAPIKeyNotFound):
+			app.invalidAuthenticationToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return r, false
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), apiKey.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			app.invalidAuthenticationToken(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return r, false
+	}
+
+	if user.Status != store.UserStatusActive {
+		app.accountNotActive(w, r, user)
+		return r, false
+	}
+
+	r = contextSetAuthenticatedUser(r, user)
+	r = contextSetAPIKey(r, apiKey)
+	return r, true
+}
+
+// authenticateSession resolves a session cookie and extends the session. An
+// unknown or expired session is treated as anonymous and its cookies are
+// cleared. It writes an error response and returns false if an unsafe
+// request is missing the session's CSRF token.
+func (app *application) authenticateSession(w http.ResponseWriter, r *http.Request, sessionToken string) (*http.Request, bool) {
+	session, err := app.store.SessionUse(r.Context(), token.Hash(sessionToken), app.sessionExpiry())
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrSessionNotFound):
+			clearSessionCookies(w)
+			return r, true
+		default:
+			app.serverError(w, r, err)
+			return r, false
+		}
+	}
+
+	if !app.csrfTokenValid(r, sessionToken) {
+		app.invalidCSRFToken(w, r)
+		return r, false
+	}
+
+	user, err := app.store.UserRetrieve(r.Context(), session.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			clearSessionCookies(w)
+			return r, true
+		default:
+			app.serverError(w, r, err)
+			return r, false
+		}
+	}
+
+	if user.Status != store.UserStatusActive {
+		clearSessionCookies(w)
+		app.accountNotActive(w, r, user)
+		return r, false
+	}
+
+	app.setSessionCookies(w, sessionToken)
+
+	r = contextSetAuthenticatedUser(r, user)
+	r = contextSetSession(r, session)
+	return r, true
+}
+
+func (app *application) forbidAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contextGetAPIKey(r) != nil {
+			app.apiKeyNotPermitted(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forbidImpersonation stops admins from changing a user's credentials while
+// impersonating them.
+func (app *application) forbidImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contextGetImpersonator(r) != nil {
+			app.impersonationNotPermitted(w, r)
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	})
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/password"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/pascaldekloe/jwt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+var errInvalidOIDCState = errors.New("invalid or expired login state")
+var errUnverifiedEmail = errors.New("identity provider did not verify the email address")
+
+var errUnverifiedAccount = errors.New("existing account's email address is not verified")
+
+type oidcClient struct {
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+}
+
+// newOIDCClient fetches the provider's discovery document, which also tells
+// the verifier where to find the JWKS used to check ID token signatures.
+func newOIDCClient(ctx context.Context, issuerURL, clientID, clientSecret, redirectURL string) (*oidcClient, error) {
+	provider, err := oidc.NewProvider(ctx, issuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &oidcClient{
+		provider: provider,
+		verifier: provider.Verifier(&oidc.Config{ClientID: clientID}),
+		oauth2: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+	}
+
+	return client, nil
+}
+
+type oidcState struct {
+	State    string
+	Nonce    string
+	Verifier string
+	Session  bool
+}
+
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+func (app *application) oidcStateAudience() string {
+	return app.config.baseURL + "/oidc"
+}
+
+// setOIDCStateCookie stores the login state in a short-lived cookie signed
+// with the JWT secret, so the callback can be handled by any instance.
+func (app *application) setOIDCStateCookie(w http.ResponseWriter, state oidcState) error {
+	now := app.now()
+
+	var claims jwt.Claims
+	claims.Issued = jwt.NewNumericTime(now)
+	claims.Expires = jwt.NewNumericTime(now.Add(oidcStateTTL))
+	claims.Issuer = app.config.baseURL
+	claims.Audiences = []string{app.oidcStateAudience()}
+	claims.Set = map[string]interface{}{
+		"state":    state.State,
+		"nonce":    state.Nonce,
+		"verifier": state.Verifier,
+		"session":  state.Session,
+	}
+
+	jwtBytes, err := claims.HMACSign(jwt.HS256, []byte(app.config.jwt.secretKey))
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    string(jwtBytes),
+		Path:     "/oidc",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(app.config.baseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+func (app *application) readOIDCStateCookie(r *http.Request) (*oidcState, error) {
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		return nil, errInvalidOIDCState
+	}
+
+	claims, err := jwt.HMACCheck([]byte(cookie.Value), []byte(app.config.jwt.secretKey))
+	if err != nil {
+		return nil, errInvalidOIDCState
+	}
+
+	if claims.Expires == nil || claims.AcceptTemporal(app.now(), app.config.jwt.leeway) != nil {
+		return nil, errInvalidOIDCState
+	}
+
+	if claims.Issuer != app.config.baseURL || !claims.AcceptAudience(app.oidcStateAudience()) {
+		return nil, errInvalidOIDCState
+	}
+
+	state, _ := claims.String("state")
+	nonce, _ := claims.String("nonce")
+	verifier, _ := claims.String("verifier")
+	if state == "" || nonce == "" || verifier == "" {
+		return nil, errInvalidOIDCState
+	}
+
+	session, _ := claims.Set["session"].(bool)
+
+	return &oidcState{State: state, Nonce: nonce, Verifier: verifier, Session: session}, nil
+}
+
+func clearOIDCStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     "/oidc",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// resolveOIDCUser finds the user linked to the ID token's subject. Unknown
+// subjects are linked to the existing user with the same verified email
+// address, as long as the user has verified it too, or to a newly
+// provisioned user if provisioning is enabled.
+func (app *application) resolveOIDCUser(r *http.Request, idToken *oidc.IDToken, claims oidcClaims) (*store.User, error) {
+	identity, err := app.store.IdentityRetrieve(r.Context(), idToken.Issuer, idToken.Subject)
+	switch {
+	case err == nil:
+		return app.store.UserRetrieve(r.Context(), identity.UserID)
+	case !errors.Is(err, store.ErrIdentityNotFound):
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errUnverifiedEmail
+	}
+	claims.Email = normalizeEmail(claims.Email)
+
+	user, err := app.store.UserRetrieveByEmail(r.Context(), claims.Email)
+	switch {
+	case errors.Is(err, store.ErrUserNotFound):
+		if !app.config.oidc.provisionUsers {
+			return nil, store.ErrUserNotFound
+		}
+
+		user, err = app.provisionOIDCUser(r, claims.Email)
+		if err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case user.EmailVerified == nil:
+		// Anyone can sign up with an address they don't own, so linking
+		// to an unverified account would hand it to whoever created it.
+		return nil, errUnverifiedAccount
+	}
+
+	_, err = app.store.IdentityLink(r.Context(), store.Identity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		UserID:  user.ID,
+		Email:   claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provisionOIDCUser creates a verified user with a random password. The user
+// can set a real password later through the password reset flow.
+func (app *application) provisionOIDCUser(r *http.Request, email string) (*store.User, error) {
+	randomPassword, _, err := token.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := password.Hash(randomPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *store.User
+	err = app.auditStore(r).WithinTx(r.Context(), store.TxOptions{}, func(tx store.Tx) error {
+		inserted, err := tx.UserInsert(r.Context(), email, hashedPassword, uuid.New(), false)
+		if err != nil {
+			return err
+		}
+
+		err = tx.UserVerifyEmail(r.Context(), inserted.ID)
+		if err != nil {
+			return err
+		}
+
+		user, err = tx.UserRetrieve(r.Context(), inserted.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	memstore "github.com/mrityunjaygr8/autostrada-test/internal/memory/store"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+const fakeOIDCClientID = "guzei-test"
+
+type fakeOIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type fakeOIDCAuthorization struct {
+	identity      fakeOIDCIdentity
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// fakeOIDCProvider is a minimal OpenID Connect provider supporting discovery,
+// JWKS and the authorization code flow with PKCE.
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	// signingKey, when set, signs ID tokens instead of the published key.
+	signingKey *rsa.PrivateKey
+
+	mu             sync.Mutex
+	identity       fakeOIDCIdentity
+	nonceOverride  string
+	authorizations map[string]fakeOIDCAuthorization
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	provider := &fakeOIDCProvider{
+		key:            key,
+		authorizations: make(map[string]fakeOIDCAuthorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", provider.discovery)
+	mux.HandleFunc("/jwks", provider.jwks)
+	mux.HandleFunc("/authorize", provider.authorize)
+	mux.HandleFunc("/token", provider.token)
+
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+func (p *fakeOIDCProvider) setIdentity(identity fakeOIDCIdentity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.identity = identity
+}
+
+func (p *fakeOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *fakeOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       &p.key.PublicKey,
+		KeyID:     "test",
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}}})
+}
+
+func (p *fakeOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != fakeOIDCClientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	codeBytes := make([]byte, 16)
+	if _, err := rand.Read(codeBytes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	code := base64.RawURLEncoding.EncodeToString(codeBytes)
+
+	p.mu.Lock()
+	p.authorizations[code] = fakeOIDCAuthorization{
+		identity:      p.identity,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectURI:   query.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	authorization, ok := p.authorizations[r.PostForm.Get("code")]
+	delete(p.authorizations, r.PostForm.Get("code"))
+	nonce := authorization.nonce
+	if p.nonceOverride != "" {
+		nonce = p.nonceOverride
+	}
+	key := p.key
+	if p.signingKey != nil {
+		key = p.signingKey
+	}
+	p.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != authorization.codeChallenge || r.PostForm.Get("redirect_uri") != authorization.redirectURI {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	idToken, err := jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:   p.server.URL,
+		Subject:  authorization.identity.Subject,
+		Audience: jwt.Audience{fakeOIDCClientID},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}).Claims(map[string]any{
+		"nonce":          nonce,
+		"email":          authorization.identity.Email,
+		"email_verified": authorization.identity.EmailVerified,
+	}).CompactSerialize()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func newOIDCTestApplication(t *testing.T) (*application, *memstore.MemoryStore, *fakeOIDCProvider) {
+	app, memStore := newAuthTestApplication()
+	app.config.oidc.provisionUsers = true
+	provider := newFakeOIDCProvider(t)
+
+	sso, err := newOIDCClient(context.Background(), provider.server.URL, fakeOIDCClientID, "secret", app.config.baseURL+"/oidc/callback")
+	require.Nil(t, err)
+	app.oidc = sso
+
+	return app, memStore, provider
+}
+
+func countTestUsers(t *testing.T, memStore *memstore.MemoryStore) int {
+	users, err := memStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 1})
+	require.Nil(t, err)
+	return users.TotalObjects
+}
+
+// oidcTestLogin runs the browser side of the flow: start the login, follow
+// the redirect to the provider and return the callback URL and state cookie.
+func oidcTestLogin(t *testing.T, app *application) (string, *http.Cookie) {
+	response := serveTestRequest(app, http.MethodGet, "/oidc/login", "", "")
+	require.Equal(t, http.StatusFound, response.Code)
+
+	cookies := response.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, oidcStateCookie, cookies[0].Name)
+	require.True(t, cookies[0].HttpOnly)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	providerResponse, err := client.Get(response.Header().Get("Location"))
+	require.Nil(t, err)
+	defer providerResponse.Body.Close()
+	require.Equal(t, http.StatusFound, providerResponse.StatusCode)
+
+	callback, err := url.Parse(providerResponse.Header.Get("Location"))
+	require.Nil(t, err)
+
+	return callback.RequestURI(), cookies[0]
+}
+
+func serveOIDCCallback(app *application, target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+
+	app.routes().ServeHTTP(response, request)
+
+	return response
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Run("OIDC disabled", func(t *testing.T) {
+		app, _ := newAuthTestApplication()
+
+		response := serveTestRequest(app, http.MethodGet, "/oidc/login", "", "")
+		require.Equal(t, http.StatusNotFound, response.Code)
+	})
+
+	t.Run("Just-in-time provisioning", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-1", Email: "sso@gmail.com", EmailVerified: true})
+
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res authenticationTokenResp
+		err := json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotEmpty(t, res.AuthenticationToken)
+		require.NotEmpty(t, res.RefreshToken)
+
+		user, err := memStore.UserRetrieveByEmail(context.Background(), "sso@gmail.com")
+		require.Nil(t, err)
+		require.NotNil(t, user.EmailVerified)
+
+		identity, err := memStore.IdentityRetrieve(context.Background(), provider.server.URL, "sub-1")
+		require.Nil(t, err)
+		require.Equal(t, user.ID, identity.UserID)
+
+		// Authorization codes are single use.
+		response = serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		// Later logins resolve the subject even if the email changes.
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-1", Email: "changed@gmail.com", EmailVerified: true})
+		target, cookie = oidcTestLogin(t, app)
+		response = serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, 1, countTestUsers(t, memStore))
+	})
+
+	t.Run("Link existing user by verified email", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		createTestUser(t, app, "existing@gmail.com", "qweqweqwe")
+		existing, err := memStore.UserRetrieveByEmail(context.Background(), "existing@gmail.com")
+		require.Nil(t, err)
+		err = memStore.UserVerifyEmail(context.Background(), existing.ID)
+		require.Nil(t, err)
+
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-2", Email: "existing@gmail.com", EmailVerified: true})
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res authenticationTokenResp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		response = serveTestRequest(app, http.MethodGet, "/me", "", res.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var me resp
+		err = json.Unmarshal(response.Body.Bytes(), &me)
+		require.Nil(t, err)
+		require.Equal(t, existing.ID.String(), me.Data["id"])
+		require.Equal(t, 1, countTestUsers(t, memStore))
+	})
+
+	t.Run("TOTP still required", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		now := time.Now().Truncate(30 * time.Second)
+		app.clock = func() time.Time { return now }
+		admin := createTestAdmin(t, app, memStore, "admin@gmail.com", "qweqweqwe")
+		err := memStore.UserVerifyEmail(context.Background(), admin.ID)
+		require.Nil(t, err)
+		login := loginTestUser(t, app, "admin@gmail.com", "qweqweqwe")
+
+		response := serveTestRequest(app, http.MethodPost, "/me/mfa/totp", "", login.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+		var enrollment resp
+		err = json.Unmarshal(response.Body.Bytes(), &enrollment)
+		require.Nil(t, err)
+		totpSecret := enrollment.Data["secret"].(string)
+		code, err := totp.GenerateCode(totpSecret, now)
+		require.Nil(t, err)
+		response = serveTestRequest(app, http.MethodPost, "/me/mfa/totp/confirm", fmt.Sprintf(`{"code": %q}`, code), login.AuthenticationToken)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-8", Email: "admin@gmail.com", EmailVerified: true})
+		target, cookie := oidcTestLogin(t, app)
+		response = serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var challenge mfaChallengeResp
+		err = json.Unmarshal(response.Body.Bytes(), &challenge)
+		require.Nil(t, err)
+		require.True(t, challenge.MFARequired)
+		require.NotContains(t, response.Body.String(), "AuthenticationToken")
+
+		now = now.Add(time.Minute)
+		code, err = totp.GenerateCode(totpSecret, now)
+		require.Nil(t, err)
+		response = serveTestRequest(app, http.MethodPost, "/authentication-tokens/mfa", fmt.Sprintf(`{"mfa_token": %q, "code": %q}`, challenge.MFAToken, code), "")
+		require.Equal(t, http.StatusOK, response.Code)
+
+		var res authenticationTokenResp
+		err = json.Unmarshal(response.Body.Bytes(), &res)
+		require.Nil(t, err)
+		require.NotEmpty(t, res.AuthenticationToken)
+	})
+
+	t.Run("Unverified local account", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		createTestUser(t, app, "existing@gmail.com", "qweqweqwe")
+
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-9", Email: "existing@gmail.com", EmailVerified: true})
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		_, err := memStore.IdentityRetrieve(context.Background(), provider.server.URL, "sub-9")
+		require.Equal(t, store.ErrIdentityNotFound, err)
+		require.Equal(t, 1, countTestUsers(t, memStore))
+	})
+
+	t.Run("Unverified email", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		createTestUser(t, app, "existing@gmail.com", "qweqweqwe")
+
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-3", Email: "existing@gmail.com", EmailVerified: false})
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+
+		_, err := memStore.IdentityRetrieve(context.Background(), provider.server.URL, "sub-3")
+		require.NotNil(t, err)
+	})
+
+	t.Run("Provisioning disabled", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		app.config.oidc.provisionUsers = false
+
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-4", Email: "new@gmail.com", EmailVerified: true})
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Zero(t, countTestUsers(t, memStore))
+	})
+
+	t.Run("Invalid state", func(t *testing.T) {
+		app, _, provider := newOIDCTestApplication(t)
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-5", Email: "sso@gmail.com", EmailVerified: true})
+
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, nil)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+
+		_, otherCookie := oidcTestLogin(t, app)
+		response = serveOIDCCallback(app, target, otherCookie)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+
+		tampered := *cookie
+		tampered.Value += "x"
+		response = serveOIDCCallback(app, target, &tampered)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
+	t.Run("Nonce mismatch", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-6", Email: "sso@gmail.com", EmailVerified: true})
+		provider.nonceOverride = "replayed"
+
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Zero(t, countTestUsers(t, memStore))
+	})
+
+	t.Run("Forged ID token signature", func(t *testing.T) {
+		app, memStore, provider := newOIDCTestApplication(t)
+		provider.setIdentity(fakeOIDCIdentity{Subject: "sub-7", Email: "sso@gmail.com", EmailVerified: true})
+
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.Nil(t, err)
+		provider.signingKey = otherKey
+
+		target, cookie := oidcTestLogin(t, app)
+		response := serveOIDCCallback(app, target, cookie)
+		require.Equal(t, http.StatusUnauthorized, response.Code)
+		require.Zero(t, countTestUsers(t, memStore))
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// organizationHeader selects the organization a request acts in. Access
+// tokens issued for an organization carry it in organizationClaim instead.
+const (
+	organizationHeader = "X-Organization-ID"
+	organizationClaim  = "org"
+)
+
+var errOrganizationMismatch = errors.New("the " + organizationHeader + " header doesn't match the authentication token's organization")
+
+// organizationRolePermissions lists the permissions that an organization role
+// grants over the organization's members. Changing users still requires a
+// global role, since users can belong to more than one organization.
+var organizationRolePermissions = map[string][]string{
+	store.OrganizationRoleOwner: {store.PermissionUsersRead},
+	store.OrganizationRoleAdmin: {store.PermissionUsersRead},
+}
+
+// authenticateOrganization selects the organization named by the token claim
+// or the X-Organization-ID header for the authenticated user. It writes an
+// error response and returns false if the user isn't a member.
+func (app *application) authenticateOrganization(w http.ResponseWriter, r *http.Request, claimed string) (*http.Request, bool) {
+	requested := r.Header.Get(organizationHeader)
+	if claimed != "" {
+		if requested != "" && requested != claimed {
+			app.badRequest(w, r, errOrganizationMismatch)
+			return r, false
+		}
+		requested = claimed
+	}
+
+	if requested == "" {
+		return r, true
+	}
+
+	organizationID, err := uuid.Parse(requested)
+	if err != nil {
+		app.badRequest(w, r, errors.New("invalid "+organizationHeader+" header"))
+		return r, false
+	}
+
+	user := contextGetAuthenticatedUser(r)
+	membership, err := app.store.MembershipRetrieve(r.Context(), organizationID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrMembershipNotFound):
+			app.notOrganizationMember(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return r, false
+	}
+
+	organization, err := app.store.OrganizationRetrieve(r.Context(), organizationID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return r, false
+	}
+
+	r = contextSetOrganization(r, organization)
+	r = contextSetMembership(r, membership)
+	return r, true
+}
+
+// newOrganizationToken issues an access token that selects the organization
+// for every request made with it.
+func (app *application) newOrganizationToken(user *store.User, organization *store.Organization) (string, time.Time, error) {
+	extraClaims := map[string]any{
+		organizationClaim: organization.ID.String(),
+	}
+
+	return app.signAuthenticationToken(user, app.config.jwt.accessTokenTTL, extraClaims)
+}
+
+// organizationRole returns the authenticated user's role in the organization,
+// or an empty string if they aren't a member. Users with the global
+// users:admin permission are treated as owners of every organization.
+func (app *application) organizationRole(r *http.Request, organizationID uuid.UUID) (string, error) {
+	user := contextGetAuthenticatedUser(r)
+	membership, err := app.store.MembershipRetrieve(r.Context(), organizationID, user.ID)
+	switch {
+	case err == nil:
+		if membership.Role == store.OrganizationRoleOwner {
+			return membership.Role, nil
+		}
+	case !errors.Is(err, store.ErrMembershipNotFound):
+		return "", err
+	}
+
+	admin, err := app.userHasGlobalPermission(r, store.PermissionUsersAdmin)
+	if err != nil {
+		return "", err
+	}
+	if admin {
+		return store.OrganizationRoleOwner, nil
+	}
+
+	if membership != nil {
+		return membership.Role, nil
+	}
+	return "", nil
+}
+
+// canManageMember reports whether a member with the given role can change a
+// member's role from currentRole to newRole. Only owners can manage owners.
+func canManageMember(role, currentRole, newRole string) bool {
+	switch role {
+	case store.OrganizationRoleOwner:
+		return true
+	case store.OrganizationRoleAdmin:
+		return currentRole != store.OrganizationRoleOwner && newRole != store.OrganizationRoleOwner
+	}
+	return false
+}
+
+// isLastOwner reports whether the user is the organization's only owner, who
+// can't leave or be demoted.
+func (app *application) isLastOwner(ctx context.Context, organizationID, userID uuid.UUID) (bool, error) {
+	memberships, err := app.store.MembershipList(ctx, organizationID)
+	if err != nil {
+		return false, err
+	}
+
+	owners := 0
+	isOwner := false
+	for _, membership := range memberships {
+		if membership.Role == store.OrganizationRoleOwner {
+			owners++
+			isOwner = isOwner || membership.UserID == userID
+		}
+	}
+	return isOwner && owners == 1, nil
+}
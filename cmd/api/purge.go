@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runUserPurge permanently deletes the users who were deleted more than the
+// retention period ago, once at startup and then every purge interval, until
+// the context is cancelled.
+func (app *application) runUserPurge(ctx context.Context) {
+	ticker := time.NewTicker(app.config.userDeletion.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		app.purgeDeletedUsers(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) purgeDeletedUsers(ctx context.Context) {
+	purged, err := app.store.UserPurgeDeleted(ctx, app.now().Add(-app.config.userDeletion.retention))
+	if err != nil {
+		app.logger.Error("purging deleted users failed", "error", err.Error())
+		return
+	}
+
+	if purged > 0 {
+		app.logger.Info("purged deleted users", "count", purged)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mrityunjaygr8/autostrada-test/store"
 )
 
 func (app *application) routes() http.Handler {
@@ -21,13 +22,86 @@ func (app *application) routes() http.Handler {
 
 	mux.Get("/status", app.status)
 	mux.Post("/users", app.createUser)
-	mux.Get("/users", app.listUsers)
-	//mux.Post("/authentication-tokens", app.createAuthenticationToken)
+	mux.Post("/authentication-tokens", app.createAuthenticationToken)
+	mux.Post("/authentication-tokens/refresh", app.refreshAuthenticationToken)
+	mux.Post("/authentication-tokens/mfa", app.completeMFAChallenge)
+	mux.Post("/magic-links", app.createMagicLink)
+	mux.Get("/magic-links/exchange", app.exchangeMagicLink)
+	mux.Get("/oidc/login", app.oidcLogin)
+	mux.Get("/oidc/callback", app.oidcCallback)
+	mux.Post("/password-reset-tokens", app.createPasswordResetToken)
+	mux.Put("/users/password", app.resetPassword)
+	mux.Put("/users/activated", app.activateUser)
+	mux.Post("/users/activation-tokens", app.createActivationToken)
+	mux.Post("/invitations/accept", app.acceptInvitation)
 
 	mux.Group(func(mux chi.Router) {
 		mux.Use(app.requireAuthenticatedUser)
 
-		//mux.Get("/protected", app.protected)
+		mux.Get("/me", app.retrieveMe)
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(app.forbidAPIKey)
+
+			mux.Patch("/me", app.updateMe)
+
+			mux.Get("/me/api-keys", app.listAPIKeys)
+			mux.Get("/me/api-keys/{id}", app.retrieveAPIKey)
+
+			mux.Group(func(mux chi.Router) {
+				mux.Use(app.forbidImpersonation)
+
+				mux.Post("/me/password", app.changeMyPassword)
+				mux.Post("/me/mfa/totp", app.enrollTOTP)
+				mux.Post("/me/mfa/totp/confirm", app.confirmTOTP)
+				mux.Delete("/me/mfa/totp", app.disableTOTP)
+
+				mux.Post("/me/api-keys", app.createAPIKey)
+				mux.Patch("/me/api-keys/{id}", app.updateAPIKey)
+				mux.Delete("/me/api-keys/{id}", app.deleteAPIKey)
+			})
+
+			mux.Get("/me/sessions", app.listSessions)
+			mux.Delete("/me/sessions/{id}", app.deleteSession)
+
+			mux.Post("/organizations", app.createOrganization)
+			mux.Put("/organizations/{id}/members/{userID}", app.updateMember)
+			mux.Delete("/organizations/{id}/members/{userID}", app.deleteMember)
+			mux.With(app.forbidImpersonation).Post("/organizations/{id}/authentication-tokens", app.createOrganizationToken)
+
+			mux.Get("/organizations/{id}/invitations", app.listInvitations)
+			mux.Post("/organizations/{id}/invitations", app.createInvitation)
+			mux.Post("/organizations/{id}/invitations/{invitationID}/resend", app.resendInvitation)
+			mux.Delete("/organizations/{id}/invitations/{invitationID}", app.deleteInvitation)
+		})
+
+		mux.Get("/organizations", app.listOrganizations)
+		mux.Get("/organizations/{id}", app.retrieveOrganization)
+		mux.Get("/organizations/{id}/members", app.listMembers)
+
+		mux.Get("/users/{id}", app.retrieveUser)
+		mux.Patch("/users/{id}", app.updateUser)
+		mux.Delete("/users/{id}", app.deleteUser)
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(app.requirePermission(store.PermissionUsersRead))
+
+			mux.Get("/users", app.listUsers)
+			mux.Get("/users/{id}/roles", app.listUserRoles)
+			mux.Get("/roles", app.listRoles)
+		})
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(app.requirePermission(store.PermissionUsersAdmin))
+
+			mux.Put("/users/{id}/roles/{role}", app.grantUserRole)
+			mux.Delete("/users/{id}/roles/{role}", app.revokeUserRole)
+			mux.Delete("/users/{id}/lockout", app.unlockUser)
+			mux.Put("/users/{id}/status", app.updateUserStatus)
+			mux.Post("/users/{id}/restore", app.restoreUser)
+			mux.Get("/audit-events", app.listAuditEvents)
+			mux.With(app.forbidAPIKey, app.forbidImpersonation).Post("/users/{id}/impersonate", app.impersonateUser)
+		})
 	})
 
 	return mux
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,6 +21,11 @@ const (
 )
 
 func (app *application) serveHTTP() error {
+	// Request contexts derive from baseCtx, which is cancelled once the
+	// shutdown period is over, so queries still running by then are aborted.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", app.config.httpPort),
 		Handler:      app.routes(),
@@ -27,10 +33,20 @@ func (app *application) serveHTTP() error {
 		IdleTimeout:  defaultIdleTimeout,
 		ReadTimeout:  defaultReadTimeout,
 		WriteTimeout: defaultWriteTimeout,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
 
 	shutdownErrorChan := make(chan error)
 
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		app.runUserPurge(purgeCtx)
+	}()
+
 	go func() {
 		quitChan := make(chan os.Signal, 1)
 		signal.Notify(quitChan, syscall.SIGINT, syscall.SIGTERM)
@@ -39,7 +55,9 @@ func (app *application) serveHTTP() error {
 		ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownPeriod)
 		defer cancel()
 
-		shutdownErrorChan <- srv.Shutdown(ctx)
+		err := srv.Shutdown(ctx)
+		cancelRequests()
+		shutdownErrorChan <- err
 	}()
 
 	app.logger.Info("starting server", slog.Group("server", "addr", srv.Addr))
@@ -56,6 +74,7 @@ func (app *application) serveHTTP() error {
 
 	app.logger.Info("stopped server", slog.Group("server", "addr", srv.Addr))
 
+	stopPurge()
 	app.wg.Wait()
 	return nil
 }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/response"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const (
+	sessionCookie = "session"
+	csrfCookie    = "csrf_token"
+	csrfHeader    = "X-CSRF-Token"
+)
+
+// newSession creates a session for the user, recording the device and
+// address the request was made from.
+func (app *application) newSession(r *http.Request, userID uuid.UUID) (string, *store.Session, error) {
+	plaintext, hash, err := token.Generate()
+	if err != nil {
+		return "", nil, err
+	}
+
+	session, err := app.store.SessionInsert(r.Context(), store.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Hash:      hash,
+		Device:    describeDevice(r.UserAgent()),
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		Expires:   app.sessionExpiry(),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return plaintext, session, nil
+}
+
+// csrfToken derives the CSRF token for a session, so it doesn't need to be
+// stored and can't be forged by planting a cookie without the session.
+func (app *application) csrfToken(sessionToken string) string {
+	mac := hmac.New(sha256.New, []byte(app.config.jwt.secretKey))
+	mac.Write([]byte("csrf:" + sessionToken))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// setSessionCookies sets the HttpOnly session cookie and the CSRF cookie,
+// which scripts read and send back in the X-CSRF-Token header.
+func (app *application) setSessionCookies(w http.ResponseWriter, sessionToken string) {
+	secure := strings.HasPrefix(app.config.baseURL, "https://")
+	maxAge := int(app.config.sessions.ttl.Seconds())
+
+	replaceCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    sessionToken,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	replaceCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    app.csrfToken(sessionToken),
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearSessionCookies(w http.ResponseWriter) {
+	for _, name := range []string{sessionCookie, csrfCookie} {
+		replaceCookie(w, &http.Cookie{
+			Name:   name,
+			Value:  "",
+			Path:   "/",
+			MaxAge: -1,
+		})
+	}
+}
+
+// replaceCookie sets a cookie, dropping any earlier Set-Cookie header for the
+// same name, such as the refreshed session cookie written by authenticate
+// before a handler logs the user out.
+func replaceCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	headers := w.Header()["Set-Cookie"]
+	kept := headers[:0]
+	for _, header := range headers {
+		if !strings.HasPrefix(header, cookie.Name+"=") {
+			kept = append(kept, header)
+		}
+	}
+	w.Header()["Set-Cookie"] = kept
+
+	http.SetCookie(w, cookie)
+}
+
+// csrfTokenValid checks the double-submitted CSRF token: the header must
+// match both the cookie and the token derived from the session.
+func (app *application) csrfTokenValid(r *http.Request, sessionToken string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	header := r.Header.Get(csrfHeader)
+	cookie, err := r.Cookie(csrfCookie)
+	if header == "" || err != nil {
+		return false
+	}
+
+	expected := []byte(app.csrfToken(sessionToken))
+	return hmac.Equal([]byte(header), []byte(cookie.Value)) && hmac.Equal([]byte(header), expected)
+}
+
+// writeLogin responds to a successful login, either with a new session
+// cookie or with bearer authentication and refresh tokens.
+func (app *application) writeLogin(w http.ResponseWriter, r *http.Request, user *store.User, useSession bool) {
+	if !useSession {
+		data, err := app.issueAuthenticationTokens(r.Context(), user)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		err = response.JSON(w, http.StatusOK, data)
+		if err != nil {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	sessionToken, session, err := app.newSession(r, user.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.setSessionCookies(w, sessionToken)
+
+	data := map[string]any{
+		"Session":   session,
+		"CSRFToken": app.csrfToken(sessionToken),
+	}
+
+	err = response.JSON(w, http.StatusOK, data)
+	if err != nil {
+		app.serverError(w, r, err)
+	}
+}
+
+// describeDevice gives a short, human readable name for the browser and
+// operating system in a User-Agent header, for listing sessions.
+func describeDevice(userAgent string) string {
+	var browser, os string
+
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(userAgent, "curl/"):
+		browser = "curl"
+	}
+
+	switch {
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown device"
+	}
+}
+
+// sessionExpiry returns when a session used now should expire, so sessions
+// last for the configured TTL after their last use.
+func (app *application) sessionExpiry() time.Time {
+	return app.now().Add(app.config.sessions.ttl)
+}
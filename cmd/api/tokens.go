@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/pascaldekloe/jwt"
+)
+
+var errInvalidToken = errors.New("invalid authentication token")
+
+const tokenVersionClaim = "ver"
+
+// apiKeyPrefix marks bearer tokens that are API keys rather than JWTs. The
+// prefix plus the first characters of the key are stored unhashed so users
+// can tell their keys apart.
+const (
+	apiKeyPrefix        = "gz_"
+	apiKeyVisibleLength = len(apiKeyPrefix) + 8
+)
+
+func (app *application) newAuthenticationToken(user *store.User) (string, time.Time, error) {
+	return app.signAuthenticationToken(user, app.config.jwt.accessTokenTTL, nil)
+}
+
+// signAuthenticationToken issues an access token for the user that is valid
+// for the given duration, with any extra claims added to it.
+func (app *application) signAuthenticationToken(user *store.User, ttl time.Duration, extraClaims map[string]any) (string, time.Time, error) {
+	now := app.now()
+	expiry := now.Add(ttl)
+
+	var claims jwt.Claims
+	claims.ID = uuid.NewString()
+	claims.Subject = user.ID.String()
+	claims.Issued = jwt.NewNumericTime(now)
+	claims.NotBefore = jwt.NewNumericTime(now)
+	claims.Expires = jwt.NewNumericTime(expiry)
+
+	claims.Issuer = app.config.baseURL
+	claims.Audiences = []string{app.config.baseURL}
+
+	claims.Set = map[string]interface{}{
+		tokenVersionClaim: user.TokenVersion,
+	}
+	for name, value := range extraClaims {
+		claims.Set[name] = value
+	}
+
+	jwtBytes, err := claims.HMACSign(jwt.HS256, []byte(app.config.jwt.secretKey))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return string(jwtBytes), expiry, nil
+}
+
+func (app *application) checkAuthenticationToken(token string) (*jwt.Claims, error) {
+	claims, err := jwt.HMACCheck([]byte(token), []byte(app.config.jwt.secretKey))
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	if claims.Expires == nil || claims.AcceptTemporal(app.now(), app.config.jwt.leeway) != nil {
+		return nil, errInvalidToken
+	}
+
+	if claims.Issuer != app.config.baseURL {
+		return nil, errInvalidToken
+	}
+
+	if !claims.AcceptAudience(app.config.baseURL) {
+		return nil, errInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (app *application) newRefreshToken(userID, familyID uuid.UUID) (string, store.RefreshToken, error) {
+	plaintext, hash, err := token.Generate()
+	if err != nil {
+		return "", store.RefreshToken{}, err
+	}
+
+	refreshToken := store.RefreshToken{
+		ID:       uuid.New(),
+		FamilyID: familyID,
+		UserID:   userID,
+		Hash:     hash,
+		Expires:  app.now().Add(app.config.jwt.refreshTokenTTL),
+	}
+
+	return plaintext, refreshToken, nil
+}
+
+func (app *application) issueAuthenticationTokens(ctx context.Context, user *store.User) (map[string]string, error) {
+	accessToken, accessTokenExpiry, err := app.newAuthenticationToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshTokenRecord, err := app.newRefreshToken(user.ID, uuid.New())
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = app.store.RefreshTokenInsert(ctx, refreshTokenRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	return authenticationTokensData(accessToken, accessTokenExpiry, refreshToken, refreshTokenRecord.Expires), nil
+}
+
+func authenticationTokensData(accessToken string, accessTokenExpiry time.Time, refreshToken string, refreshTokenExpiry time.Time) map[string]string {
+	return map[string]string{
+		"AuthenticationToken":       accessToken,
+		"AuthenticationTokenExpiry": accessTokenExpiry.Format(time.RFC3339),
+		"RefreshToken":              refreshToken,
+		"RefreshTokenExpiry":        refreshTokenExpiry.Format(time.RFC3339),
+	}
+}
+
+func tokenVersionMatches(claims *jwt.Claims, user *store.User) bool {
+	version, ok := claims.Number(tokenVersionClaim)
+	return ok && int(version) == user.TokenVersion
+}
+
+func (app *application) newScopedToken(ctx context.Context, guzeiStore store.GuzeiStore, userID uuid.UUID, scope string, ttl time.Duration) (string, store.Token, error) {
+	plaintext, hash, err := token.Generate()
+	if err != nil {
+		return "", store.Token{}, err
+	}
+
+	scopedToken := store.Token{
+		Hash:   hash,
+		UserID: userID,
+		Scope:  scope,
+		Expiry: app.now().Add(ttl),
+	}
+
+	err = guzeiStore.TokenInsert(ctx, scopedToken)
+	if err != nil {
+		return "", store.Token{}, err
+	}
+
+	return plaintext, scopedToken, nil
+}
+
+func newAPIKey() (plaintext, prefix string, hash []byte, err error) {
+	secret, _, err := token.Generate()
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	plaintext = apiKeyPrefix + secret
+
+	return plaintext, plaintext[:apiKeyVisibleLength], token.Hash(plaintext), nil
+}
@@ -0,0 +1,41 @@
+package emailaddr
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrInvalid = errors.New("invalid email address")
+
+// Normalize returns the canonical form of an email address, which is how it
+// is stored and looked up. Surrounding whitespace is removed, the address is
+// put into Unicode normalization form C and the domain is converted to
+// lowercase ASCII, with internationalized domains in their punycode form. The
+// local part keeps its case, since it's up to the mail server to interpret
+// it; addresses are compared case-insensitively instead.
+func Normalize(address string) (string, error) {
+	address = norm.NFC.String(strings.TrimSpace(address))
+
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return "", ErrInvalid
+	}
+
+	local, domain := address[:at], strings.TrimSuffix(address[at+1:], ".")
+
+	domain, err := idna.Lookup.ToASCII(domain)
+	if err != nil || domain == "" {
+		return "", ErrInvalid
+	}
+
+	return local + "@" + domain, nil
+}
+
+// Key returns the form of a normalized address used to tell whether two
+// addresses are the same, matching the lower(email) lookups in the database.
+func Key(address string) string {
+	return strings.ToLower(address)
+}
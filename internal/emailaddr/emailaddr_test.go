@@ -0,0 +1,89 @@
+package emailaddr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{"already normalized", "im@parham.im", "im@parham.im"},
+		{"surrounding whitespace", "  im@parham.im\n", "im@parham.im"},
+		{"domain is lowercased", "im@Parham.IM", "im@parham.im"},
+		{"local part keeps its case", "Im@parham.im", "Im@parham.im"},
+		{"trailing dot", "im@parham.im.", "im@parham.im"},
+		{"split at the last @", `"a@b"@parham.im`, `"a@b"@parham.im`},
+		{"internationalized domain", "im@bücher.de", "im@xn--bcher-kva.de"},
+		{"uppercase internationalized domain", "im@BÜCHER.de", "im@xn--bcher-kva.de"},
+		{"punycode domain", "im@XN--BCHER-KVA.de", "im@xn--bcher-kva.de"},
+		{"NFC local part", "é@parham.im", "é@parham.im"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.address)
+			require.Nil(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{"empty", ""},
+		{"whitespace", "   "},
+		{"no @", "parham.im"},
+		{"empty local part", "@x"},
+		{"empty domain", "a@"},
+		{"domain is only a dot", "a@."},
+		{"space in domain", "im@par ham.im"},
+		{"leading hyphen in label", "im@-parham.im"},
+		{"invalid punycode", "im@xn--zz.im"},
+		{"disallowed character", "im@par_ham.im"},
+		{"zero width joiner", "im@\u200d.im"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Normalize(tt.address)
+			require.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"im@parham.im", "im@parham.im", true},
+		{"Im@parham.im", "iM@parham.im", true},
+		{"\u00c9mile@parham.im", "e\u0301mile@parham.im", true},
+		{"im@parham.im", "im2@parham.im", false},
+		{"im@parham.im", "im@parham.io", false},
+	}
+
+	for _, tt := range tests {
+		a, err := Normalize(tt.a)
+		require.Nil(t, err)
+		b, err := Normalize(tt.b)
+		require.Nil(t, err)
+		require.Equal(t, tt.same, Key(a) == Key(b), "%s and %s", tt.a, tt.b)
+	}
+
+	// Normalized addresses that differ only in their domain's case or
+	// encoding are the same address.
+	a, err := Normalize("Im@BÜCHER.de")
+	require.Nil(t, err)
+	b, err := Normalize("im@xn--bcher-kva.DE")
+	require.Nil(t, err)
+	require.Equal(t, Key(a), Key(b))
+}
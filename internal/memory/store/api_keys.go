@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+type apiKey struct {
+	store.APIKey
+	seq uint64
+}
+
+func (m *MemoryStore) APIKeyInsert(ctx context.Context, key store.APIKey) (*store.APIKey, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := m.db.apiKeys[key.ID]; ok {
+		return nil, fmt.Errorf("%w: duplicate api key id", store.ErrStoreError)
+	}
+	if _, ok := m.db.apiKeyHashes[string(key.Hash)]; ok {
+		return nil, fmt.Errorf("%w: duplicate api key hash", store.ErrStoreError)
+	}
+	if !m.db.userRowExists(key.UserID) {
+		return nil, store.ErrUserNotFound
+	}
+
+	key.Scopes = append([]string{}, key.Scopes...)
+	key.Created = now()
+	key.LastUsed = nil
+	m.db.apiKeys[key.ID] = apiKey{APIKey: key, seq: m.db.nextSeq()}
+	m.db.apiKeyHashes[string(key.Hash)] = key.ID
+	return &key, nil
+}
+
+func (m *MemoryStore) APIKeyList(ctx context.Context, userID uuid.UUID) ([]store.APIKey, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	matches := make([]apiKey, 0)
+	for _, key := range m.db.apiKeys {
+		if key.UserID == userID {
+			matches = append(matches, key)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].Created.Equal(matches[j].Created) {
+			return matches[i].Created.Before(matches[j].Created)
+		}
+		return matches[i].seq < matches[j].seq
+	})
+
+	keys := make([]store.APIKey, 0, len(matches))
+	for _, key := range matches {
+		keys = append(keys, key.APIKey)
+	}
+	return keys, nil
+}
+
+func (m *MemoryStore) APIKeyRetrieve(ctx context.Context, userID, id uuid.UUID) (*store.APIKey, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	key, ok := m.db.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return nil, store.ErrAPIKeyNotFound
+	}
+	return &key.APIKey, nil
+}
+
+func (m *MemoryStore) APIKeyUpdateName(ctx context.Context, userID, id uuid.UUID, name string) (*store.APIKey, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	key, ok := m.db.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return nil, store.ErrAPIKeyNotFound
+	}
+
+	key.Name = name
+	m.db.apiKeys[id] = key
+	return &key.APIKey, nil
+}
+
+func (m *MemoryStore) APIKeyDelete(ctx context.Context, userID, id uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	key, ok := m.db.apiKeys[id]
+	if !ok || key.UserID != userID {
+		return store.ErrAPIKeyNotFound
+	}
+
+	m.db.deleteAPIKey(id)
+	return nil
+}
+
+func (db *database) deleteAPIKey(id uuid.UUID) {
+	delete(db.apiKeyHashes, string(db.apiKeys[id].Hash))
+	delete(db.apiKeys, id)
+}
+
+func (m *MemoryStore) APIKeyUse(ctx context.Context, hash []byte) (*store.APIKey, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	id, ok := m.db.apiKeyHashes[string(hash)]
+	if !ok {
+		return nil, store.ErrAPIKeyNotFound
+	}
+
+	key := m.db.apiKeys[id]
+	if key.Expires != nil && !key.Expires.After(time.Now()) {
+		return nil, store.ErrAPIKeyNotFound
+	}
+
+	lastUsed := now()
+	key.LastUsed = &lastUsed
+	m.db.apiKeys[id] = key
+	return &key.APIKey, nil
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (m *MemoryStore) WithAudit(audit store.AuditContext) store.GuzeiStore {
+	return &MemoryStore{db: m.db, audit: audit, inTx: m.inTx}
+}
+
+// recordAudit appends an audit event. The caller must hold the write lock,
+// so the event is recorded together with the change it describes.
+func (db *database) recordAudit(audit store.AuditContext, action string, targetID uuid.UUID, diff map[string]store.AuditChange) {
+	db.auditEvents = append(db.auditEvents, store.AuditEvent{
+		ID:        uuid.New(),
+		ActorID:   audit.ActorID,
+		TargetID:  &targetID,
+		Action:    action,
+		IP:        audit.IP,
+		RequestID: audit.RequestID,
+		Diff:      auditDiff(diff),
+		Created:   now(),
+	})
+}
+
+// auditDiff copies the diff through JSON, so the values read back are the
+// same as those PostgresStore reads from its JSONB column.
+func auditDiff(diff map[string]store.AuditChange) map[string]store.AuditChange {
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return diff
+	}
+
+	var copied map[string]store.AuditChange
+	err = json.Unmarshal(diffJSON, &copied)
+	if err != nil {
+		return diff
+	}
+	return copied
+}
+
+func (m *MemoryStore) AuditRecord(ctx context.Context, action string, targetID uuid.UUID, diff map[string]store.AuditChange) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	m.db.recordAudit(m.audit, action, targetID, diff)
+	return nil
+}
+
+func (m *MemoryStore) AuditEventList(ctx context.Context, auditEventListParams store.AuditEventListParams) (*store.AuditEventsList, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	matches := make([]store.AuditEvent, 0)
+	for _, event := range m.db.auditEvents {
+		switch {
+		case auditEventListParams.ActorID != nil && (event.ActorID == nil || *event.ActorID != *auditEventListParams.ActorID):
+			continue
+		case auditEventListParams.TargetID != nil && (event.TargetID == nil || *event.TargetID != *auditEventListParams.TargetID):
+			continue
+		case auditEventListParams.Action != "" && event.Action != auditEventListParams.Action:
+			continue
+		case auditEventListParams.Since != nil && event.Created.Before(*auditEventListParams.Since):
+			continue
+		case auditEventListParams.Until != nil && !event.Created.Before(*auditEventListParams.Until):
+			continue
+		}
+		matches = append(matches, event)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].Created.Equal(matches[j].Created) {
+			return matches[i].Created.After(matches[j].Created)
+		}
+		return bytes.Compare(matches[i].ID[:], matches[j].ID[:]) < 0
+	})
+
+	events := make([]store.AuditEvent, 0)
+	events = append(events, paginate(matches, auditEventListParams.PageNumber, auditEventListParams.PageSize)...)
+
+	totalObjects, totalPages := pageTotals(len(matches), auditEventListParams.PageSize)
+
+	return &store.AuditEventsList{
+		Data:         events,
+		TotalObjects: totalObjects,
+		TotalPages:   totalPages,
+		Page:         auditEventListParams.PageNumber,
+		PageSize:     auditEventListParams.PageSize,
+	}, nil
+}
@@ -0,0 +1,220 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// invitationKey makes invitations unique per organization and email address,
+// regardless of the address's case.
+type invitationKey struct {
+	organizationID uuid.UUID
+	email          string
+}
+
+func keyForInvitation(invitation store.Invitation) invitationKey {
+	return invitationKey{invitation.OrganizationID, emailaddr.Key(invitation.Email)}
+}
+
+func (m *MemoryStore) InvitationInsert(ctx context.Context, invitation store.Invitation) (*store.Invitation, error) {
+	email, err := emailaddr.Normalize(invitation.Email)
+	if err != nil {
+		return nil, err
+	}
+	invitation.Email = email
+
+	if !validOrganizationRole(invitation.Role) {
+		return nil, fmt.Errorf("%w: invalid organization role %q", store.ErrStoreError, invitation.Role)
+	}
+
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := m.db.invitations[invitation.ID]; ok {
+		return nil, store.ErrInvitationExists
+	}
+	if _, ok := m.db.invitationKeys[keyForInvitation(invitation)]; ok {
+		return nil, store.ErrInvitationExists
+	}
+	if _, ok := m.db.invitationHash[string(invitation.Hash)]; ok {
+		return nil, store.ErrInvitationExists
+	}
+	if _, ok := m.db.organizations[invitation.OrganizationID]; !ok {
+		return nil, store.ErrOrganizationNotFound
+	}
+	if invitation.InvitedBy != nil && !m.db.userRowExists(*invitation.InvitedBy) {
+		return nil, store.ErrUserNotFound
+	}
+
+	invitation.Created = now()
+	m.db.invitations[invitation.ID] = invitation
+	m.db.invitationKeys[keyForInvitation(invitation)] = invitation.ID
+	m.db.invitationHash[string(invitation.Hash)] = invitation.ID
+	return &invitation, nil
+}
+
+func (m *MemoryStore) InvitationRetrieve(ctx context.Context, organizationID, id uuid.UUID) (*store.Invitation, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	invitation, ok := m.db.invitations[id]
+	if !ok || invitation.OrganizationID != organizationID {
+		return nil, store.ErrInvitationNotFound
+	}
+	return &invitation, nil
+}
+
+func (m *MemoryStore) InvitationRetrieveByHash(ctx context.Context, hash []byte) (*store.Invitation, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	invitation, ok := m.db.unexpiredInvitation(hash)
+	if !ok {
+		return nil, store.ErrInvitationNotFound
+	}
+	return &invitation, nil
+}
+
+func (db *database) unexpiredInvitation(hash []byte) (store.Invitation, bool) {
+	id, ok := db.invitationHash[string(hash)]
+	if !ok {
+		return store.Invitation{}, false
+	}
+
+	invitation := db.invitations[id]
+	if !invitation.Expires.After(time.Now()) {
+		return store.Invitation{}, false
+	}
+	return invitation, true
+}
+
+func (m *MemoryStore) InvitationList(ctx context.Context, organizationID uuid.UUID) ([]store.Invitation, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	invitations := make([]store.Invitation, 0)
+	for _, invitation := range m.db.invitations {
+		if invitation.OrganizationID == organizationID {
+			invitations = append(invitations, invitation)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		if !invitations[i].Created.Equal(invitations[j].Created) {
+			return invitations[i].Created.After(invitations[j].Created)
+		}
+		return bytes.Compare(invitations[i].ID[:], invitations[j].ID[:]) < 0
+	})
+	return invitations, nil
+}
+
+func (m *MemoryStore) InvitationRenew(ctx context.Context, organizationID, id uuid.UUID, hash []byte, expires time.Time) (*store.Invitation, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	invitation, ok := m.db.invitations[id]
+	if !ok || invitation.OrganizationID != organizationID {
+		return nil, store.ErrInvitationNotFound
+	}
+	if other, ok := m.db.invitationHash[string(hash)]; ok && other != id {
+		return nil, fmt.Errorf("%w: duplicate invitation hash", store.ErrStoreError)
+	}
+
+	delete(m.db.invitationHash, string(invitation.Hash))
+	invitation.Hash = hash
+	invitation.Expires = expires
+	m.db.invitations[id] = invitation
+	m.db.invitationHash[string(hash)] = id
+	return &invitation, nil
+}
+
+func (m *MemoryStore) InvitationDelete(ctx context.Context, organizationID, id uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	invitation, ok := m.db.invitations[id]
+	if !ok || invitation.OrganizationID != organizationID {
+		return store.ErrInvitationNotFound
+	}
+
+	m.db.deleteInvitation(invitation)
+	return nil
+}
+
+func (db *database) deleteInvitation(invitation store.Invitation) {
+	delete(db.invitationKeys, keyForInvitation(invitation))
+	delete(db.invitationHash, string(invitation.Hash))
+	delete(db.invitations, invitation.ID)
+}
+
+func (m *MemoryStore) InvitationAccept(ctx context.Context, hash []byte, userID uuid.UUID, hashedPassword string) (*store.Membership, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	invitation, ok := m.db.unexpiredInvitation(hash)
+	if !ok {
+		return nil, store.ErrInvitationNotFound
+	}
+
+	// Check everything that can fail before changing anything, since there
+	// is no transaction to roll back.
+	u, exists := m.db.activeUserByEmail(invitation.Email)
+	if exists {
+		userID = u.ID
+	} else {
+		if _, ok := m.db.users[userID]; ok {
+			return nil, store.ErrUserExists
+		}
+		if _, ok := m.db.usersByEmail[emailaddr.Key(invitation.Email)]; ok {
+			return nil, store.ErrUserExists
+		}
+	}
+
+	m.db.deleteInvitation(invitation)
+
+	if !exists {
+		_, err = m.db.insertUser(invitation.Email, hashedPassword, userID)
+		if err != nil {
+			return nil, err
+		}
+		m.db.verifyEmail(userID)
+
+		m.db.recordAudit(m.audit, store.AuditUserCreate, userID, map[string]store.AuditChange{
+			"email": {New: invitation.Email},
+			"admin": {New: false},
+		})
+	}
+
+	if current, ok := m.db.membershipToStore(membershipKey{invitation.OrganizationID, userID}); ok {
+		return current, nil
+	}
+
+	return m.db.upsertMembership(m.audit, invitation.OrganizationID, userID, invitation.Role)
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+type loginThrottleKey struct {
+	kind    string
+	subject string
+}
+
+func (m *MemoryStore) LoginThrottleRetrieve(ctx context.Context, kind, subject string) (*store.LoginThrottle, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	throttle, ok := m.db.loginThrottles[loginThrottleKey{kind, subject}]
+	if !ok {
+		return nil, store.ErrLoginThrottleNotFound
+	}
+	return &throttle, nil
+}
+
+func (m *MemoryStore) LoginFailureRecord(ctx context.Context, kind, subject string, at, windowStart time.Time) (*store.LoginThrottle, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	key := loginThrottleKey{kind, subject}
+	throttle, ok := m.db.loginThrottles[key]
+	switch {
+	case !ok:
+		throttle = store.LoginThrottle{Kind: kind, Subject: subject, Failures: 1}
+	case throttle.LastFailure.Before(windowStart):
+		throttle.Failures = 1
+	default:
+		throttle.Failures++
+	}
+	throttle.LastFailure = at
+	m.db.loginThrottles[key] = throttle
+	return &throttle, nil
+}
+
+func (m *MemoryStore) LoginThrottleLock(ctx context.Context, kind, subject string, until time.Time) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	key := loginThrottleKey{kind, subject}
+	throttle, ok := m.db.loginThrottles[key]
+	if !ok {
+		return nil
+	}
+
+	if throttle.LockedUntil == nil || until.After(*throttle.LockedUntil) {
+		throttle.LockedUntil = &until
+		m.db.loginThrottles[key] = throttle
+	}
+	return nil
+}
+
+func (m *MemoryStore) LoginThrottleReset(ctx context.Context, kind, subject string) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	delete(m.db.loginThrottles, loginThrottleKey{kind, subject})
+	return nil
+}
@@ -0,0 +1,217 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+type membershipKey struct {
+	organizationID uuid.UUID
+	userID         uuid.UUID
+}
+
+type membership struct {
+	role    string
+	created time.Time
+}
+
+func validOrganizationRole(role string) bool {
+	switch role {
+	case store.OrganizationRoleOwner, store.OrganizationRoleAdmin, store.OrganizationRoleMember:
+		return true
+	}
+	return false
+}
+
+func (m *MemoryStore) OrganizationInsert(ctx context.Context, organization store.Organization, ownerID uuid.UUID) (*store.Organization, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := m.db.organizations[organization.ID]; ok {
+		return nil, fmt.Errorf("%w: duplicate organization id", store.ErrStoreError)
+	}
+	if _, ok := m.db.activeUser(ownerID); !ok {
+		return nil, store.ErrUserNotFound
+	}
+
+	organization.Created = now()
+	m.db.organizations[organization.ID] = organization
+
+	_, err = m.db.upsertMembership(m.audit, organization.ID, ownerID, store.OrganizationRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	return &organization, nil
+}
+
+func (m *MemoryStore) OrganizationRetrieve(ctx context.Context, id uuid.UUID) (*store.Organization, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	organization, ok := m.db.organizations[id]
+	if !ok {
+		return nil, store.ErrOrganizationNotFound
+	}
+	return &organization, nil
+}
+
+func (m *MemoryStore) OrganizationListForUser(ctx context.Context, userID uuid.UUID) ([]store.Organization, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	organizations := make([]store.Organization, 0)
+	for key := range m.db.memberships {
+		if key.userID == userID {
+			organizations = append(organizations, m.db.organizations[key.organizationID])
+		}
+	}
+	sort.Slice(organizations, func(i, j int) bool {
+		if organizations[i].Name != organizations[j].Name {
+			return organizations[i].Name < organizations[j].Name
+		}
+		return bytes.Compare(organizations[i].ID[:], organizations[j].ID[:]) < 0
+	})
+	return organizations, nil
+}
+
+// membershipToStore returns the membership, unless its user has been
+// deleted.
+func (db *database) membershipToStore(key membershipKey) (*store.Membership, bool) {
+	current, ok := db.memberships[key]
+	if !ok {
+		return nil, false
+	}
+
+	u, ok := db.activeUser(key.userID)
+	if !ok {
+		return nil, false
+	}
+
+	return &store.Membership{
+		OrganizationID: key.organizationID,
+		UserID:         key.userID,
+		Email:          u.Email,
+		Role:           current.role,
+		Created:        current.created,
+	}, true
+}
+
+func (m *MemoryStore) MembershipRetrieve(ctx context.Context, organizationID, userID uuid.UUID) (*store.Membership, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result, ok := m.db.membershipToStore(membershipKey{organizationID, userID})
+	if !ok {
+		return nil, store.ErrMembershipNotFound
+	}
+	return result, nil
+}
+
+func (m *MemoryStore) MembershipList(ctx context.Context, organizationID uuid.UUID) ([]store.Membership, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	memberships := make([]store.Membership, 0)
+	for key := range m.db.memberships {
+		if key.organizationID != organizationID {
+			continue
+		}
+		if result, ok := m.db.membershipToStore(key); ok {
+			memberships = append(memberships, *result)
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].Email < memberships[j].Email
+	})
+	return memberships, nil
+}
+
+func (m *MemoryStore) MembershipUpsert(ctx context.Context, organizationID, userID uuid.UUID, role string) (*store.Membership, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return m.db.upsertMembership(m.audit, organizationID, userID, role)
+}
+
+// upsertMembership adds or updates a membership and records the change in
+// the audit log.
+func (db *database) upsertMembership(audit store.AuditContext, organizationID, userID uuid.UUID, role string) (*store.Membership, error) {
+	if !validOrganizationRole(role) {
+		return nil, fmt.Errorf("%w: invalid organization role %q", store.ErrStoreError, role)
+	}
+	if _, ok := db.organizations[organizationID]; !ok {
+		return nil, store.ErrOrganizationNotFound
+	}
+	if _, ok := db.activeUser(userID); !ok {
+		return nil, store.ErrUserNotFound
+	}
+
+	key := membershipKey{organizationID, userID}
+	current, exists := db.memberships[key]
+	if !exists {
+		current.created = now()
+	}
+
+	if !exists || current.role != role {
+		roleChange := store.AuditChange{New: role}
+		if exists {
+			roleChange.Old = current.role
+		}
+		db.recordAudit(audit, store.AuditMembershipUpdate, userID, map[string]store.AuditChange{
+			"organization": {New: organizationID},
+			"role":         roleChange,
+		})
+	}
+
+	current.role = role
+	db.memberships[key] = current
+
+	result, _ := db.membershipToStore(key)
+	return result, nil
+}
+
+func (m *MemoryStore) MembershipDelete(ctx context.Context, organizationID, userID uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	key := membershipKey{organizationID, userID}
+	current, ok := m.db.membershipToStore(key)
+	if !ok {
+		return store.ErrMembershipNotFound
+	}
+
+	delete(m.db.memberships, key)
+
+	m.db.recordAudit(m.audit, store.AuditMembershipDelete, userID, map[string]store.AuditChange{
+		"organization": {Old: organizationID},
+		"role":         {Old: current.Role},
+	})
+	return nil
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (m *MemoryStore) RefreshTokenInsert(ctx context.Context, token store.RefreshToken) (*store.RefreshToken, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	return m.db.insertRefreshToken(token)
+}
+
+func (db *database) insertRefreshToken(token store.RefreshToken) (*store.RefreshToken, error) {
+	if _, ok := db.refreshTokens[token.ID]; ok {
+		return nil, fmt.Errorf("%w: duplicate refresh token id", store.ErrStoreError)
+	}
+	if _, ok := db.refreshHashes[string(token.Hash)]; ok {
+		return nil, fmt.Errorf("%w: duplicate refresh token hash", store.ErrStoreError)
+	}
+	if !db.userRowExists(token.UserID) {
+		return nil, store.ErrUserNotFound
+	}
+
+	token.Created = now()
+	token.Used = nil
+	token.Revoked = nil
+	db.refreshTokens[token.ID] = token
+	db.refreshHashes[string(token.Hash)] = token.ID
+	return &token, nil
+}
+
+func (m *MemoryStore) RefreshTokenRetrieve(ctx context.Context, hash []byte) (*store.RefreshToken, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	id, ok := m.db.refreshHashes[string(hash)]
+	if !ok {
+		return nil, store.ErrRefreshTokenNotFound
+	}
+
+	token := m.db.refreshTokens[id]
+	return &token, nil
+}
+
+func (m *MemoryStore) RefreshTokenRotate(ctx context.Context, id uuid.UUID, next store.RefreshToken) (*store.RefreshToken, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	current, ok := m.db.refreshTokens[id]
+	if !ok || current.Used != nil || current.Revoked != nil {
+		return nil, store.ErrRefreshTokenReused
+	}
+
+	inserted, err := m.db.insertRefreshToken(next)
+	if err != nil {
+		return nil, err
+	}
+
+	used := now()
+	current.Used = &used
+	m.db.refreshTokens[id] = current
+	return inserted, nil
+}
+
+func (m *MemoryStore) RefreshTokenRevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	m.db.revokeRefreshTokens(func(token store.RefreshToken) bool {
+		return token.FamilyID == familyID
+	})
+	return nil
+}
+
+// revokeRefreshTokens revokes the unrevoked refresh tokens that match.
+func (db *database) revokeRefreshTokens(match func(store.RefreshToken) bool) {
+	revoked := now()
+	for id, token := range db.refreshTokens {
+		if token.Revoked == nil && match(token) {
+			token.Revoked = &revoked
+			db.refreshTokens[id] = token
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// defaultRoles are the roles and permissions seeded by the migrations.
+var defaultRoles = []store.Role{
+	{
+		Name:        store.RoleAdmin,
+		Description: "Full access to user management, including granting roles",
+		Permissions: []string{store.PermissionUsersAdmin, store.PermissionUsersRead, store.PermissionUsersWrite},
+	},
+	{
+		Name:        "user-manager",
+		Description: "Can view and modify user accounts",
+		Permissions: []string{store.PermissionUsersRead, store.PermissionUsersWrite},
+	},
+}
+
+func (m *MemoryStore) RoleList(ctx context.Context) ([]store.Role, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	roles := make([]store.Role, 0, len(m.db.roles))
+	for _, role := range m.db.roles {
+		role.Permissions = append([]string{}, role.Permissions...)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+	return roles, nil
+}
+
+func (m *MemoryStore) UserRoles(ctx context.Context, id uuid.UUID) ([]string, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := m.db.activeUser(id); !ok {
+		return nil, store.ErrUserNotFound
+	}
+
+	roles := make([]string, 0, len(m.db.userRoles[id]))
+	for role := range m.db.userRoles[id] {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+func (m *MemoryStore) UserPermissions(ctx context.Context, id uuid.UUID) ([]string, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	granted := make(map[string]bool)
+	for role := range m.db.userRoles[id] {
+		for _, permission := range m.db.roles[role].Permissions {
+			granted[permission] = true
+		}
+	}
+
+	permissions := make([]string, 0, len(granted))
+	for permission := range granted {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions, nil
+}
+
+func (m *MemoryStore) UserRoleGrant(ctx context.Context, id uuid.UUID, role string) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := m.db.roles[role]; !ok {
+		return store.ErrRoleNotFound
+	}
+	if !m.db.userRowExists(id) {
+		return store.ErrUserNotFound
+	}
+
+	if m.db.grantRole(id, role) {
+		m.db.recordAudit(m.audit, store.AuditUserRoleGrant, id, map[string]store.AuditChange{
+			"role": {New: role},
+		})
+	}
+	return nil
+}
+
+// grantRole gives the user the role, and reports whether they didn't already
+// have it.
+func (db *database) grantRole(id uuid.UUID, role string) bool {
+	if db.userRoles[id][role] {
+		return false
+	}
+	if db.userRoles[id] == nil {
+		db.userRoles[id] = make(map[string]bool)
+	}
+	db.userRoles[id][role] = true
+	return true
+}
+
+func (m *MemoryStore) UserRoleRevoke(ctx context.Context, id uuid.UUID, role string) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := m.db.activeUser(id); !ok {
+		return store.ErrUserNotFound
+	}
+
+	if m.db.userRoles[id][role] {
+		delete(m.db.userRoles[id], role)
+
+		m.db.recordAudit(m.audit, store.AuditUserRoleRevoke, id, map[string]store.AuditChange{
+			"role": {Old: role},
+		})
+	}
+	return nil
+}
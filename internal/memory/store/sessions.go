@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+type session struct {
+	store.Session
+	seq uint64
+}
+
+func (m *MemoryStore) SessionInsert(ctx context.Context, newSession store.Session) (*store.Session, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if _, ok := m.db.sessions[newSession.ID]; ok {
+		return nil, fmt.Errorf("%w: duplicate session id", store.ErrStoreError)
+	}
+	if _, ok := m.db.sessionHashes[string(newSession.Hash)]; ok {
+		return nil, fmt.Errorf("%w: duplicate session hash", store.ErrStoreError)
+	}
+	if !m.db.userRowExists(newSession.UserID) {
+		return nil, store.ErrUserNotFound
+	}
+
+	newSession.Created = now()
+	newSession.LastSeen = newSession.Created
+	m.db.sessions[newSession.ID] = session{Session: newSession, seq: m.db.nextSeq()}
+	m.db.sessionHashes[string(newSession.Hash)] = newSession.ID
+	return &newSession, nil
+}
+
+func (m *MemoryStore) SessionUse(ctx context.Context, hash []byte, expires time.Time) (*store.Session, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	id, ok := m.db.sessionHashes[string(hash)]
+	if !ok {
+		return nil, store.ErrSessionNotFound
+	}
+
+	current := m.db.sessions[id]
+	if !current.Expires.After(time.Now()) {
+		return nil, store.ErrSessionNotFound
+	}
+
+	current.LastSeen = now()
+	current.Expires = expires
+	m.db.sessions[id] = current
+	return &current.Session, nil
+}
+
+func (m *MemoryStore) SessionList(ctx context.Context, userID uuid.UUID) ([]store.Session, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	current := time.Now()
+	matches := make([]session, 0)
+	for _, s := range m.db.sessions {
+		if s.UserID == userID && s.Expires.After(current) {
+			matches = append(matches, s)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].LastSeen.Equal(matches[j].LastSeen) {
+			return matches[i].LastSeen.After(matches[j].LastSeen)
+		}
+		return matches[i].seq > matches[j].seq
+	})
+
+	sessions := make([]store.Session, 0, len(matches))
+	for _, s := range matches {
+		sessions = append(sessions, s.Session)
+	}
+	return sessions, nil
+}
+
+func (m *MemoryStore) SessionDelete(ctx context.Context, userID, id uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, ok := m.db.sessions[id]
+	if !ok || current.UserID != userID {
+		return store.ErrSessionNotFound
+	}
+
+	m.db.deleteSession(id)
+	return nil
+}
+
+func (db *database) deleteSession(id uuid.UUID) {
+	delete(db.sessionHashes, string(db.sessions[id].Hash))
+	delete(db.sessions, id)
+}
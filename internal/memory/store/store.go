@@ -0,0 +1,594 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// MemoryStore keeps everything in memory, for development and tests. It
+// enforces the same constraints as the PostgreSQL schema, so it returns the
+// same errors as PostgresStore, but its data is lost when the process exits.
+type MemoryStore struct {
+	db    *database
+	audit store.AuditContext
+
+	// inTx is set on the stores passed to a WithinTx callback, which run
+	// under the write lock WithinTx already holds.
+	inTx bool
+}
+
+// database holds the tables shared by a MemoryStore and the stores returned
+// by its WithAudit method. Every method holds mu for its whole run, so each
+// one is atomic like a PostgresStore transaction.
+type database struct {
+	mu sync.RWMutex
+
+	users          map[uuid.UUID]user
+	usersByEmail   map[string]uuid.UUID
+	roles          map[string]store.Role
+	userRoles      map[uuid.UUID]map[string]bool
+	refreshTokens  map[uuid.UUID]store.RefreshToken
+	refreshHashes  map[string]uuid.UUID
+	tokens         map[string]store.Token
+	totp           map[uuid.UUID]store.TOTP
+	recoveryCodes  map[uuid.UUID][]recoveryCode
+	apiKeys        map[uuid.UUID]apiKey
+	apiKeyHashes   map[string]uuid.UUID
+	identities     map[identityKey]store.Identity
+	loginThrottles map[loginThrottleKey]store.LoginThrottle
+	sessions       map[uuid.UUID]session
+	sessionHashes  map[string]uuid.UUID
+	auditEvents    []store.AuditEvent
+	organizations  map[uuid.UUID]store.Organization
+	memberships    map[membershipKey]membership
+	invitations    map[uuid.UUID]store.Invitation
+	invitationKeys map[invitationKey]uuid.UUID
+	invitationHash map[string]uuid.UUID
+
+	// seq numbers rows in the order they were inserted, so that rows with
+	// the same timestamp are listed in a stable order.
+	seq uint64
+}
+
+type user struct {
+	store.User
+	deleted *time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	db := &database{
+		users:          make(map[uuid.UUID]user),
+		usersByEmail:   make(map[string]uuid.UUID),
+		roles:          make(map[string]store.Role),
+		userRoles:      make(map[uuid.UUID]map[string]bool),
+		refreshTokens:  make(map[uuid.UUID]store.RefreshToken),
+		refreshHashes:  make(map[string]uuid.UUID),
+		tokens:         make(map[string]store.Token),
+		totp:           make(map[uuid.UUID]store.TOTP),
+		recoveryCodes:  make(map[uuid.UUID][]recoveryCode),
+		apiKeys:        make(map[uuid.UUID]apiKey),
+		apiKeyHashes:   make(map[string]uuid.UUID),
+		identities:     make(map[identityKey]store.Identity),
+		loginThrottles: make(map[loginThrottleKey]store.LoginThrottle),
+		sessions:       make(map[uuid.UUID]session),
+		sessionHashes:  make(map[string]uuid.UUID),
+		organizations:  make(map[uuid.UUID]store.Organization),
+		memberships:    make(map[membershipKey]membership),
+		invitations:    make(map[uuid.UUID]store.Invitation),
+		invitationKeys: make(map[invitationKey]uuid.UUID),
+		invitationHash: make(map[string]uuid.UUID),
+	}
+
+	for _, role := range defaultRoles {
+		db.roles[role.Name] = role
+	}
+
+	return &MemoryStore{db: db}
+}
+
+// lock takes the write lock for a method that changes data, unless the
+// context is already done.
+func (m *MemoryStore) lock(ctx context.Context) (unlock func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", store.ErrStoreError, err)
+	}
+	if m.inTx {
+		return func() {}, nil
+	}
+
+	m.db.mu.Lock()
+	return m.db.mu.Unlock, nil
+}
+
+// rlock takes the read lock for a method that only reads data, unless the
+// context is already done.
+func (m *MemoryStore) rlock(ctx context.Context) (unlock func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", store.ErrStoreError, err)
+	}
+	if m.inTx {
+		return func() {}, nil
+	}
+
+	m.db.mu.RLock()
+	return m.db.mu.RUnlock, nil
+}
+
+// now returns the time that rows are stamped with. Like PostgreSQL
+// timestamps, it has microsecond precision.
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+func (db *database) nextSeq() uint64 {
+	db.seq++
+	return db.seq
+}
+
+// userRowExists reports whether the user exists, even if they have been
+// deleted, the way a foreign key to the users table does.
+func (db *database) userRowExists(id uuid.UUID) bool {
+	_, ok := db.users[id]
+	return ok
+}
+
+// activeUser returns the user unless they don't exist or have been deleted.
+func (db *database) activeUser(id uuid.UUID) (user, bool) {
+	u, ok := db.users[id]
+	if !ok || u.deleted != nil {
+		return user{}, false
+	}
+	return u, true
+}
+
+func (db *database) userToStore(u user) *store.User {
+	result := u.User
+	result.Admin = db.userRoles[u.ID][store.RoleAdmin]
+	return &result
+}
+
+func validUserStatus(status string) bool {
+	switch status {
+	case store.UserStatusActive, store.UserStatusSuspended, store.UserStatusDeactivated:
+		return true
+	}
+	return false
+}
+
+func (m *MemoryStore) UserInsert(ctx context.Context, email, password string, id uuid.UUID, admin bool) (*store.User, error) {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	u, err := m.db.insertUser(email, password, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if admin {
+		m.db.grantRole(id, store.RoleAdmin)
+	}
+
+	m.db.recordAudit(m.audit, store.AuditUserCreate, id, map[string]store.AuditChange{
+		"email": {New: email},
+		"admin": {New: admin},
+	})
+
+	return m.db.userToStore(u), nil
+}
+
+// insertUser adds a user with the defaults from the users table. Emails are
+// unique regardless of case, including those of deleted users.
+func (db *database) insertUser(email, password string, id uuid.UUID) (user, error) {
+	if _, ok := db.users[id]; ok {
+		return user{}, store.ErrUserExists
+	}
+	if _, ok := db.usersByEmail[emailaddr.Key(email)]; ok {
+		return user{}, store.ErrUserExists
+	}
+
+	u := user{User: store.User{
+		Email:          email,
+		ID:             id,
+		Created:        now(),
+		HashedPassword: password,
+		TokenVersion:   1,
+		Status:         store.UserStatusActive,
+	}}
+	db.users[id] = u
+	db.usersByEmail[emailaddr.Key(email)] = id
+	return u, nil
+}
+
+func (m *MemoryStore) UserList(ctx context.Context, userListParams store.UserListParams) (*store.UsersList, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	matches := make([]user, 0)
+	for _, u := range m.db.users {
+		if u.deleted != nil {
+			continue
+		}
+		if userListParams.Status != "" && u.Status != userListParams.Status {
+			continue
+		}
+		if userListParams.OrganizationID != nil {
+			if _, ok := m.db.memberships[membershipKey{*userListParams.OrganizationID, u.ID}]; !ok {
+				continue
+			}
+		}
+		matches = append(matches, u)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Email < matches[j].Email
+	})
+
+	users := make([]store.User, 0)
+	for _, u := range paginate(matches, userListParams.PageNumber, userListParams.PageSize) {
+		result := m.db.userToStore(u)
+		result.HashedPassword = ""
+		result.TokenVersion = 0
+		users = append(users, *result)
+	}
+
+	totalObjects, totalPages := pageTotals(len(matches), userListParams.PageSize)
+
+	return &store.UsersList{
+		Data:         users,
+		TotalObjects: totalObjects,
+		TotalPages:   totalPages,
+		Page:         userListParams.PageNumber,
+		PageSize:     userListParams.PageSize,
+	}, nil
+}
+
+// paginate returns the given page of rows, like LIMIT and OFFSET.
+func paginate[T any](rows []T, pageNumber, pageSize int) []T {
+	offset := max((pageNumber-1)*pageSize, 0)
+	if offset >= len(rows) || pageSize <= 0 {
+		return nil
+	}
+	return rows[offset:min(offset+pageSize, len(rows))]
+}
+
+// pageTotals returns the total count and number of pages, which are the same
+// for every page, including those past the end.
+func pageTotals(totalRows, pageSize int) (totalObjects, totalPages int) {
+	if pageSize <= 0 {
+		return totalRows, 0
+	}
+	return totalRows, (totalRows + pageSize - 1) / pageSize
+}
+
+func (m *MemoryStore) UserRetrieve(ctx context.Context, id uuid.UUID) (*store.User, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	u, ok := m.db.activeUser(id)
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+	return m.db.userToStore(u), nil
+}
+
+func (m *MemoryStore) UserRetrieveByEmail(ctx context.Context, email string) (*store.User, error) {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return nil, store.ErrUserNotFound
+	}
+
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	u, ok := m.db.activeUserByEmail(email)
+	if !ok {
+		return nil, store.ErrUserNotFound
+	}
+	return m.db.userToStore(u), nil
+}
+
+func (db *database) activeUserByEmail(email string) (user, bool) {
+	id, ok := db.usersByEmail[emailaddr.Key(email)]
+	if !ok {
+		return user{}, false
+	}
+	return db.activeUser(id)
+}
+
+func (m *MemoryStore) UserUpdateEmail(ctx context.Context, id uuid.UUID, newEmail string) error {
+	newEmail, err := emailaddr.Normalize(newEmail)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, ok := m.db.activeUser(id)
+	if !ok {
+		return store.ErrUserNotFound
+	}
+
+	oldKey, newKey := emailaddr.Key(current.Email), emailaddr.Key(newEmail)
+	if oldKey != newKey {
+		if _, ok := m.db.usersByEmail[newKey]; ok {
+			return store.ErrUserExists
+		}
+		delete(m.db.usersByEmail, oldKey)
+		m.db.usersByEmail[newKey] = id
+
+		current.EmailVerified = nil
+	}
+
+	oldEmail := current.Email
+	current.Email = newEmail
+	m.db.users[id] = current
+
+	if oldEmail != newEmail {
+		m.db.recordAudit(m.audit, store.AuditUserUpdateEmail, id, map[string]store.AuditChange{
+			"email": {Old: oldEmail, New: newEmail},
+		})
+	}
+	return nil
+}
+
+func (m *MemoryStore) UserUpdatePassword(ctx context.Context, id uuid.UUID, newPassword string) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, ok := m.db.activeUser(id)
+	if !ok {
+		return store.ErrUserNotFound
+	}
+
+	current.HashedPassword = newPassword
+	current.TokenVersion++
+	m.db.users[id] = current
+
+	m.db.logOut(id)
+
+	m.db.recordAudit(m.audit, store.AuditUserUpdatePassword, id, map[string]store.AuditChange{
+		"password": {New: store.AuditRedacted},
+	})
+	return nil
+}
+
+// logOut revokes the user's refresh tokens and deletes their sessions.
+func (db *database) logOut(userID uuid.UUID) {
+	db.revokeRefreshTokens(func(token store.RefreshToken) bool {
+		return token.UserID == userID
+	})
+
+	for id, session := range db.sessions {
+		if session.UserID == userID {
+			db.deleteSession(id)
+		}
+	}
+}
+
+func (m *MemoryStore) UserVerifyEmail(ctx context.Context, id uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, ok := m.db.activeUser(id)
+	if !ok {
+		return store.ErrUserNotFound
+	}
+
+	if current.EmailVerified == nil {
+		m.db.verifyEmail(id)
+
+		m.db.recordAudit(m.audit, store.AuditUserVerifyEmail, id, map[string]store.AuditChange{
+			"email_verified": {Old: false, New: true},
+		})
+	}
+	return nil
+}
+
+func (db *database) verifyEmail(id uuid.UUID) {
+	u := db.users[id]
+	if u.EmailVerified == nil {
+		verified := now()
+		u.EmailVerified = &verified
+		db.users[id] = u
+	}
+}
+
+// UserUpdateAdmin is kept for callers that predate roles; it grants or
+// revokes the admin role.
+func (m *MemoryStore) UserUpdateAdmin(ctx context.Context, id uuid.UUID, newAdminValue bool) error {
+	if newAdminValue {
+		return m.UserRoleGrant(ctx, id, store.RoleAdmin)
+	}
+	return m.UserRoleRevoke(ctx, id, store.RoleAdmin)
+}
+
+func (m *MemoryStore) UserUpdateStatus(ctx context.Context, id uuid.UUID, status, reason string) error {
+	if !validUserStatus(status) {
+		return fmt.Errorf("%w: invalid user status %q", store.ErrStoreError, status)
+	}
+
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, ok := m.db.activeUser(id)
+	if !ok {
+		return store.ErrUserNotFound
+	}
+
+	updated := current
+	changed := now()
+	updated.Status = status
+	updated.StatusReason = reason
+	updated.StatusChanged = &changed
+	if status != store.UserStatusActive {
+		updated.TokenVersion++
+	}
+	m.db.users[id] = updated
+
+	if status != store.UserStatusActive {
+		m.db.logOut(id)
+	}
+
+	if current.Status != status || current.StatusReason != reason {
+		m.db.recordAudit(m.audit, store.AuditUserUpdateStatus, id, map[string]store.AuditChange{
+			"status": {Old: current.Status, New: status},
+			"reason": {Old: current.StatusReason, New: reason},
+		})
+	}
+	return nil
+}
+
+func (m *MemoryStore) UserDelete(ctx context.Context, id uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, ok := m.db.activeUser(id)
+	if !ok {
+		return store.ErrUserNotFound
+	}
+	admin := m.db.userRoles[id][store.RoleAdmin]
+
+	deleted := now()
+	current.deleted = &deleted
+	current.TokenVersion++
+	m.db.users[id] = current
+
+	m.db.logOut(id)
+
+	m.db.recordAudit(m.audit, store.AuditUserDelete, id, map[string]store.AuditChange{
+		"email": {Old: current.Email},
+		"admin": {Old: admin},
+	})
+	return nil
+}
+
+func (m *MemoryStore) UserRestore(ctx context.Context, id uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, ok := m.db.users[id]
+	if !ok || current.deleted == nil {
+		return store.ErrUserNotFound
+	}
+
+	current.deleted = nil
+	m.db.users[id] = current
+
+	m.db.recordAudit(m.audit, store.AuditUserRestore, id, map[string]store.AuditChange{
+		"email": {New: current.Email},
+	})
+	return nil
+}
+
+func (m *MemoryStore) UserPurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	purged := 0
+	for id, u := range m.db.users {
+		if u.deleted == nil || !u.deleted.Before(deletedBefore) {
+			continue
+		}
+
+		m.db.purgeUser(id)
+		purged++
+
+		m.db.recordAudit(m.audit, store.AuditUserPurge, id, map[string]store.AuditChange{
+			"email": {Old: u.Email},
+		})
+	}
+	return purged, nil
+}
+
+// purgeUser deletes the user and everything that references them, like the
+// ON DELETE clauses of the foreign keys to the users table.
+func (db *database) purgeUser(id uuid.UUID) {
+	delete(db.usersByEmail, emailaddr.Key(db.users[id].Email))
+	delete(db.users, id)
+	delete(db.userRoles, id)
+	delete(db.totp, id)
+	delete(db.recoveryCodes, id)
+
+	for tokenID, token := range db.refreshTokens {
+		if token.UserID == id {
+			delete(db.refreshHashes, string(token.Hash))
+			delete(db.refreshTokens, tokenID)
+		}
+	}
+	for hash, token := range db.tokens {
+		if token.UserID == id {
+			delete(db.tokens, hash)
+		}
+	}
+	for keyID, key := range db.apiKeys {
+		if key.UserID == id {
+			db.deleteAPIKey(keyID)
+		}
+	}
+	for key, identity := range db.identities {
+		if identity.UserID == id {
+			delete(db.identities, key)
+		}
+	}
+	for sessionID, session := range db.sessions {
+		if session.UserID == id {
+			db.deleteSession(sessionID)
+		}
+	}
+	for key := range db.memberships {
+		if key.userID == id {
+			delete(db.memberships, key)
+		}
+	}
+	for invitationID, invitation := range db.invitations {
+		if invitation.InvitedBy != nil && *invitation.InvitedBy == id {
+			invitation.InvitedBy = nil
+			db.invitations[invitationID] = invitation
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/mrityunjaygr8/autostrada-test/store/storetest"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.GuzeiStore {
+		return NewMemoryStore()
+	})
+}
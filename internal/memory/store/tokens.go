@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (m *MemoryStore) TokenInsert(ctx context.Context, token store.Token) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := m.db.tokens[string(token.Hash)]; ok {
+		return fmt.Errorf("%w: duplicate token hash", store.ErrStoreError)
+	}
+	if !m.db.userRowExists(token.UserID) {
+		return store.ErrUserNotFound
+	}
+
+	token.Created = now()
+	m.db.tokens[string(token.Hash)] = token
+	return nil
+}
+
+func (m *MemoryStore) TokenConsume(ctx context.Context, scope string, hash []byte) (uuid.UUID, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer unlock()
+
+	token, ok := m.db.tokens[string(hash)]
+	if !ok || token.Scope != scope || !token.Expiry.After(time.Now()) {
+		return uuid.Nil, store.ErrTokenNotFound
+	}
+
+	delete(m.db.tokens, string(hash))
+	return token.UserID, nil
+}
+
+func (m *MemoryStore) TokenDeleteAllForUser(ctx context.Context, scope string, userID uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for hash, token := range m.db.tokens {
+		if token.UserID == userID && token.Scope == scope {
+			delete(m.db.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) TokenCountSince(ctx context.Context, scope string, userID uuid.UUID, since time.Time) (int, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	count := 0
+	for _, token := range m.db.tokens {
+		if token.UserID == userID && token.Scope == scope && token.Created.After(since) {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+type recoveryCode struct {
+	hash []byte
+	used bool
+}
+
+func (m *MemoryStore) TOTPRetrieve(ctx context.Context, userID uuid.UUID) (*store.TOTP, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	userTOTP, ok := m.db.totp[userID]
+	if !ok {
+		return nil, store.ErrTOTPNotFound
+	}
+	return &userTOTP, nil
+}
+
+func (m *MemoryStore) TOTPEnroll(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !m.db.userRowExists(userID) {
+		return store.ErrUserNotFound
+	}
+
+	m.db.totp[userID] = store.TOTP{
+		UserID:  userID,
+		Secret:  secret,
+		Created: now(),
+	}
+	return nil
+}
+
+func (m *MemoryStore) TOTPConfirm(ctx context.Context, userID uuid.UUID, recoveryCodeHashes [][]byte) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	userTOTP, ok := m.db.totp[userID]
+	if !ok || userTOTP.Confirmed != nil {
+		return store.ErrTOTPNotFound
+	}
+
+	confirmed := now()
+	userTOTP.Confirmed = &confirmed
+	m.db.totp[userID] = userTOTP
+
+	codes := make([]recoveryCode, 0, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes = append(codes, recoveryCode{hash: hash})
+	}
+	m.db.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (m *MemoryStore) TOTPDelete(ctx context.Context, userID uuid.UUID) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, ok := m.db.totp[userID]; !ok {
+		return store.ErrTOTPNotFound
+	}
+
+	delete(m.db.totp, userID)
+	delete(m.db.recoveryCodes, userID)
+	return nil
+}
+
+func (m *MemoryStore) RecoveryCodeConsume(ctx context.Context, userID uuid.UUID, hash []byte) error {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for i, code := range m.db.recoveryCodes[userID] {
+		if !code.used && bytes.Equal(code.hash, hash) {
+			m.db.recoveryCodes[userID][i].used = true
+			return nil
+		}
+	}
+	return store.ErrRecoveryCodeNotFound
+}
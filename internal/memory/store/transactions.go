@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// WithinTx runs fn under the write lock, so transactions run one at a time
+// and never need retrying. If fn fails, the tables are restored from a copy
+// taken before it ran.
+func (m *MemoryStore) WithinTx(ctx context.Context, opts store.TxOptions, fn func(tx store.Tx) error) error {
+	if opts.Isolation < store.IsolationDefault || opts.Isolation > store.IsolationSerializable {
+		return fmt.Errorf("%w: invalid isolation level %d", store.ErrStoreError, opts.Isolation)
+	}
+
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	saved := m.db.snapshot()
+	err = fn(&MemoryStore{db: m.db, audit: m.audit, inTx: true})
+	if err != nil {
+		m.db.restore(saved)
+		return err
+	}
+	return nil
+}
+
+// snapshot copies the tables, including the rows that methods change in
+// place. The caller must hold the write lock.
+func (db *database) snapshot() *database {
+	userRoles := make(map[uuid.UUID]map[string]bool, len(db.userRoles))
+	for id, roles := range db.userRoles {
+		userRoles[id] = maps.Clone(roles)
+	}
+	recoveryCodes := make(map[uuid.UUID][]recoveryCode, len(db.recoveryCodes))
+	for id, codes := range db.recoveryCodes {
+		recoveryCodes[id] = slices.Clone(codes)
+	}
+
+	return &database{
+		users:          maps.Clone(db.users),
+		usersByEmail:   maps.Clone(db.usersByEmail),
+		roles:          maps.Clone(db.roles),
+		userRoles:      userRoles,
+		refreshTokens:  maps.Clone(db.refreshTokens),
+		refreshHashes:  maps.Clone(db.refreshHashes),
+		tokens:         maps.Clone(db.tokens),
+		totp:           maps.Clone(db.totp),
+		recoveryCodes:  recoveryCodes,
+		apiKeys:        maps.Clone(db.apiKeys),
+		apiKeyHashes:   maps.Clone(db.apiKeyHashes),
+		identities:     maps.Clone(db.identities),
+		loginThrottles: maps.Clone(db.loginThrottles),
+		sessions:       maps.Clone(db.sessions),
+		sessionHashes:  maps.Clone(db.sessionHashes),
+		auditEvents:    slices.Clone(db.auditEvents),
+		organizations:  maps.Clone(db.organizations),
+		memberships:    maps.Clone(db.memberships),
+		invitations:    maps.Clone(db.invitations),
+		invitationKeys: maps.Clone(db.invitationKeys),
+		invitationHash: maps.Clone(db.invitationHash),
+		seq:            db.seq,
+	}
+}
+
+// restore puts back the tables from a snapshot. The caller must hold the
+// write lock.
+func (db *database) restore(saved *database) {
+	db.users = saved.users
+	db.usersByEmail = saved.usersByEmail
+	db.roles = saved.roles
+	db.userRoles = saved.userRoles
+	db.refreshTokens = saved.refreshTokens
+	db.refreshHashes = saved.refreshHashes
+	db.tokens = saved.tokens
+	db.totp = saved.totp
+	db.recoveryCodes = saved.recoveryCodes
+	db.apiKeys = saved.apiKeys
+	db.apiKeyHashes = saved.apiKeyHashes
+	db.identities = saved.identities
+	db.loginThrottles = saved.loginThrottles
+	db.sessions = saved.sessions
+	db.sessionHashes = saved.sessionHashes
+	db.auditEvents = saved.auditEvents
+	db.organizations = saved.organizations
+	db.memberships = saved.memberships
+	db.invitations = saved.invitations
+	db.invitationKeys = saved.invitationKeys
+	db.invitationHash = saved.invitationHash
+	db.seq = saved.seq
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+type identityKey struct {
+	issuer  string
+	subject string
+}
+
+func (m *MemoryStore) IdentityRetrieve(ctx context.Context, issuer, subject string) (*store.Identity, error) {
+	unlock, err := m.rlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	identity, ok := m.db.identities[identityKey{issuer, subject}]
+	if !ok {
+		return nil, store.ErrIdentityNotFound
+	}
+	return &identity, nil
+}
+
+func (m *MemoryStore) IdentityLink(ctx context.Context, identity store.Identity) (*store.Identity, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	key := identityKey{identity.Issuer, identity.Subject}
+	if _, ok := m.db.identities[key]; ok {
+		return nil, store.ErrIdentityExists
+	}
+	if !m.db.userRowExists(identity.UserID) {
+		return nil, store.ErrUserNotFound
+	}
+
+	identity.Created = now()
+	m.db.identities[key] = identity
+	return &identity, nil
+}
@@ -9,10 +9,141 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type User struct {
+type ApiKey struct {
+	ID       uuid.UUID
+	UserID   uuid.UUID
+	Name     string
+	Prefix   string
+	Hash     []byte
+	Scopes   []string
+	Created  pgtype.Timestamptz
+	Expires  pgtype.Timestamptz
+	LastUsed pgtype.Timestamptz
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID
+	ActorID   *uuid.UUID
+	TargetID  *uuid.UUID
+	Action    string
+	Ip        string
+	RequestID string
+	Diff      []byte
+	Created   pgtype.Timestamptz
+}
+
+type Invitation struct {
 	ID             uuid.UUID
-	Created        pgtype.Timestamptz
+	OrganizationID uuid.UUID
 	Email          string
-	Admin          bool
-	HashedPassword string
+	Role           string
+	Hash           []byte
+	InvitedBy      *uuid.UUID
+	Created        pgtype.Timestamptz
+	Expires        pgtype.Timestamptz
+}
+
+type LoginThrottle struct {
+	Kind        string
+	Subject     string
+	Failures    int32
+	LastFailure pgtype.Timestamptz
+	LockedUntil pgtype.Timestamptz
+}
+
+type Organization struct {
+	ID      uuid.UUID
+	Name    string
+	Created pgtype.Timestamptz
+}
+
+type OrganizationMembership struct {
+	OrganizationID uuid.UUID
+	UserID         uuid.UUID
+	Role           string
+	Created        pgtype.Timestamptz
+}
+
+type Permission struct {
+	Name string
+}
+
+type RecoveryCode struct {
+	UserID   uuid.UUID
+	CodeHash []byte
+	Used     pgtype.Timestamptz
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	TokenHash []byte
+	Created   pgtype.Timestamptz
+	Expires   pgtype.Timestamptz
+	Used      pgtype.Timestamptz
+	Revoked   pgtype.Timestamptz
+}
+
+type Role struct {
+	Name        string
+	Description string
+}
+
+type RolePermission struct {
+	Role       string
+	Permission string
+}
+
+type Session struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Hash      []byte
+	Device    string
+	Ip        string
+	UserAgent string
+	Created   pgtype.Timestamptz
+	LastSeen  pgtype.Timestamptz
+	Expires   pgtype.Timestamptz
+}
+
+type Token struct {
+	Hash    []byte
+	UserID  uuid.UUID
+	Scope   string
+	Created pgtype.Timestamptz
+	Expiry  pgtype.Timestamptz
+}
+
+type User struct {
+	ID              uuid.UUID
+	Created         pgtype.Timestamptz
+	Email           string
+	HashedPassword  string
+	TokenVersion    int32
+	EmailVerifiedAt pgtype.Timestamptz
+	Status          string
+	StatusReason    string
+	StatusChangedAt pgtype.Timestamptz
+	DeletedAt       pgtype.Timestamptz
+}
+
+type UserIdentity struct {
+	Issuer  string
+	Subject string
+	UserID  uuid.UUID
+	Email   string
+	Created pgtype.Timestamptz
+}
+
+type UserRole struct {
+	UserID uuid.UUID
+	Role   string
+}
+
+type UserTotp struct {
+	UserID    uuid.UUID
+	Secret    []byte
+	Created   pgtype.Timestamptz
+	Confirmed pgtype.Timestamptz
 }
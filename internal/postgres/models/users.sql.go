@@ -14,57 +14,118 @@ import (
 )
 
 const userDelete = `-- name: UserDelete :execresult
-DELETE FROM users WHERE id = $1
+UPDATE users SET deleted_at = now(), token_version = token_version + 1 WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) UserDelete(ctx context.Context, id uuid.UUID) (pgconn.CommandTag, error) {
 	return q.db.Exec(ctx, userDelete, id)
 }
 
+const userExists = `-- name: UserExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)
+`
+
+func (q *Queries) UserExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRow(ctx, userExists, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const userInsert = `-- name: UserInsert :one
-INSERT INTO users (email, hashed_password, id, admin) VALUES ($1, $2, $3, $4) RETURNING email, created, id, admin
+INSERT INTO users (email, hashed_password, id) VALUES ($1, $2, $3) RETURNING email, created, id, hashed_password, token_version, email_verified_at, status, status_reason, status_changed_at
 `
 
 type UserInsertParams struct {
 	Email          string
 	HashedPassword string
 	ID             uuid.UUID
-	Admin          bool
 }
 
 type UserInsertRow struct {
-	Email   string
-	Created pgtype.Timestamptz
-	ID      uuid.UUID
-	Admin   bool
+	Email           string
+	Created         pgtype.Timestamptz
+	ID              uuid.UUID
+	HashedPassword  string
+	TokenVersion    int32
+	EmailVerifiedAt pgtype.Timestamptz
+	Status          string
+	StatusReason    string
+	StatusChangedAt pgtype.Timestamptz
 }
 
 func (q *Queries) UserInsert(ctx context.Context, arg UserInsertParams) (UserInsertRow, error) {
-	row := q.db.QueryRow(ctx, userInsert,
-		arg.Email,
-		arg.HashedPassword,
-		arg.ID,
-		arg.Admin,
-	)
+	row := q.db.QueryRow(ctx, userInsert, arg.Email, arg.HashedPassword, arg.ID)
 	var i UserInsertRow
 	err := row.Scan(
 		&i.Email,
 		&i.Created,
 		&i.ID,
-		&i.Admin,
+		&i.HashedPassword,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
 	)
 	return i, err
 }
 
+const userPurgeDeleted = `-- name: UserPurgeDeleted :many
+DELETE FROM users WHERE deleted_at < $1 RETURNING id, email
+`
+
+type UserPurgeDeletedRow struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) UserPurgeDeleted(ctx context.Context, deletedAt pgtype.Timestamptz) ([]UserPurgeDeletedRow, error) {
+	rows, err := q.db.Query(ctx, userPurgeDeleted, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserPurgeDeletedRow
+	for rows.Next() {
+		var i UserPurgeDeletedRow
+		if err := rows.Scan(&i.ID, &i.Email); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const userRestore = `-- name: UserRestore :one
+UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING email
+`
+
+func (q *Queries) UserRestore(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRow(ctx, userRestore, id)
+	var email string
+	err := row.Scan(&email)
+	return email, err
+}
+
 const userRetrieve = `-- name: UserRetrieve :one
-SELECT email, created,  id, admin FROM users WHERE id = $1 LIMIT 1
+SELECT email, created,  id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, hashed_password, token_version, email_verified_at, status, status_reason, status_changed_at FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 type UserRetrieveRow struct {
-	Email   string
-	Created pgtype.Timestamptz
-	ID      uuid.UUID
-	Admin   bool
+	Email           string
+	Created         pgtype.Timestamptz
+	ID              uuid.UUID
+	Admin           bool
+	HashedPassword  string
+	TokenVersion    int32
+	EmailVerifiedAt pgtype.Timestamptz
+	Status          string
+	StatusReason    string
+	StatusChangedAt pgtype.Timestamptz
 }
 
 func (q *Queries) UserRetrieve(ctx context.Context, id uuid.UUID) (UserRetrieveRow, error) {
@@ -75,19 +136,31 @@ func (q *Queries) UserRetrieve(ctx context.Context, id uuid.UUID) (UserRetrieveR
 		&i.Created,
 		&i.ID,
 		&i.Admin,
+		&i.HashedPassword,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
 	)
 	return i, err
 }
 
 const userRetrieveByEmail = `-- name: UserRetrieveByEmail :one
-SELECT email, created,  id, admin FROM users WHERE email = $1 LIMIT 1
+SELECT email, created,  id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, hashed_password, token_version, email_verified_at, status, status_reason, status_changed_at FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1
 `
 
 type UserRetrieveByEmailRow struct {
-	Email   string
-	Created pgtype.Timestamptz
-	ID      uuid.UUID
-	Admin   bool
+	Email           string
+	Created         pgtype.Timestamptz
+	ID              uuid.UUID
+	Admin           bool
+	HashedPassword  string
+	TokenVersion    int32
+	EmailVerifiedAt pgtype.Timestamptz
+	Status          string
+	StatusReason    string
+	StatusChangedAt pgtype.Timestamptz
 }
 
 func (q *Queries) UserRetrieveByEmail(ctx context.Context, email string) (UserRetrieveByEmailRow, error) {
@@ -98,25 +171,31 @@ func (q *Queries) UserRetrieveByEmail(ctx context.Context, email string) (UserRe
 		&i.Created,
 		&i.ID,
 		&i.Admin,
+		&i.HashedPassword,
+		&i.TokenVersion,
+		&i.EmailVerifiedAt,
+		&i.Status,
+		&i.StatusReason,
+		&i.StatusChangedAt,
 	)
 	return i, err
 }
 
-const userUpdateAdmin = `-- name: UserUpdateAdmin :execresult
-UPDATE users SET admin = $2 WHERE id = $1
+const userUpdateEmail = `-- name: UserUpdateEmail :execresult
+UPDATE users SET email = $2, email_verified_at = CASE WHEN lower(email) = lower($2) THEN email_verified_at END WHERE id = $1 AND deleted_at IS NULL
 `
 
-type UserUpdateAdminParams struct {
+type UserUpdateEmailParams struct {
 	ID    uuid.UUID
-	Admin bool
+	Email string
 }
 
-func (q *Queries) UserUpdateAdmin(ctx context.Context, arg UserUpdateAdminParams) (pgconn.CommandTag, error) {
-	return q.db.Exec(ctx, userUpdateAdmin, arg.ID, arg.Admin)
+func (q *Queries) UserUpdateEmail(ctx context.Context, arg UserUpdateEmailParams) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, userUpdateEmail, arg.ID, arg.Email)
 }
 
 const userUpdatePassword = `-- name: UserUpdatePassword :execresult
-UPDATE users SET hashed_password = $2 WHERE id = $1
+UPDATE users SET hashed_password = $2, token_version = token_version + 1 WHERE id = $1 AND deleted_at IS NULL
 `
 
 type UserUpdatePasswordParams struct {
@@ -128,30 +207,86 @@ func (q *Queries) UserUpdatePassword(ctx context.Context, arg UserUpdatePassword
 	return q.db.Exec(ctx, userUpdatePassword, arg.ID, arg.HashedPassword)
 }
 
+const userUpdateStatus = `-- name: UserUpdateStatus :execresult
+UPDATE users SET status = $2, status_reason = $3, status_changed_at = now(), token_version = CASE WHEN $2 = 'active' THEN token_version ELSE token_version + 1 END WHERE id = $1 AND deleted_at IS NULL
+`
+
+type UserUpdateStatusParams struct {
+	ID           uuid.UUID
+	Status       string
+	StatusReason string
+}
+
+func (q *Queries) UserUpdateStatus(ctx context.Context, arg UserUpdateStatusParams) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, userUpdateStatus, arg.ID, arg.Status, arg.StatusReason)
+}
+
+const userVerifyEmail = `-- name: UserVerifyEmail :execresult
+UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) UserVerifyEmail(ctx context.Context, id uuid.UUID) (pgconn.CommandTag, error) {
+	return q.db.Exec(ctx, userVerifyEmail, id)
+}
+
+const usersCount = `-- name: UsersCount :one
+SELECT COUNT(*) FROM users
+WHERE deleted_at IS NULL
+  AND ($1::uuid IS NULL OR EXISTS (SELECT 1 FROM organization_memberships WHERE organization_memberships.user_id = users.id AND organization_memberships.organization_id = $1))
+  AND ($2::text IS NULL OR status = $2)
+`
+
+type UsersCountParams struct {
+	OrganizationID *uuid.UUID
+	Status         pgtype.Text
+}
+
+func (q *Queries) UsersCount(ctx context.Context, arg UsersCountParams) (int64, error) {
+	row := q.db.QueryRow(ctx, usersCount, arg.OrganizationID, arg.Status)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const usersList = `-- name: UsersList :many
 WITH row_data AS (
-    SELECT email, created, id, admin FROM users ORDER BY email LIMIT $1 OFFSET $2
+    SELECT email, created, id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, email_verified_at, status, status_reason, status_changed_at FROM users
+    WHERE deleted_at IS NULL
+      AND ($1::uuid IS NULL OR EXISTS (SELECT 1 FROM organization_memberships WHERE organization_memberships.user_id = users.id AND organization_memberships.organization_id = $1))
+      AND ($2::text IS NULL OR status = $2)
+    ORDER BY email LIMIT $4 OFFSET $3
 ) SELECT
-      email, created, id, admin,
-      (SELECT COUNT(*) FROM users) AS row_data
+      email, created, id, admin, email_verified_at, status, status_reason, status_changed_at,
+      (SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND ($1::uuid IS NULL OR EXISTS (SELECT 1 FROM organization_memberships WHERE organization_memberships.user_id = users.id AND organization_memberships.organization_id = $1)) AND ($2::text IS NULL OR status = $2)) AS row_data
 FROM row_data
 `
 
 type UsersListParams struct {
-	Limit  int32
-	Offset int32
+	OrganizationID *uuid.UUID
+	Status         pgtype.Text
+	Offset         int32
+	Limit          int32
 }
 
 type UsersListRow struct {
-	Email   string
-	Created pgtype.Timestamptz
-	ID      uuid.UUID
-	Admin   bool
-	RowData int64
+	Email           string
+	Created         pgtype.Timestamptz
+	ID              uuid.UUID
+	Admin           bool
+	EmailVerifiedAt pgtype.Timestamptz
+	Status          string
+	StatusReason    string
+	StatusChangedAt pgtype.Timestamptz
+	RowData         int64
 }
 
 func (q *Queries) UsersList(ctx context.Context, arg UsersListParams) ([]UsersListRow, error) {
-	rows, err := q.db.Query(ctx, usersList, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, usersList,
+		arg.OrganizationID,
+		arg.Status,
+		arg.Offset,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +299,10 @@ func (q *Queries) UsersList(ctx context.Context, arg UsersListParams) ([]UsersLi
 			&i.Created,
 			&i.ID,
 			&i.Admin,
+			&i.EmailVerifiedAt,
+			&i.Status,
+			&i.StatusReason,
+			&i.StatusChangedAt,
 			&i.RowData,
 		); err != nil {
 			return nil, err
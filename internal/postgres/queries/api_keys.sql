@@ -0,0 +1,17 @@
+-- name: APIKeyInsert :one
+INSERT INTO api_keys (id, user_id, name, prefix, hash, scopes, expires) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: APIKeyList :many
+SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created;
+
+-- name: APIKeyRetrieve :one
+SELECT * FROM api_keys WHERE id = $1 AND user_id = $2;
+
+-- name: APIKeyUpdateName :one
+UPDATE api_keys SET name = $3 WHERE id = $1 AND user_id = $2 RETURNING *;
+
+-- name: APIKeyDelete :execresult
+DELETE FROM api_keys WHERE id = $1 AND user_id = $2;
+
+-- name: APIKeyUse :one
+UPDATE api_keys SET last_used = now() WHERE hash = $1 AND (expires IS NULL OR expires > now()) RETURNING *;
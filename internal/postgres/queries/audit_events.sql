@@ -0,0 +1,20 @@
+-- name: AuditEventInsert :exec
+INSERT INTO audit_events (id, actor_id, target_id, action, ip, request_id, diff) VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: AuditEventCount :one
+SELECT COUNT(*) FROM audit_events
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR actor_id = sqlc.narg('actor_id'))
+    AND (sqlc.narg('target_id')::uuid IS NULL OR target_id = sqlc.narg('target_id'))
+    AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+    AND (sqlc.narg('since')::timestamptz IS NULL OR created >= sqlc.narg('since'))
+    AND (sqlc.narg('until')::timestamptz IS NULL OR created < sqlc.narg('until'));
+
+-- name: AuditEventList :many
+SELECT *, COUNT(*) OVER () AS row_data FROM audit_events
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR actor_id = sqlc.narg('actor_id'))
+    AND (sqlc.narg('target_id')::uuid IS NULL OR target_id = sqlc.narg('target_id'))
+    AND (sqlc.narg('action')::text IS NULL OR action = sqlc.narg('action'))
+    AND (sqlc.narg('since')::timestamptz IS NULL OR created >= sqlc.narg('since'))
+    AND (sqlc.narg('until')::timestamptz IS NULL OR created < sqlc.narg('until'))
+ORDER BY created DESC, id
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
@@ -0,0 +1,20 @@
+-- name: InvitationInsert :one
+INSERT INTO invitations (id, organization_id, email, role, hash, invited_by, expires) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: InvitationRetrieve :one
+SELECT * FROM invitations WHERE organization_id = $1 AND id = $2;
+
+-- name: InvitationRetrieveByHash :one
+SELECT * FROM invitations WHERE hash = $1 AND expires > now();
+
+-- name: InvitationList :many
+SELECT * FROM invitations WHERE organization_id = $1 ORDER BY created DESC, id;
+
+-- name: InvitationRenew :one
+UPDATE invitations SET hash = $3, expires = $4 WHERE organization_id = $1 AND id = $2 RETURNING *;
+
+-- name: InvitationDelete :execresult
+DELETE FROM invitations WHERE organization_id = $1 AND id = $2;
+
+-- name: InvitationConsume :one
+DELETE FROM invitations WHERE hash = $1 AND expires > now() RETURNING *;
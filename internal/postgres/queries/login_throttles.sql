@@ -0,0 +1,15 @@
+-- name: LoginThrottleRetrieve :one
+SELECT * FROM login_throttles WHERE kind = $1 AND subject = $2;
+
+-- name: LoginFailureRecord :one
+INSERT INTO login_throttles (kind, subject, failures, last_failure) VALUES (@kind, @subject, 1, @at)
+ON CONFLICT (kind, subject) DO UPDATE SET
+    failures = CASE WHEN login_throttles.last_failure < @window_start THEN 1 ELSE login_throttles.failures + 1 END,
+    last_failure = EXCLUDED.last_failure
+RETURNING *;
+
+-- name: LoginThrottleLock :exec
+UPDATE login_throttles SET locked_until = GREATEST(locked_until, @locked_until) WHERE kind = @kind AND subject = @subject;
+
+-- name: LoginThrottleReset :exec
+DELETE FROM login_throttles WHERE kind = $1 AND subject = $2;
@@ -0,0 +1,30 @@
+-- name: OrganizationInsert :one
+INSERT INTO organizations (id, name) VALUES ($1, $2) RETURNING *;
+
+-- name: OrganizationRetrieve :one
+SELECT * FROM organizations WHERE id = $1;
+
+-- name: OrganizationListForUser :many
+SELECT organizations.* FROM organizations
+JOIN organization_memberships ON organization_memberships.organization_id = organizations.id
+WHERE organization_memberships.user_id = $1
+ORDER BY organizations.name, organizations.id;
+
+-- name: MembershipRetrieve :one
+SELECT organization_memberships.*, users.email FROM organization_memberships
+JOIN users ON users.id = organization_memberships.user_id
+WHERE organization_memberships.organization_id = $1 AND organization_memberships.user_id = $2 AND users.deleted_at IS NULL;
+
+-- name: MembershipList :many
+SELECT organization_memberships.*, users.email FROM organization_memberships
+JOIN users ON users.id = organization_memberships.user_id
+WHERE organization_memberships.organization_id = $1 AND users.deleted_at IS NULL
+ORDER BY users.email;
+
+-- name: MembershipUpsert :one
+INSERT INTO organization_memberships (organization_id, user_id, role) VALUES ($1, $2, $3)
+ON CONFLICT (organization_id, user_id) DO UPDATE SET role = EXCLUDED.role
+RETURNING *;
+
+-- name: MembershipDelete :execresult
+DELETE FROM organization_memberships WHERE organization_id = $1 AND user_id = $2;
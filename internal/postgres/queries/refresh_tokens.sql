@@ -0,0 +1,14 @@
+-- name: RefreshTokenInsert :one
+INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, expires) VALUES ($1, $2, $3, $4, $5) RETURNING id, family_id, user_id, token_hash, created, expires, used, revoked;
+
+-- name: RefreshTokenRetrieveByHash :one
+SELECT id, family_id, user_id, token_hash, created, expires, used, revoked FROM refresh_tokens WHERE token_hash = $1 LIMIT 1;
+
+-- name: RefreshTokenMarkUsed :execresult
+UPDATE refresh_tokens SET used = now() WHERE id = $1 AND used IS NULL AND revoked IS NULL;
+
+-- name: RefreshTokenRevokeFamily :exec
+UPDATE refresh_tokens SET revoked = now() WHERE family_id = $1 AND revoked IS NULL;
+
+-- name: RefreshTokenRevokeAllForUser :exec
+UPDATE refresh_tokens SET revoked = now() WHERE user_id = $1 AND revoked IS NULL;
@@ -0,0 +1,21 @@
+-- name: RoleList :many
+SELECT
+    roles.name,
+    roles.description,
+    COALESCE(array_agg(role_permissions.permission ORDER BY role_permissions.permission) FILTER (WHERE role_permissions.permission IS NOT NULL), '{}')::text[] AS permissions
+FROM roles
+LEFT JOIN role_permissions ON role_permissions.role = roles.name
+GROUP BY roles.name
+ORDER BY roles.name;
+
+-- name: UserRoles :many
+SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role;
+
+-- name: UserPermissions :many
+SELECT DISTINCT role_permissions.permission FROM user_roles JOIN role_permissions ON role_permissions.role = user_roles.role WHERE user_roles.user_id = $1 ORDER BY role_permissions.permission;
+
+-- name: UserRoleGrant :execresult
+INSERT INTO user_roles (user_id, role) VALUES ($1, $2) ON CONFLICT DO NOTHING;
+
+-- name: UserRoleRevoke :execresult
+DELETE FROM user_roles WHERE user_id = $1 AND role = $2;
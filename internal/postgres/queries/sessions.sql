@@ -0,0 +1,14 @@
+-- name: SessionInsert :one
+INSERT INTO sessions (id, user_id, hash, device, ip, user_agent, expires) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: SessionUse :one
+UPDATE sessions SET last_seen = now(), expires = $2 WHERE hash = $1 AND expires > now() RETURNING *;
+
+-- name: SessionList :many
+SELECT * FROM sessions WHERE user_id = $1 AND expires > now() ORDER BY last_seen DESC;
+
+-- name: SessionDelete :execresult
+DELETE FROM sessions WHERE id = $1 AND user_id = $2;
+
+-- name: SessionDeleteAllForUser :exec
+DELETE FROM sessions WHERE user_id = $1;
@@ -0,0 +1,11 @@
+-- name: TokenInsert :exec
+INSERT INTO tokens (hash, user_id, scope, expiry) VALUES ($1, $2, $3, $4);
+
+-- name: TokenConsume :one
+DELETE FROM tokens WHERE hash = $1 AND scope = $2 AND expiry > now() RETURNING user_id;
+
+-- name: TokenDeleteAllForUser :exec
+DELETE FROM tokens WHERE user_id = $1 AND scope = $2;
+
+-- name: TokenCountSince :one
+SELECT COUNT(*) FROM tokens WHERE user_id = $1 AND scope = $2 AND created > $3;
@@ -0,0 +1,21 @@
+-- name: TOTPRetrieve :one
+SELECT user_id, secret, created, confirmed FROM user_totp WHERE user_id = $1;
+
+-- name: TOTPEnroll :exec
+INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created = now(), confirmed = NULL;
+
+-- name: TOTPConfirm :execresult
+UPDATE user_totp SET confirmed = now() WHERE user_id = $1 AND confirmed IS NULL;
+
+-- name: TOTPDelete :execresult
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: RecoveryCodeInsert :exec
+INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2);
+
+-- name: RecoveryCodeDeleteAllForUser :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: RecoveryCodeConsume :execresult
+UPDATE recovery_codes SET used = now() WHERE user_id = $1 AND code_hash = $2 AND used IS NULL;
@@ -0,0 +1,5 @@
+-- name: IdentityRetrieve :one
+SELECT * FROM user_identities WHERE issuer = $1 AND subject = $2;
+
+-- name: IdentityInsert :one
+INSERT INTO user_identities (issuer, subject, user_id, email) VALUES ($1, $2, $3, $4) RETURNING *;
@@ -1,25 +1,50 @@
 -- name: UserRetrieve :one
-SELECT email, created,  id, admin FROM users WHERE id = $1 LIMIT 1;
+SELECT email, created,  id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, hashed_password, token_version, email_verified_at, status, status_reason, status_changed_at FROM users WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
 
 -- name: UserRetrieveByEmail :one
-SELECT email, created,  id, admin FROM users WHERE email = $1 LIMIT 1;
+SELECT email, created,  id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, hashed_password, token_version, email_verified_at, status, status_reason, status_changed_at FROM users WHERE lower(email) = lower($1) AND deleted_at IS NULL LIMIT 1;
+
+-- name: UserExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL);
+
+-- name: UsersCount :one
+SELECT COUNT(*) FROM users
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('organization_id')::uuid IS NULL OR EXISTS (SELECT 1 FROM organization_memberships WHERE organization_memberships.user_id = users.id AND organization_memberships.organization_id = sqlc.narg('organization_id')))
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'));
 
 -- name: UsersList :many
 WITH row_data AS (
-    SELECT email, created, id, admin FROM users ORDER BY email LIMIT $1 OFFSET $2
+    SELECT email, created, id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, email_verified_at, status, status_reason, status_changed_at FROM users
+    WHERE deleted_at IS NULL
+      AND (sqlc.narg('organization_id')::uuid IS NULL OR EXISTS (SELECT 1 FROM organization_memberships WHERE organization_memberships.user_id = users.id AND organization_memberships.organization_id = sqlc.narg('organization_id')))
+      AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+    ORDER BY email LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset')
 ) SELECT
       *,
-      (SELECT COUNT(*) FROM users) AS row_data
+      (SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND (sqlc.narg('organization_id')::uuid IS NULL OR EXISTS (SELECT 1 FROM organization_memberships WHERE organization_memberships.user_id = users.id AND organization_memberships.organization_id = sqlc.narg('organization_id'))) AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))) AS row_data
 FROM row_data;
 
 -- name: UserInsert :one
-INSERT INTO users (email, hashed_password, id, admin) VALUES ($1, $2, $3, $4) RETURNING email, created, id, admin;
+INSERT INTO users (email, hashed_password, id) VALUES ($1, $2, $3) RETURNING email, created, id, hashed_password, token_version, email_verified_at, status, status_reason, status_changed_at;
 
--- name: UserUpdatePassword :execresult
-UPDATE users SET hashed_password = $2 WHERE id = $1;
+-- name: UserUpdateEmail :execresult
+UPDATE users SET email = $2, email_verified_at = CASE WHEN lower(email) = lower($2) THEN email_verified_at END WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: UserVerifyEmail :execresult
+UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE id = $1 AND deleted_at IS NULL;
 
--- name: UserUpdateAdmin :execresult
-UPDATE users SET admin = $2 WHERE id = $1;
+-- name: UserUpdatePassword :execresult
+UPDATE users SET hashed_password = $2, token_version = token_version + 1 WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: UserDelete :execresult
-DELETE FROM users WHERE id = $1;
\ No newline at end of file
+UPDATE users SET deleted_at = now(), token_version = token_version + 1 WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: UserRestore :one
+UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING email;
+
+-- name: UserPurgeDeleted :many
+DELETE FROM users WHERE deleted_at < $1 RETURNING id, email;
+
+-- name: UserUpdateStatus :execresult
+UPDATE users SET status = $2, status_reason = $3, status_changed_at = now(), token_version = CASE WHEN $2 = 'active' THEN token_version ELSE token_version + 1 END WHERE id = $1 AND deleted_at IS NULL;
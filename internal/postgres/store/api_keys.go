@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func apiKeyFromModel(dbKey models.ApiKey) *store.APIKey {
+	return &store.APIKey{
+		ID:       dbKey.ID,
+		UserID:   dbKey.UserID,
+		Name:     dbKey.Name,
+		Prefix:   dbKey.Prefix,
+		Hash:     dbKey.Hash,
+		Scopes:   dbKey.Scopes,
+		Created:  dbKey.Created.Time,
+		Expires:  nullableTime(dbKey.Expires),
+		LastUsed: nullableTime(dbKey.LastUsed),
+	}
+}
+
+func (p *PostgresStore) APIKeyInsert(ctx context.Context, key store.APIKey) (*store.APIKey, error) {
+	query := models.New(p.db)
+	params := models.APIKeyInsertParams{
+		ID:     key.ID,
+		UserID: key.UserID,
+		Name:   key.Name,
+		Prefix: key.Prefix,
+		Hash:   key.Hash,
+		Scopes: key.Scopes,
+	}
+	if params.Scopes == nil {
+		params.Scopes = []string{}
+	}
+	if key.Expires != nil {
+		params.Expires = pgtype.Timestamptz{Time: *key.Expires, Valid: true}
+	}
+	dbKey, err := query.APIKeyInsert(ctx, params)
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			if pge.SQLState() == "23503" {
+				return nil, store.ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return apiKeyFromModel(dbKey), nil
+}
+
+func (p *PostgresStore) APIKeyList(ctx context.Context, userID uuid.UUID) ([]store.APIKey, error) {
+	query := models.New(p.db)
+	dbKeys, err := query.APIKeyList(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]store.APIKey, 0, len(dbKeys))
+	for _, dbKey := range dbKeys {
+		keys = append(keys, *apiKeyFromModel(dbKey))
+	}
+
+	return keys, nil
+}
+
+func (p *PostgresStore) APIKeyRetrieve(ctx context.Context, userID, id uuid.UUID) (*store.APIKey, error) {
+	query := models.New(p.db)
+	dbKey, err := query.APIKeyRetrieve(ctx, models.APIKeyRetrieveParams{ID: id, UserID: userID})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return apiKeyFromModel(dbKey), nil
+}
+
+func (p *PostgresStore) APIKeyUpdateName(ctx context.Context, userID, id uuid.UUID, name string) (*store.APIKey, error) {
+	query := models.New(p.db)
+	dbKey, err := query.APIKeyUpdateName(ctx, models.APIKeyUpdateNameParams{ID: id, UserID: userID, Name: name})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return apiKeyFromModel(dbKey), nil
+}
+
+func (p *PostgresStore) APIKeyDelete(ctx context.Context, userID, id uuid.UUID) error {
+	query := models.New(p.db)
+	res, err := query.APIKeyDelete(ctx, models.APIKeyDeleteParams{ID: id, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (p *PostgresStore) APIKeyUse(ctx context.Context, hash []byte) (*store.APIKey, error) {
+	query := models.New(p.db)
+	dbKey, err := query.APIKeyUse(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return apiKeyFromModel(dbKey), nil
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPIKey(t *testing.T, userID uuid.UUID, expires *time.Time) store.APIKey {
+	plaintext, hash, err := token.Generate()
+	require.Nil(t, err)
+	return store.APIKey{
+		ID:      uuid.New(),
+		UserID:  userID,
+		Name:    "test",
+		Prefix:  "gz_" + plaintext[:8],
+		Hash:    hash,
+		Scopes:  []string{store.PermissionUsersRead},
+		Expires: expires,
+	}
+}
+
+func TestPostgresStoreAPIKeys(t *testing.T) {
+	t.Run("APIKey happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		inserted, err := postgresStore.APIKeyInsert(context.Background(), newTestAPIKey(t, user.ID, nil))
+		require.Nil(t, err)
+		require.Equal(t, []string{store.PermissionUsersRead}, inserted.Scopes)
+		require.Nil(t, inserted.LastUsed)
+
+		used, err := postgresStore.APIKeyUse(context.Background(), inserted.Hash)
+		require.Nil(t, err)
+		require.Equal(t, inserted.ID, used.ID)
+		require.NotNil(t, used.LastUsed)
+
+		updated, err := postgresStore.APIKeyUpdateName(context.Background(), user.ID, inserted.ID, "renamed")
+		require.Nil(t, err)
+		require.Equal(t, "renamed", updated.Name)
+
+		keys, err := postgresStore.APIKeyList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Len(t, keys, 1)
+		require.Equal(t, "renamed", keys[0].Name)
+
+		err = postgresStore.APIKeyDelete(context.Background(), user.ID, inserted.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.APIKeyUse(context.Background(), inserted.Hash)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+		_, err = postgresStore.APIKeyRetrieve(context.Background(), user.ID, inserted.ID)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+	})
+
+	t.Run("APIKey scoped to owner", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		owner, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		other, err := postgresStore.UserInsert(context.Background(), "other@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		inserted, err := postgresStore.APIKeyInsert(context.Background(), newTestAPIKey(t, owner.ID, nil))
+		require.Nil(t, err)
+
+		_, err = postgresStore.APIKeyRetrieve(context.Background(), other.ID, inserted.ID)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+		_, err = postgresStore.APIKeyUpdateName(context.Background(), other.ID, inserted.ID, "stolen")
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+		err = postgresStore.APIKeyDelete(context.Background(), other.ID, inserted.ID)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+	})
+
+	t.Run("APIKeyUse expired", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		expired := time.Now().Add(-time.Minute)
+		inserted, err := postgresStore.APIKeyInsert(context.Background(), newTestAPIKey(t, user.ID, &expired))
+		require.Nil(t, err)
+
+		_, err = postgresStore.APIKeyUse(context.Background(), inserted.Hash)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+	})
+
+	t.Run("APIKeyInsert user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		_, err := postgresStore.APIKeyInsert(context.Background(), newTestAPIKey(t, uuid.New(), nil))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
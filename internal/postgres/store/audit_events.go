@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (p *PostgresStore) WithAudit(audit store.AuditContext) store.GuzeiStore {
+	return &PostgresStore{db: p.db, audit: audit}
+}
+
+// recordAudit writes an audit event using the given queries, which should be
+// bound to the transaction making the change.
+func (p *PostgresStore) recordAudit(ctx context.Context, query *models.Queries, action string, targetID uuid.UUID, diff map[string]store.AuditChange) error {
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	params := models.AuditEventInsertParams{
+		ID:        uuid.New(),
+		ActorID:   p.audit.ActorID,
+		TargetID:  &targetID,
+		Action:    action,
+		Ip:        p.audit.IP,
+		RequestID: p.audit.RequestID,
+		Diff:      diffJSON,
+	}
+	return query.AuditEventInsert(ctx, params)
+}
+
+func (p *PostgresStore) AuditRecord(ctx context.Context, action string, targetID uuid.UUID, diff map[string]store.AuditChange) error {
+	query := models.New(p.db)
+	return p.recordAudit(ctx, query, action, targetID, diff)
+}
+
+func (p *PostgresStore) AuditEventList(ctx context.Context, auditEventListParams store.AuditEventListParams) (*store.AuditEventsList, error) {
+	query := models.New(p.db)
+	params := models.AuditEventListParams{
+		ActorID:  auditEventListParams.ActorID,
+		TargetID: auditEventListParams.TargetID,
+		Limit:    int32(auditEventListParams.PageSize),
+		Offset:   int32((auditEventListParams.PageNumber - 1) * auditEventListParams.PageSize),
+	}
+	if auditEventListParams.Action != "" {
+		params.Action = pgtype.Text{String: auditEventListParams.Action, Valid: true}
+	}
+	if auditEventListParams.Since != nil {
+		params.Since = pgtype.Timestamptz{Time: *auditEventListParams.Since, Valid: true}
+	}
+	if auditEventListParams.Until != nil {
+		params.Until = pgtype.Timestamptz{Time: *auditEventListParams.Until, Valid: true}
+	}
+
+	dbEvents, err := query.AuditEventList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]store.AuditEvent, 0, len(dbEvents))
+	for _, dbEvent := range dbEvents {
+		var diff map[string]store.AuditChange
+		err = json.Unmarshal(dbEvent.Diff, &diff)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, store.AuditEvent{
+			ID:        dbEvent.ID,
+			ActorID:   dbEvent.ActorID,
+			TargetID:  dbEvent.TargetID,
+			Action:    dbEvent.Action,
+			IP:        dbEvent.Ip,
+			RequestID: dbEvent.RequestID,
+			Diff:      diff,
+			Created:   dbEvent.Created.Time,
+		})
+	}
+
+	totalObjects := 0
+	if len(dbEvents) > 0 {
+		totalObjects = int(dbEvents[0].RowData)
+	} else if params.Offset > 0 {
+		count, err := query.AuditEventCount(ctx, models.AuditEventCountParams{
+			ActorID:  params.ActorID,
+			TargetID: params.TargetID,
+			Action:   params.Action,
+			Since:    params.Since,
+			Until:    params.Until,
+		})
+		if err != nil {
+			return nil, err
+		}
+		totalObjects = int(count)
+	}
+	totalPages := int(math.Ceil(float64(totalObjects) / float64(auditEventListParams.PageSize)))
+
+	return &store.AuditEventsList{
+		Data:         events,
+		TotalObjects: totalObjects,
+		TotalPages:   totalPages,
+		Page:         auditEventListParams.PageNumber,
+		PageSize:     auditEventListParams.PageSize,
+	}, nil
+}
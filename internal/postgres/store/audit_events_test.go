@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreAuditEvents(t *testing.T) {
+	t.Run("AuditEvent happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		actorID := uuid.New()
+		audited := postgresStore.WithAudit(store.AuditContext{
+			ActorID:   &actorID,
+			IP:        "203.0.113.1",
+			RequestID: "host/abc-000001",
+		})
+
+		user, err := audited.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		err = audited.UserUpdateAdmin(context.Background(), user.ID, true)
+		require.Nil(t, err)
+		err = audited.UserUpdateAdmin(context.Background(), user.ID, true)
+		require.Nil(t, err)
+		err = audited.UserUpdatePassword(context.Background(), user.ID, "new-password")
+		require.Nil(t, err)
+
+		params := store.AuditEventListParams{TargetID: &user.ID, PageNumber: 1, PageSize: 10}
+		events, err := postgresStore.AuditEventList(context.Background(), params)
+		require.Nil(t, err)
+		require.Equal(t, 3, events.TotalObjects)
+
+		actions := make([]string, 0)
+		for _, event := range events.Data {
+			require.Equal(t, actorID, *event.ActorID)
+			require.Equal(t, "203.0.113.1", event.IP)
+			require.Equal(t, "host/abc-000001", event.RequestID)
+			actions = append(actions, event.Action)
+		}
+		require.ElementsMatch(t, []string{store.AuditUserCreate, store.AuditUserRoleGrant, store.AuditUserUpdatePassword}, actions)
+
+		params.Action = store.AuditUserUpdatePassword
+		events, err = postgresStore.AuditEventList(context.Background(), params)
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Equal(t, store.AuditRedacted, events.Data[0].Diff["password"].New)
+	})
+
+	t.Run("AuditEvent without actor", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		err = postgresStore.UserUpdateEmail(context.Background(), user.ID, "new@parham.im")
+		require.Nil(t, err)
+		err = postgresStore.UserDelete(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		events, err := postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserUpdateEmail, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Nil(t, events.Data[0].ActorID)
+		require.Equal(t, "im@parham.im", events.Data[0].Diff["email"].Old)
+		require.Equal(t, "new@parham.im", events.Data[0].Diff["email"].New)
+
+		events, err = postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserDelete, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Equal(t, "new@parham.im", events.Data[0].Diff["email"].Old)
+	})
+
+	t.Run("AuditEvent time range", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		_, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		since := time.Now().Add(time.Hour)
+		events, err := postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{Since: &since, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 0)
+
+		until := time.Now().Add(time.Hour)
+		events, err = postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{Until: &until, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+	})
+
+	t.Run("AuditEvent not written without a change", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		err = postgresStore.UserDelete(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+		err = postgresStore.UserRoleRevoke(context.Background(), user.ID, store.RoleAdmin)
+		require.Nil(t, err)
+		err = postgresStore.UserUpdateEmail(context.Background(), user.ID, "im@parham.im")
+		require.Nil(t, err)
+
+		events, err := postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Equal(t, store.AuditUserCreate, events.Data[0].Action)
+	})
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func invitationFromModel(dbInvitation models.Invitation) *store.Invitation {
+	return &store.Invitation{
+		ID:             dbInvitation.ID,
+		OrganizationID: dbInvitation.OrganizationID,
+		Email:          dbInvitation.Email,
+		Role:           dbInvitation.Role,
+		Hash:           dbInvitation.Hash,
+		InvitedBy:      dbInvitation.InvitedBy,
+		Created:        dbInvitation.Created.Time,
+		Expires:        dbInvitation.Expires.Time,
+	}
+}
+
+func (p *PostgresStore) InvitationInsert(ctx context.Context, invitation store.Invitation) (*store.Invitation, error) {
+	email, err := emailaddr.Normalize(invitation.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.New(p.db)
+	params := models.InvitationInsertParams{
+		ID:             invitation.ID,
+		OrganizationID: invitation.OrganizationID,
+		Email:          email,
+		Role:           invitation.Role,
+		Hash:           invitation.Hash,
+		InvitedBy:      invitation.InvitedBy,
+		Expires:        pgtype.Timestamptz{Time: invitation.Expires, Valid: true},
+	}
+	dbInvitation, err := query.InvitationInsert(ctx, params)
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			switch pge.SQLState() {
+			case "23505":
+				return nil, store.ErrInvitationExists
+			case "23503":
+				if pge.ConstraintName == "invitations_organization_id_fkey" {
+					return nil, store.ErrOrganizationNotFound
+				}
+				return nil, store.ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return invitationFromModel(dbInvitation), nil
+}
+
+func (p *PostgresStore) InvitationRetrieve(ctx context.Context, organizationID, id uuid.UUID) (*store.Invitation, error) {
+	query := models.New(p.db)
+	params := models.InvitationRetrieveParams{OrganizationID: organizationID, ID: id}
+	dbInvitation, err := query.InvitationRetrieve(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return invitationFromModel(dbInvitation), nil
+}
+
+func (p *PostgresStore) InvitationRetrieveByHash(ctx context.Context, hash []byte) (*store.Invitation, error) {
+	query := models.New(p.db)
+	dbInvitation, err := query.InvitationRetrieveByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return invitationFromModel(dbInvitation), nil
+}
+
+func (p *PostgresStore) InvitationList(ctx context.Context, organizationID uuid.UUID) ([]store.Invitation, error) {
+	query := models.New(p.db)
+	dbInvitations, err := query.InvitationList(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := make([]store.Invitation, 0, len(dbInvitations))
+	for _, dbInvitation := range dbInvitations {
+		invitations = append(invitations, *invitationFromModel(dbInvitation))
+	}
+	return invitations, nil
+}
+
+func (p *PostgresStore) InvitationRenew(ctx context.Context, organizationID, id uuid.UUID, hash []byte, expires time.Time) (*store.Invitation, error) {
+	query := models.New(p.db)
+	params := models.InvitationRenewParams{
+		OrganizationID: organizationID,
+		ID:             id,
+		Hash:           hash,
+		Expires:        pgtype.Timestamptz{Time: expires, Valid: true},
+	}
+	dbInvitation, err := query.InvitationRenew(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return invitationFromModel(dbInvitation), nil
+}
+
+func (p *PostgresStore) InvitationDelete(ctx context.Context, organizationID, id uuid.UUID) error {
+	query := models.New(p.db)
+	params := models.InvitationDeleteParams{OrganizationID: organizationID, ID: id}
+	res, err := query.InvitationDelete(ctx, params)
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (p *PostgresStore) InvitationAccept(ctx context.Context, hash []byte, userID uuid.UUID, hashedPassword string) (*store.Membership, error) {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.New(tx)
+	membership, err := p.acceptInvitation(ctx, query, hash, userID, hashedPassword)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return membership, nil
+}
+
+func (p *PostgresStore) acceptInvitation(ctx context.Context, query *models.Queries, hash []byte, userID uuid.UUID, hashedPassword string) (*store.Membership, error) {
+	invitation, err := query.InvitationConsume(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	user, err := query.UserRetrieveByEmail(ctx, invitation.Email)
+	switch {
+	case err == nil:
+		userID = user.ID
+	case errors.Is(err, pgx.ErrNoRows):
+		_, err = query.UserInsert(ctx, models.UserInsertParams{Email: invitation.Email, HashedPassword: hashedPassword, ID: userID})
+		if err != nil {
+			var pge *pgconn.PgError
+			if errors.As(err, &pge) && pge.SQLState() == "23505" {
+				return nil, store.ErrUserExists
+			}
+			return nil, err
+		}
+
+		_, err = query.UserVerifyEmail(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.recordAudit(ctx, query, store.AuditUserCreate, userID, map[string]store.AuditChange{
+			"email": {New: invitation.Email},
+			"admin": {New: false},
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	current, err := query.MembershipRetrieve(ctx, models.MembershipRetrieveParams{OrganizationID: invitation.OrganizationID, UserID: userID})
+	switch {
+	case err == nil:
+		return membershipFromModel(current), nil
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, err
+	}
+
+	return p.upsertMembership(ctx, query, invitation.OrganizationID, userID, invitation.Role)
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreInvitations(t *testing.T) {
+	t.Run("Invitation happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		owner, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		organization, err := postgresStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+
+		invitation, err := postgresStore.InvitationInsert(context.Background(), store.Invitation{
+			ID:             uuid.New(),
+			OrganizationID: organization.ID,
+			Email:          "new@parham.im",
+			Role:           store.OrganizationRoleAdmin,
+			Hash:           []byte("hash"),
+			InvitedBy:      &owner.ID,
+			Expires:        time.Now().Add(time.Hour),
+		})
+		require.Nil(t, err)
+		require.Equal(t, "new@parham.im", invitation.Email)
+
+		_, err = postgresStore.InvitationInsert(context.Background(), store.Invitation{
+			ID:             uuid.New(),
+			OrganizationID: organization.ID,
+			Email:          "new@parham.im",
+			Role:           store.OrganizationRoleMember,
+			Hash:           []byte("other"),
+			Expires:        time.Now().Add(time.Hour),
+		})
+		require.Equal(t, store.ErrInvitationExists, err)
+
+		invitations, err := postgresStore.InvitationList(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Len(t, invitations, 1)
+
+		invitation, err = postgresStore.InvitationRenew(context.Background(), organization.ID, invitation.ID, []byte("renewed"), time.Now().Add(time.Hour))
+		require.Nil(t, err)
+		_, err = postgresStore.InvitationRetrieveByHash(context.Background(), []byte("hash"))
+		require.Equal(t, store.ErrInvitationNotFound, err)
+
+		membership, err := postgresStore.InvitationAccept(context.Background(), []byte("renewed"), uuid.New(), "password")
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleAdmin, membership.Role)
+
+		user, err := postgresStore.UserRetrieveByEmail(context.Background(), "new@parham.im")
+		require.Nil(t, err)
+		require.NotNil(t, user.EmailVerified)
+		require.Equal(t, user.ID, membership.UserID)
+
+		_, err = postgresStore.InvitationAccept(context.Background(), []byte("renewed"), uuid.New(), "password")
+		require.Equal(t, store.ErrInvitationNotFound, err)
+		_, err = postgresStore.InvitationRetrieve(context.Background(), organization.ID, invitation.ID)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+	})
+
+	t.Run("Accept keeps an existing membership", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		owner, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		organization, err := postgresStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.InvitationInsert(context.Background(), store.Invitation{
+			ID:             uuid.New(),
+			OrganizationID: organization.ID,
+			Email:          "im@parham.im",
+			Role:           store.OrganizationRoleMember,
+			Hash:           []byte("hash"),
+			Expires:        time.Now().Add(time.Hour),
+		})
+		require.Nil(t, err)
+
+		membership, err := postgresStore.InvitationAccept(context.Background(), []byte("hash"), uuid.New(), "")
+		require.Nil(t, err)
+		require.Equal(t, owner.ID, membership.UserID)
+		require.Equal(t, store.OrganizationRoleOwner, membership.Role)
+	})
+
+	t.Run("Expired and missing invitations", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		owner, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		organization, err := postgresStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.InvitationInsert(context.Background(), store.Invitation{
+			ID:             uuid.New(),
+			OrganizationID: organization.ID,
+			Email:          "late@parham.im",
+			Role:           store.OrganizationRoleMember,
+			Hash:           []byte("hash"),
+			Expires:        time.Now().Add(-time.Minute),
+		})
+		require.Nil(t, err)
+
+		_, err = postgresStore.InvitationRetrieveByHash(context.Background(), []byte("hash"))
+		require.Equal(t, store.ErrInvitationNotFound, err)
+		_, err = postgresStore.InvitationAccept(context.Background(), []byte("hash"), uuid.New(), "password")
+		require.Equal(t, store.ErrInvitationNotFound, err)
+		_, err = postgresStore.UserRetrieveByEmail(context.Background(), "late@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+
+		_, err = postgresStore.InvitationInsert(context.Background(), store.Invitation{
+			ID:             uuid.New(),
+			OrganizationID: uuid.New(),
+			Email:          "new@parham.im",
+			Role:           store.OrganizationRoleMember,
+			Hash:           []byte("other"),
+			Expires:        time.Now().Add(time.Hour),
+		})
+		require.Equal(t, store.ErrOrganizationNotFound, err)
+		err = postgresStore.InvitationDelete(context.Background(), organization.ID, uuid.New())
+		require.Equal(t, store.ErrInvitationNotFound, err)
+	})
+}
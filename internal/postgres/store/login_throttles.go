@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func loginThrottleFromModel(dbThrottle models.LoginThrottle) *store.LoginThrottle {
+	return &store.LoginThrottle{
+		Kind:        dbThrottle.Kind,
+		Subject:     dbThrottle.Subject,
+		Failures:    int(dbThrottle.Failures),
+		LastFailure: dbThrottle.LastFailure.Time,
+		LockedUntil: nullableTime(dbThrottle.LockedUntil),
+	}
+}
+
+func (p *PostgresStore) LoginThrottleRetrieve(ctx context.Context, kind, subject string) (*store.LoginThrottle, error) {
+	query := models.New(p.db)
+	dbThrottle, err := query.LoginThrottleRetrieve(ctx, models.LoginThrottleRetrieveParams{Kind: kind, Subject: subject})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrLoginThrottleNotFound
+		}
+		return nil, err
+	}
+
+	return loginThrottleFromModel(dbThrottle), nil
+}
+
+func (p *PostgresStore) LoginFailureRecord(ctx context.Context, kind, subject string, at, windowStart time.Time) (*store.LoginThrottle, error) {
+	query := models.New(p.db)
+	params := models.LoginFailureRecordParams{
+		Kind:        kind,
+		Subject:     subject,
+		At:          pgtype.Timestamptz{Time: at, Valid: true},
+		WindowStart: pgtype.Timestamptz{Time: windowStart, Valid: true},
+	}
+	dbThrottle, err := query.LoginFailureRecord(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return loginThrottleFromModel(dbThrottle), nil
+}
+
+func (p *PostgresStore) LoginThrottleLock(ctx context.Context, kind, subject string, until time.Time) error {
+	query := models.New(p.db)
+	params := models.LoginThrottleLockParams{
+		Kind:        kind,
+		Subject:     subject,
+		LockedUntil: pgtype.Timestamptz{Time: until, Valid: true},
+	}
+	return query.LoginThrottleLock(ctx, params)
+}
+
+func (p *PostgresStore) LoginThrottleReset(ctx context.Context, kind, subject string) error {
+	query := models.New(p.db)
+	return query.LoginThrottleReset(ctx, models.LoginThrottleResetParams{Kind: kind, Subject: subject})
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreLoginThrottles(t *testing.T) {
+	t.Run("LoginThrottle happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		_, err := postgresStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleAccount, "im@parham.im")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+
+		now := time.Now()
+		for i := 1; i <= 3; i++ {
+			throttle, err := postgresStore.LoginFailureRecord(context.Background(), store.LoginThrottleAccount, "im@parham.im", now, now.Add(-time.Hour))
+			require.Nil(t, err)
+			require.Equal(t, i, throttle.Failures)
+			require.Nil(t, throttle.LockedUntil)
+		}
+
+		lockedUntil := now.Add(time.Minute)
+		err = postgresStore.LoginThrottleLock(context.Background(), store.LoginThrottleAccount, "im@parham.im", lockedUntil)
+		require.Nil(t, err)
+
+		// An earlier lock doesn't shorten the current one.
+		err = postgresStore.LoginThrottleLock(context.Background(), store.LoginThrottleAccount, "im@parham.im", now)
+		require.Nil(t, err)
+
+		throttle, err := postgresStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleAccount, "im@parham.im")
+		require.Nil(t, err)
+		require.Equal(t, 3, throttle.Failures)
+		require.NotNil(t, throttle.LockedUntil)
+		require.WithinDuration(t, lockedUntil, *throttle.LockedUntil, time.Millisecond)
+
+		// Throttles for IP addresses are tracked separately.
+		_, err = postgresStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleIP, "im@parham.im")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+
+		err = postgresStore.LoginThrottleReset(context.Background(), store.LoginThrottleAccount, "im@parham.im")
+		require.Nil(t, err)
+
+		_, err = postgresStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleAccount, "im@parham.im")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+	})
+
+	t.Run("LoginFailureRecord outside window", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		now := time.Now()
+		_, err := postgresStore.LoginFailureRecord(context.Background(), store.LoginThrottleIP, "203.0.113.1", now.Add(-2*time.Hour), now.Add(-3*time.Hour))
+		require.Nil(t, err)
+
+		throttle, err := postgresStore.LoginFailureRecord(context.Background(), store.LoginThrottleIP, "203.0.113.1", now, now.Add(-time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, 1, throttle.Failures)
+	})
+}
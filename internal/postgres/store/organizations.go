@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func organizationFromModel(dbOrganization models.Organization) *store.Organization {
+	return &store.Organization{
+		ID:      dbOrganization.ID,
+		Name:    dbOrganization.Name,
+		Created: dbOrganization.Created.Time,
+	}
+}
+
+func membershipFromModel(dbMembership models.MembershipRetrieveRow) *store.Membership {
+	return &store.Membership{
+		OrganizationID: dbMembership.OrganizationID,
+		UserID:         dbMembership.UserID,
+		Email:          dbMembership.Email,
+		Role:           dbMembership.Role,
+		Created:        dbMembership.Created.Time,
+	}
+}
+
+func (p *PostgresStore) OrganizationInsert(ctx context.Context, organization store.Organization, ownerID uuid.UUID) (*store.Organization, error) {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.New(tx)
+	dbOrganization, err := query.OrganizationInsert(ctx, models.OrganizationInsertParams{ID: organization.ID, Name: organization.Name})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	_, err = p.upsertMembership(ctx, query, organization.ID, ownerID, store.OrganizationRoleOwner)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return organizationFromModel(dbOrganization), nil
+}
+
+func (p *PostgresStore) OrganizationRetrieve(ctx context.Context, id uuid.UUID) (*store.Organization, error) {
+	query := models.New(p.db)
+	dbOrganization, err := query.OrganizationRetrieve(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+
+	return organizationFromModel(dbOrganization), nil
+}
+
+func (p *PostgresStore) OrganizationListForUser(ctx context.Context, userID uuid.UUID) ([]store.Organization, error) {
+	query := models.New(p.db)
+	dbOrganizations, err := query.OrganizationListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	organizations := make([]store.Organization, 0, len(dbOrganizations))
+	for _, dbOrganization := range dbOrganizations {
+		organizations = append(organizations, *organizationFromModel(dbOrganization))
+	}
+	return organizations, nil
+}
+
+func (p *PostgresStore) MembershipRetrieve(ctx context.Context, organizationID, userID uuid.UUID) (*store.Membership, error) {
+	query := models.New(p.db)
+	params := models.MembershipRetrieveParams{OrganizationID: organizationID, UserID: userID}
+	dbMembership, err := query.MembershipRetrieve(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrMembershipNotFound
+		}
+		return nil, err
+	}
+
+	return membershipFromModel(dbMembership), nil
+}
+
+func (p *PostgresStore) MembershipList(ctx context.Context, organizationID uuid.UUID) ([]store.Membership, error) {
+	query := models.New(p.db)
+	dbMemberships, err := query.MembershipList(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := make([]store.Membership, 0, len(dbMemberships))
+	for _, dbMembership := range dbMemberships {
+		memberships = append(memberships, *membershipFromModel(models.MembershipRetrieveRow(dbMembership)))
+	}
+	return memberships, nil
+}
+
+func (p *PostgresStore) MembershipUpsert(ctx context.Context, organizationID, userID uuid.UUID, role string) (*store.Membership, error) {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.New(tx)
+	membership, err := p.upsertMembership(ctx, query, organizationID, userID, role)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return membership, nil
+}
+
+// upsertMembership adds or updates a membership using the given queries,
+// which should be bound to a transaction, and records the change in the
+// audit log.
+func (p *PostgresStore) upsertMembership(ctx context.Context, query *models.Queries, organizationID, userID uuid.UUID, role string) (*store.Membership, error) {
+	params := models.MembershipRetrieveParams{OrganizationID: organizationID, UserID: userID}
+	current, err := query.MembershipRetrieve(ctx, params)
+	exists := err == nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	_, err = query.MembershipUpsert(ctx, models.MembershipUpsertParams{OrganizationID: organizationID, UserID: userID, Role: role})
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) && pge.SQLState() == "23503" {
+			if pge.ConstraintName == "organization_memberships_organization_id_fkey" {
+				return nil, store.ErrOrganizationNotFound
+			}
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if !exists || current.Role != role {
+		roleChange := store.AuditChange{New: role}
+		if exists {
+			roleChange.Old = current.Role
+		}
+		err = p.recordAudit(ctx, query, store.AuditMembershipUpdate, userID, map[string]store.AuditChange{
+			"organization": {New: organizationID},
+			"role":         roleChange,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dbMembership, err := query.MembershipRetrieve(ctx, params)
+	if err != nil {
+		// Deleted users keep their row, so the foreign key doesn't catch them.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return membershipFromModel(dbMembership), nil
+}
+
+func (p *PostgresStore) MembershipDelete(ctx context.Context, organizationID, userID uuid.UUID) error {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := models.New(tx)
+	params := models.MembershipDeleteParams{OrganizationID: organizationID, UserID: userID}
+	current, err := query.MembershipRetrieve(ctx, models.MembershipRetrieveParams(params))
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrMembershipNotFound
+		}
+		return err
+	}
+
+	_, err = query.MembershipDelete(ctx, params)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = p.recordAudit(ctx, query, store.AuditMembershipDelete, userID, map[string]store.AuditChange{
+		"organization": {Old: organizationID},
+		"role":         {Old: current.Role},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreOrganizations(t *testing.T) {
+	t.Run("Organization happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		owner, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		member, err := postgresStore.UserInsert(context.Background(), "member@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		organization, err := postgresStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+		require.Equal(t, "Acme", organization.Name)
+
+		retrieved, err := postgresStore.OrganizationRetrieve(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Equal(t, organization.ID, retrieved.ID)
+
+		membership, err := postgresStore.MembershipRetrieve(context.Background(), organization.ID, owner.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleOwner, membership.Role)
+		require.Equal(t, "im@parham.im", membership.Email)
+
+		membership, err = postgresStore.MembershipUpsert(context.Background(), organization.ID, member.ID, store.OrganizationRoleMember)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleMember, membership.Role)
+		membership, err = postgresStore.MembershipUpsert(context.Background(), organization.ID, member.ID, store.OrganizationRoleAdmin)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleAdmin, membership.Role)
+
+		memberships, err := postgresStore.MembershipList(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Len(t, memberships, 2)
+
+		organizations, err := postgresStore.OrganizationListForUser(context.Background(), member.ID)
+		require.Nil(t, err)
+		require.Len(t, organizations, 1)
+
+		err = postgresStore.MembershipDelete(context.Background(), organization.ID, member.ID)
+		require.Nil(t, err)
+		_, err = postgresStore.MembershipRetrieve(context.Background(), organization.ID, member.ID)
+		require.Equal(t, store.ErrMembershipNotFound, err)
+		err = postgresStore.MembershipDelete(context.Background(), organization.ID, member.ID)
+		require.Equal(t, store.ErrMembershipNotFound, err)
+
+		events, err := postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{TargetID: &member.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 4)
+	})
+
+	t.Run("Organization not found", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		_, err = postgresStore.OrganizationRetrieve(context.Background(), uuid.New())
+		require.Equal(t, store.ErrOrganizationNotFound, err)
+		_, err = postgresStore.MembershipUpsert(context.Background(), uuid.New(), user.ID, store.OrganizationRoleMember)
+		require.Equal(t, store.ErrOrganizationNotFound, err)
+		_, err = postgresStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserList scoped to organization", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		owner, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		_, err = postgresStore.UserInsert(context.Background(), "outsider@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		organization, err := postgresStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+
+		users, err := postgresStore.UserList(context.Background(), store.UserListParams{OrganizationID: &organization.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 1, users.TotalObjects)
+		require.Equal(t, owner.ID, users.Data[0].ID)
+
+		users, err = postgresStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 2, users.TotalObjects)
+	})
+}
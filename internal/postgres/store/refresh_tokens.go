@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func refreshTokenFromModel(dbToken models.RefreshToken) *store.RefreshToken {
+	token := &store.RefreshToken{
+		ID:       dbToken.ID,
+		FamilyID: dbToken.FamilyID,
+		UserID:   dbToken.UserID,
+		Hash:     dbToken.TokenHash,
+		Created:  dbToken.Created.Time,
+		Expires:  dbToken.Expires.Time,
+	}
+	if dbToken.Used.Valid {
+		token.Used = &dbToken.Used.Time
+	}
+	if dbToken.Revoked.Valid {
+		token.Revoked = &dbToken.Revoked.Time
+	}
+	return token
+}
+
+func (p *PostgresStore) RefreshTokenInsert(ctx context.Context, token store.RefreshToken) (*store.RefreshToken, error) {
+	query := models.New(p.db)
+	params := models.RefreshTokenInsertParams{
+		ID:        token.ID,
+		FamilyID:  token.FamilyID,
+		UserID:    token.UserID,
+		TokenHash: token.Hash,
+		Expires:   pgtype.Timestamptz{Time: token.Expires, Valid: true},
+	}
+	dbToken, err := query.RefreshTokenInsert(ctx, params)
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			if pge.SQLState() == "23503" {
+				return nil, store.ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return refreshTokenFromModel(dbToken), nil
+}
+
+func (p *PostgresStore) RefreshTokenRetrieve(ctx context.Context, hash []byte) (*store.RefreshToken, error) {
+	query := models.New(p.db)
+	dbToken, err := query.RefreshTokenRetrieveByHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return refreshTokenFromModel(dbToken), nil
+}
+
+func (p *PostgresStore) RefreshTokenRotate(ctx context.Context, id uuid.UUID, next store.RefreshToken) (*store.RefreshToken, error) {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.New(tx)
+	res, err := query.RefreshTokenMarkUsed(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+	if res.RowsAffected() == 0 {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, store.ErrRefreshTokenReused
+	}
+
+	params := models.RefreshTokenInsertParams{
+		ID:        next.ID,
+		FamilyID:  next.FamilyID,
+		UserID:    next.UserID,
+		TokenHash: next.Hash,
+		Expires:   pgtype.Timestamptz{Time: next.Expires, Valid: true},
+	}
+	dbToken, err := query.RefreshTokenInsert(ctx, params)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return refreshTokenFromModel(dbToken), nil
+}
+
+func (p *PostgresStore) RefreshTokenRevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := models.New(p.db)
+	return query.RefreshTokenRevokeFamily(ctx, familyID)
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRefreshToken(t *testing.T, userID, familyID uuid.UUID) store.RefreshToken {
+	_, hash, err := token.Generate()
+	require.Nil(t, err)
+	return store.RefreshToken{
+		ID:       uuid.New(),
+		FamilyID: familyID,
+		UserID:   userID,
+		Hash:     hash,
+		Expires:  time.Now().Add(time.Hour),
+	}
+}
+
+func TestPostgresStoreRefreshTokenInsert(t *testing.T) {
+	t.Run("RefreshTokenInsert happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		refreshToken := newTestRefreshToken(t, user.ID, uuid.New())
+		inserted, err := postgresStore.RefreshTokenInsert(context.Background(), refreshToken)
+		require.Nil(t, err)
+		require.Equal(t, refreshToken.ID, inserted.ID)
+		require.Equal(t, refreshToken.Hash, inserted.Hash)
+		require.Nil(t, inserted.Used)
+		require.Nil(t, inserted.Revoked)
+
+		retrieved, err := postgresStore.RefreshTokenRetrieve(context.Background(), refreshToken.Hash)
+		require.Nil(t, err)
+		require.Equal(t, inserted, retrieved)
+	})
+
+	t.Run("RefreshTokenInsert user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		_, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, uuid.New(), uuid.New()))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("RefreshTokenRetrieve not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		_, err := postgresStore.RefreshTokenRetrieve(context.Background(), token.Hash("missing"))
+		require.Equal(t, store.ErrRefreshTokenNotFound, err)
+	})
+}
+
+func TestPostgresStoreRefreshTokenRotate(t *testing.T) {
+	t.Run("RefreshTokenRotate happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		familyID := uuid.New()
+		current, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, familyID))
+		require.Nil(t, err)
+
+		next, err := postgresStore.RefreshTokenRotate(context.Background(), current.ID, newTestRefreshToken(t, user.ID, familyID))
+		require.Nil(t, err)
+		require.Equal(t, familyID, next.FamilyID)
+
+		used, err := postgresStore.RefreshTokenRetrieve(context.Background(), current.Hash)
+		require.Nil(t, err)
+		require.NotNil(t, used.Used)
+	})
+
+	t.Run("RefreshTokenRotate already used", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		familyID := uuid.New()
+		current, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, familyID))
+		require.Nil(t, err)
+
+		_, err = postgresStore.RefreshTokenRotate(context.Background(), current.ID, newTestRefreshToken(t, user.ID, familyID))
+		require.Nil(t, err)
+
+		replacement := newTestRefreshToken(t, user.ID, familyID)
+		_, err = postgresStore.RefreshTokenRotate(context.Background(), current.ID, replacement)
+		require.Equal(t, store.ErrRefreshTokenReused, err)
+
+		_, err = postgresStore.RefreshTokenRetrieve(context.Background(), replacement.Hash)
+		require.Equal(t, store.ErrRefreshTokenNotFound, err)
+	})
+}
+
+func TestPostgresStoreRefreshTokenRevokeFamily(t *testing.T) {
+	t.Run("RefreshTokenRevokeFamily happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		familyID := uuid.New()
+		first, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, familyID))
+		require.Nil(t, err)
+		second, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, familyID))
+		require.Nil(t, err)
+		other, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, uuid.New()))
+		require.Nil(t, err)
+
+		err = postgresStore.RefreshTokenRevokeFamily(context.Background(), familyID)
+		require.Nil(t, err)
+
+		for _, hash := range [][]byte{first.Hash, second.Hash} {
+			revoked, err := postgresStore.RefreshTokenRetrieve(context.Background(), hash)
+			require.Nil(t, err)
+			require.NotNil(t, revoked.Revoked)
+		}
+
+		untouched, err := postgresStore.RefreshTokenRetrieve(context.Background(), other.Hash)
+		require.Nil(t, err)
+		require.Nil(t, untouched.Revoked)
+
+		_, err = postgresStore.RefreshTokenRotate(context.Background(), first.ID, newTestRefreshToken(t, user.ID, familyID))
+		require.Equal(t, store.ErrRefreshTokenReused, err)
+	})
+}
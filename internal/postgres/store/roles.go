@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (p *PostgresStore) RoleList(ctx context.Context) ([]store.Role, error) {
+	query := models.New(p.db)
+	dbRoles, err := query.RoleList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]store.Role, 0, len(dbRoles))
+	for _, role := range dbRoles {
+		roles = append(roles, store.Role{
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: role.Permissions,
+		})
+	}
+	return roles, nil
+}
+
+func (p *PostgresStore) UserRoles(ctx context.Context, id uuid.UUID) ([]string, error) {
+	query := models.New(p.db)
+	exists, err := query.UserExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, store.ErrUserNotFound
+	}
+
+	roles, err := query.UserRoles(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	return roles, nil
+}
+
+func (p *PostgresStore) UserPermissions(ctx context.Context, id uuid.UUID) ([]string, error) {
+	query := models.New(p.db)
+	permissions, err := query.UserPermissions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return permissions, nil
+}
+
+func (p *PostgresStore) UserRoleGrant(ctx context.Context, id uuid.UUID, role string) error {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := models.New(tx)
+	res, err := query.UserRoleGrant(ctx, models.UserRoleGrantParams{UserID: id, Role: role})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) && pge.SQLState() == "23503" {
+			if pge.ConstraintName == "user_roles_role_fkey" {
+				return store.ErrRoleNotFound
+			}
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
+	if res.RowsAffected() > 0 {
+		err = p.recordAudit(ctx, query, store.AuditUserRoleGrant, id, map[string]store.AuditChange{
+			"role": {New: role},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (p *PostgresStore) UserRoleRevoke(ctx context.Context, id uuid.UUID, role string) error {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := models.New(tx)
+	exists, err := query.UserExists(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+	if !exists {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrUserNotFound
+	}
+
+	res, err := query.UserRoleRevoke(ctx, models.UserRoleRevokeParams{UserID: id, Role: role})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if res.RowsAffected() > 0 {
+		err = p.recordAudit(ctx, query, store.AuditUserRoleRevoke, id, map[string]store.AuditChange{
+			"role": {Old: role},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
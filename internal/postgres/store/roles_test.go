@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreRoleList(t *testing.T) {
+	t.Run("RoleList returns seeded roles", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		roles, err := postgresStore.RoleList(context.Background())
+		require.Nil(t, err)
+		require.Len(t, roles, 2)
+
+		require.Equal(t, store.RoleAdmin, roles[0].Name)
+		require.Equal(t, []string{store.PermissionUsersAdmin, store.PermissionUsersRead, store.PermissionUsersWrite}, roles[0].Permissions)
+		require.Equal(t, "user-manager", roles[1].Name)
+		require.Equal(t, []string{store.PermissionUsersRead, store.PermissionUsersWrite}, roles[1].Permissions)
+	})
+}
+
+func TestPostgresStoreUserRoles(t *testing.T) {
+	t.Run("UserInsert admin grants admin role", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), true)
+		require.Nil(t, err)
+
+		roles, err := postgresStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.RoleAdmin}, roles)
+
+		permissions, err := postgresStore.UserPermissions(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.PermissionUsersAdmin, store.PermissionUsersRead, store.PermissionUsersWrite}, permissions)
+	})
+
+	t.Run("UserRoleGrant and UserRoleRevoke", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		permissions, err := postgresStore.UserPermissions(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, permissions)
+
+		err = postgresStore.UserRoleGrant(context.Background(), user.ID, "user-manager")
+		require.Nil(t, err)
+		err = postgresStore.UserRoleGrant(context.Background(), user.ID, "user-manager")
+		require.Nil(t, err)
+
+		permissions, err = postgresStore.UserPermissions(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.PermissionUsersRead, store.PermissionUsersWrite}, permissions)
+
+		retrieved, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.False(t, retrieved.Admin)
+
+		err = postgresStore.UserRoleRevoke(context.Background(), user.ID, "user-manager")
+		require.Nil(t, err)
+
+		roles, err := postgresStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, roles)
+	})
+
+	t.Run("UserRoleGrant unknown role", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		err = postgresStore.UserRoleGrant(context.Background(), user.ID, "wizard")
+		require.Equal(t, store.ErrRoleNotFound, err)
+	})
+
+	t.Run("UserRoleGrant user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		err := postgresStore.UserRoleGrant(context.Background(), uuid.New(), store.RoleAdmin)
+		require.Equal(t, store.ErrUserNotFound, err)
+
+		err = postgresStore.UserRoleRevoke(context.Background(), uuid.New(), store.RoleAdmin)
+		require.Equal(t, store.ErrUserNotFound, err)
+
+		_, err = postgresStore.UserRoles(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserUpdateAdmin maps onto the admin role", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		err = postgresStore.UserUpdateAdmin(context.Background(), user.ID, true)
+		require.Nil(t, err)
+
+		roles, err := postgresStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.RoleAdmin}, roles)
+
+		retrieved, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.True(t, retrieved.Admin)
+	})
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func sessionFromModel(dbSession models.Session) *store.Session {
+	return &store.Session{
+		ID:        dbSession.ID,
+		UserID:    dbSession.UserID,
+		Hash:      dbSession.Hash,
+		Device:    dbSession.Device,
+		IP:        dbSession.Ip,
+		UserAgent: dbSession.UserAgent,
+		Created:   dbSession.Created.Time,
+		LastSeen:  dbSession.LastSeen.Time,
+		Expires:   dbSession.Expires.Time,
+	}
+}
+
+func (p *PostgresStore) SessionInsert(ctx context.Context, session store.Session) (*store.Session, error) {
+	query := models.New(p.db)
+	params := models.SessionInsertParams{
+		ID:        session.ID,
+		UserID:    session.UserID,
+		Hash:      session.Hash,
+		Device:    session.Device,
+		Ip:        session.IP,
+		UserAgent: session.UserAgent,
+		Expires:   pgtype.Timestamptz{Time: session.Expires, Valid: true},
+	}
+	dbSession, err := query.SessionInsert(ctx, params)
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			if pge.SQLState() == "23503" {
+				return nil, store.ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return sessionFromModel(dbSession), nil
+}
+
+func (p *PostgresStore) SessionUse(ctx context.Context, hash []byte, expires time.Time) (*store.Session, error) {
+	query := models.New(p.db)
+	params := models.SessionUseParams{
+		Hash:    hash,
+		Expires: pgtype.Timestamptz{Time: expires, Valid: true},
+	}
+	dbSession, err := query.SessionUse(ctx, params)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return sessionFromModel(dbSession), nil
+}
+
+func (p *PostgresStore) SessionList(ctx context.Context, userID uuid.UUID) ([]store.Session, error) {
+	query := models.New(p.db)
+	dbSessions, err := query.SessionList(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]store.Session, 0, len(dbSessions))
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, *sessionFromModel(dbSession))
+	}
+
+	return sessions, nil
+}
+
+func (p *PostgresStore) SessionDelete(ctx context.Context, userID, id uuid.UUID) error {
+	query := models.New(p.db)
+	res, err := query.SessionDelete(ctx, models.SessionDeleteParams{ID: id, UserID: userID})
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrSessionNotFound
+	}
+	return nil
+}
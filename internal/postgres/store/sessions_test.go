@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSession(t *testing.T, userID uuid.UUID, expires time.Time) store.Session {
+	_, hash, err := token.Generate()
+	require.Nil(t, err)
+	return store.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Hash:      hash,
+		Device:    "Firefox on Linux",
+		IP:        "203.0.113.1",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/118.0",
+		Expires:   expires,
+	}
+}
+
+func TestPostgresStoreSessions(t *testing.T) {
+	t.Run("Session happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		inserted, err := postgresStore.SessionInsert(context.Background(), newTestSession(t, user.ID, time.Now().Add(time.Hour)))
+		require.Nil(t, err)
+		require.Equal(t, "203.0.113.1", inserted.IP)
+
+		extended := time.Now().Add(2 * time.Hour)
+		used, err := postgresStore.SessionUse(context.Background(), inserted.Hash, extended)
+		require.Nil(t, err)
+		require.Equal(t, inserted.ID, used.ID)
+		require.WithinDuration(t, extended, used.Expires, time.Millisecond)
+		require.False(t, used.LastSeen.Before(inserted.LastSeen))
+
+		sessions, err := postgresStore.SessionList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Len(t, sessions, 1)
+
+		err = postgresStore.SessionDelete(context.Background(), user.ID, inserted.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.SessionUse(context.Background(), inserted.Hash, extended)
+		require.Equal(t, store.ErrSessionNotFound, err)
+		err = postgresStore.SessionDelete(context.Background(), user.ID, inserted.ID)
+		require.Equal(t, store.ErrSessionNotFound, err)
+	})
+
+	t.Run("Session expired", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		inserted, err := postgresStore.SessionInsert(context.Background(), newTestSession(t, user.ID, time.Now().Add(-time.Minute)))
+		require.Nil(t, err)
+
+		_, err = postgresStore.SessionUse(context.Background(), inserted.Hash, time.Now().Add(time.Hour))
+		require.Equal(t, store.ErrSessionNotFound, err)
+
+		sessions, err := postgresStore.SessionList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, sessions)
+	})
+
+	t.Run("Session other user", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		other, err := postgresStore.UserInsert(context.Background(), "other@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		inserted, err := postgresStore.SessionInsert(context.Background(), newTestSession(t, user.ID, time.Now().Add(time.Hour)))
+		require.Nil(t, err)
+
+		err = postgresStore.SessionDelete(context.Background(), other.ID, inserted.ID)
+		require.Equal(t, store.ErrSessionNotFound, err)
+	})
+
+	t.Run("Sessions removed on password change", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		inserted, err := postgresStore.SessionInsert(context.Background(), newTestSession(t, user.ID, time.Now().Add(time.Hour)))
+		require.Nil(t, err)
+
+		err = postgresStore.UserUpdatePassword(context.Background(), user.ID, "new-password")
+		require.Nil(t, err)
+
+		_, err = postgresStore.SessionUse(context.Background(), inserted.Hash, time.Now().Add(time.Hour))
+		require.Equal(t, store.ErrSessionNotFound, err)
+	})
+}
@@ -10,15 +10,27 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mrityunjaygr8/autostrada-test/assets"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
 	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
 	"github.com/mrityunjaygr8/autostrada-test/store"
 	"math"
+	"time"
 )
 
 type PostgresStore struct {
-	db *pgxpool.Pool
+	db    conn
+	audit store.AuditContext
+}
+
+// conn is the pool, or the transaction for stores passed to a WithinTx
+// callback. Beginning a transaction on a transaction creates a savepoint, so
+// methods that need several statements stay atomic either way.
+type conn interface {
+	models.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
 var ErrCreatingPostgresPool = errors.New("error creating postgres pool")
@@ -27,29 +39,40 @@ var ErrInvalidMigrationDirection = errors.New("invalid migration direction passe
 
 type transactionFunction func() error
 
-func (p *PostgresStore) createTx() (ctx context.Context, tx pgx.Tx, commit transactionFunction, rollback transactionFunction, err error) {
-	ctx = context.Background()
-	tx, err = p.db.BeginTx(ctx, pgx.TxOptions{})
+func nullableTime(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// createTx begins a transaction bound to ctx, so cancelling the context
+// aborts whichever statement is running and rolls the transaction back.
+func (p *PostgresStore) createTx(ctx context.Context) (tx pgx.Tx, commit transactionFunction, rollback transactionFunction, err error) {
+	tx, err = p.db.Begin(ctx)
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("%w: %q", store.ErrStoreError, err)
+		return nil, nil, nil, fmt.Errorf("%w: %w", store.ErrStoreError, err)
 	}
 
 	commit = func() error {
 		err := tx.Commit(ctx)
 		if err != nil {
-			return fmt.Errorf("%w: %q", store.ErrStoreError, err)
+			return fmt.Errorf("%w: %w", store.ErrStoreError, err)
 		}
 		return nil
 	}
 	rollback = func() error {
 		err := tx.Rollback(ctx)
-		if err != nil {
-			return fmt.Errorf("%w: %q", store.ErrStoreError, err)
+		// pgx closes the connection when a statement's context is cancelled,
+		// which already discards the transaction, so let the caller report
+		// the cancellation instead.
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w: %w", store.ErrStoreError, err)
 		}
 		return nil
 	}
 
-	return ctx, tx, commit, rollback, nil
+	return tx, commit, rollback, nil
 
 }
 
@@ -114,8 +137,13 @@ func migrateDb(dbString string, direction MigrationDirection) error {
 	return nil
 }
 
-func (p *PostgresStore) UserInsert(email, password string, id uuid.UUID, admin bool) (*store.User, error) {
-	ctx, tx, commit, rollback, err := p.createTx()
+func (p *PostgresStore) UserInsert(ctx context.Context, email, password string, id uuid.UUID, admin bool) (*store.User, error) {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, commit, rollback, err := p.createTx(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -125,7 +153,6 @@ func (p *PostgresStore) UserInsert(email, password string, id uuid.UUID, admin b
 		Email:          email,
 		HashedPassword: password,
 		ID:             id,
-		Admin:          admin,
 	}
 	dbUser, err := query.UserInsert(ctx, params)
 	if err != nil {
@@ -141,48 +168,89 @@ func (p *PostgresStore) UserInsert(email, password string, id uuid.UUID, admin b
 		return nil, err
 	}
 
+	if admin {
+		_, err = query.UserRoleGrant(ctx, models.UserRoleGrantParams{UserID: id, Role: store.RoleAdmin})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return nil, txErr
+			}
+			return nil, err
+		}
+	}
+
+	err = p.recordAudit(ctx, query, store.AuditUserCreate, id, map[string]store.AuditChange{
+		"email": {New: email},
+		"admin": {New: admin},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
 	if txErr := commit(); txErr != nil {
 		return nil, txErr
 	}
 	user := &store.User{
-		Email:   dbUser.Email,
-		ID:      dbUser.ID,
-		Admin:   dbUser.Admin,
-		Created: dbUser.Created.Time,
+		Email:          dbUser.Email,
+		ID:             dbUser.ID,
+		Admin:          admin,
+		Created:        dbUser.Created.Time,
+		HashedPassword: dbUser.HashedPassword,
+		TokenVersion:   int(dbUser.TokenVersion),
+		EmailVerified:  nullableTime(dbUser.EmailVerifiedAt),
+		Status:         dbUser.Status,
+		StatusReason:   dbUser.StatusReason,
+		StatusChanged:  nullableTime(dbUser.StatusChangedAt),
 	}
 	return user, nil
 }
 
-func (p *PostgresStore) UserList(userListParmas store.UserListParams) (*store.UsersList, error) {
+func (p *PostgresStore) UserList(ctx context.Context, userListParmas store.UserListParams) (*store.UsersList, error) {
 	query := models.New(p.db)
 	params := models.UsersListParams{
-		Limit:  int32(userListParmas.PageSize),
-		Offset: int32((userListParmas.PageNumber - 1) * userListParmas.PageSize),
+		OrganizationID: userListParmas.OrganizationID,
+		Limit:          int32(userListParmas.PageSize),
+		Offset:         int32((userListParmas.PageNumber - 1) * userListParmas.PageSize),
 	}
-	dbUsers, err := query.UsersList(context.Background(), params)
+	if userListParmas.Status != "" {
+		params.Status = pgtype.Text{String: userListParmas.Status, Valid: true}
+	}
+	dbUsers, err := query.UsersList(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
 	users := make([]store.User, 0)
 	totalObjects := 0
-	totalPages := 0
 
 	for _, user := range dbUsers {
 		users = append(users, store.User{
-			Email:   user.Email,
-			ID:      user.ID,
-			Admin:   user.Admin,
-			Created: user.Created.Time,
+			Email:         user.Email,
+			ID:            user.ID,
+			Admin:         user.Admin,
+			Created:       user.Created.Time,
+			EmailVerified: nullableTime(user.EmailVerifiedAt),
+			Status:        user.Status,
+			StatusReason:  user.StatusReason,
+			StatusChanged: nullableTime(user.StatusChangedAt),
 		})
 	}
 
 	if len(dbUsers) > 0 {
 		totalObjects = int(dbUsers[0].RowData)
-		totalPages = int(math.Ceil(float64(totalObjects) / float64(userListParmas.PageSize)))
+	} else if params.Offset > 0 {
+		// The count comes with the rows, so a page past the end has to
+		// count the users separately.
+		count, err := query.UsersCount(ctx, models.UsersCountParams{OrganizationID: params.OrganizationID, Status: params.Status})
+		if err != nil {
+			return nil, err
+		}
+		totalObjects = int(count)
 	}
+	totalPages := int(math.Ceil(float64(totalObjects) / float64(userListParmas.PageSize)))
 
-	fmt.Println(users, dbUsers)
 	return &store.UsersList{
 		Data:         users,
 		TotalObjects: totalObjects,
@@ -192,9 +260,9 @@ func (p *PostgresStore) UserList(userListParmas store.UserListParams) (*store.Us
 	}, nil
 }
 
-func (p *PostgresStore) UserRetrieve(id uuid.UUID) (*store.User, error) {
+func (p *PostgresStore) UserRetrieve(ctx context.Context, id uuid.UUID) (*store.User, error) {
 	query := models.New(p.db)
-	user, err := query.UserRetrieve(context.Background(), id)
+	user, err := query.UserRetrieve(ctx, id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrUserNotFound
@@ -203,16 +271,27 @@ func (p *PostgresStore) UserRetrieve(id uuid.UUID) (*store.User, error) {
 	}
 
 	return &store.User{
-		Email:   user.Email,
-		ID:      user.ID,
-		Admin:   user.Admin,
-		Created: user.Created.Time,
+		Email:          user.Email,
+		ID:             user.ID,
+		Admin:          user.Admin,
+		Created:        user.Created.Time,
+		HashedPassword: user.HashedPassword,
+		TokenVersion:   int(user.TokenVersion),
+		EmailVerified:  nullableTime(user.EmailVerifiedAt),
+		Status:         user.Status,
+		StatusReason:   user.StatusReason,
+		StatusChanged:  nullableTime(user.StatusChangedAt),
 	}, nil
 }
 
-func (p *PostgresStore) UserRetrieveByEmail(email string) (*store.User, error) {
+func (p *PostgresStore) UserRetrieveByEmail(ctx context.Context, email string) (*store.User, error) {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return nil, store.ErrUserNotFound
+	}
+
 	query := models.New(p.db)
-	user, err := query.UserRetrieveByEmail(context.Background(), email)
+	user, err := query.UserRetrieveByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, store.ErrUserNotFound
@@ -221,14 +300,84 @@ func (p *PostgresStore) UserRetrieveByEmail(email string) (*store.User, error) {
 	}
 
 	return &store.User{
-		Email:   user.Email,
-		ID:      user.ID,
-		Admin:   user.Admin,
-		Created: user.Created.Time,
+		Email:          user.Email,
+		ID:             user.ID,
+		Admin:          user.Admin,
+		Created:        user.Created.Time,
+		HashedPassword: user.HashedPassword,
+		TokenVersion:   int(user.TokenVersion),
+		EmailVerified:  nullableTime(user.EmailVerifiedAt),
+		Status:         user.Status,
+		StatusReason:   user.StatusReason,
+		StatusChanged:  nullableTime(user.StatusChangedAt),
 	}, nil
 }
-func (p *PostgresStore) UserUpdatePassword(id uuid.UUID, newPassword string) error {
-	ctx, tx, commit, rollback, err := p.createTx()
+func (p *PostgresStore) UserUpdateEmail(ctx context.Context, id uuid.UUID, newEmail string) error {
+	newEmail, err := emailaddr.Normalize(newEmail)
+	if err != nil {
+		return err
+	}
+
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+	query := models.New(tx)
+	current, err := query.UserRetrieve(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
+	params := models.UserUpdateEmailParams{
+		ID:    id,
+		Email: newEmail,
+	}
+	res, err := query.UserUpdateEmail(ctx, params)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			if pge.SQLState() == "23505" {
+				return store.ErrUserExists
+			}
+		}
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrUserNotFound
+	}
+
+	if current.Email != newEmail {
+		err = p.recordAudit(ctx, query, store.AuditUserUpdateEmail, id, map[string]store.AuditChange{
+			"email": {Old: current.Email, New: newEmail},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (p *PostgresStore) UserUpdatePassword(ctx context.Context, id uuid.UUID, newPassword string) error {
+	tx, commit, rollback, err := p.createTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -251,49 +400,172 @@ func (p *PostgresStore) UserUpdatePassword(id uuid.UUID, newPassword string) err
 		return store.ErrUserNotFound
 	}
 
+	err = query.RefreshTokenRevokeAllForUser(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = query.SessionDeleteAllForUser(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = p.recordAudit(ctx, query, store.AuditUserUpdatePassword, id, map[string]store.AuditChange{
+		"password": {New: store.AuditRedacted},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
 	if txErr := commit(); txErr != nil {
 		return txErr
 	}
 	return nil
 }
 
-func (p *PostgresStore) UserUpdateAdmin(id uuid.UUID, newAdminValue bool) error {
-	ctx, tx, commit, rollback, err := p.createTx()
+func (p *PostgresStore) UserVerifyEmail(ctx context.Context, id uuid.UUID) error {
+	tx, commit, rollback, err := p.createTx(ctx)
 	if err != nil {
 		return err
 	}
+
 	query := models.New(tx)
-	params := models.UserUpdateAdminParams{
-		ID:    id,
-		Admin: newAdminValue,
+	current, err := query.UserRetrieve(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrUserNotFound
+		}
+		return err
 	}
-	res, err := query.UserUpdateAdmin(ctx, params)
+
+	_, err = query.UserVerifyEmail(ctx, id)
 	if err != nil {
 		if txErr := rollback(); txErr != nil {
 			return txErr
 		}
 		return err
 	}
-	if res.RowsAffected() == 0 {
+
+	if !current.EmailVerifiedAt.Valid {
+		err = p.recordAudit(ctx, query, store.AuditUserVerifyEmail, id, map[string]store.AuditChange{
+			"email_verified": {Old: false, New: true},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+// UserUpdateAdmin is kept for callers that predate roles; it grants or
+// revokes the admin role.
+func (p *PostgresStore) UserUpdateAdmin(ctx context.Context, id uuid.UUID, newAdminValue bool) error {
+	if newAdminValue {
+		return p.UserRoleGrant(ctx, id, store.RoleAdmin)
+	}
+	return p.UserRoleRevoke(ctx, id, store.RoleAdmin)
+}
+
+func (p *PostgresStore) UserUpdateStatus(ctx context.Context, id uuid.UUID, status, reason string) error {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := models.New(tx)
+	current, err := query.UserRetrieve(ctx, id)
+	if err != nil {
 		if txErr := rollback(); txErr != nil {
 			return txErr
 		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
 
-		return store.ErrUserNotFound
+	_, err = query.UserUpdateStatus(ctx, models.UserUpdateStatusParams{ID: id, Status: status, StatusReason: reason})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
 	}
+
+	if status != store.UserStatusActive {
+		err = query.RefreshTokenRevokeAllForUser(ctx, id)
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+
+		err = query.SessionDeleteAllForUser(ctx, id)
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if current.Status != status || current.StatusReason != reason {
+		err = p.recordAudit(ctx, query, store.AuditUserUpdateStatus, id, map[string]store.AuditChange{
+			"status": {Old: current.Status, New: status},
+			"reason": {Old: current.StatusReason, New: reason},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
 	if txErr := commit(); txErr != nil {
 		return txErr
 	}
 	return nil
 }
 
-func (p *PostgresStore) UserDelete(id uuid.UUID) error {
-	ctx, tx, commit, rollback, err := p.createTx()
+func (p *PostgresStore) UserDelete(ctx context.Context, id uuid.UUID) error {
+	tx, commit, rollback, err := p.createTx(ctx)
 	if err != nil {
 		return err
 	}
 
 	query := models.New(tx)
+	current, err := query.UserRetrieve(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
 	res, err := query.UserDelete(ctx, id)
 	if err != nil {
 		if txErr := rollback(); txErr != nil {
@@ -307,8 +579,103 @@ func (p *PostgresStore) UserDelete(id uuid.UUID) error {
 		}
 		return store.ErrUserNotFound
 	}
+
+	err = query.RefreshTokenRevokeAllForUser(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = query.SessionDeleteAllForUser(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = p.recordAudit(ctx, query, store.AuditUserDelete, id, map[string]store.AuditChange{
+		"email": {Old: current.Email},
+		"admin": {Old: current.Admin},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
 	if txErr := commit(); txErr != nil {
 		return txErr
 	}
 	return nil
 }
+
+func (p *PostgresStore) UserRestore(ctx context.Context, id uuid.UUID) error {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := models.New(tx)
+	email, err := query.UserRestore(ctx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
+	err = p.recordAudit(ctx, query, store.AuditUserRestore, id, map[string]store.AuditChange{
+		"email": {New: email},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (p *PostgresStore) UserPurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error) {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	query := models.New(tx)
+	purged, err := query.UserPurgeDeleted(ctx, pgtype.Timestamptz{Time: deletedBefore, Valid: true})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return 0, txErr
+		}
+		return 0, err
+	}
+
+	for _, user := range purged {
+		err = p.recordAudit(ctx, query, store.AuditUserPurge, user.ID, map[string]store.AuditChange{
+			"email": {Old: user.Email},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return 0, txErr
+			}
+			return 0, err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return 0, txErr
+	}
+	return len(purged), nil
+}
@@ -1,13 +1,16 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/mrityunjaygr8/autostrada-test/store/storetest"
 	"github.com/stretchr/testify/require"
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -26,6 +29,14 @@ func setupTest(t testing.TB) (*PostgresStore, func(tb testing.TB)) {
 	}
 }
 
+func TestPostgresStoreConformance(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.GuzeiStore {
+		postgresStore, teardownTest := setupTest(t)
+		t.Cleanup(func() { teardownTest(t) })
+		return postgresStore
+	})
+}
+
 func TestPostgresStoreUserInsert(t *testing.T) {
 	t.Run("test UserInsert method happy path", func(t *testing.T) {
 		postgresStore, teardownTest := setupTest(t)
@@ -36,7 +47,7 @@ func TestPostgresStoreUserInsert(t *testing.T) {
 		id := uuid.New()
 		admin := true
 
-		user, err := postgresStore.UserInsert(email, password, id, admin)
+		user, err := postgresStore.UserInsert(context.Background(), email, password, id, admin)
 
 		fmt.Println(err)
 		require.Nil(t, err)
@@ -56,8 +67,8 @@ func TestPostgresStoreUserInsert(t *testing.T) {
 		id := uuid.New()
 		admin := true
 
-		_, _ = postgresStore.UserInsert(email, password, id, admin)
-		_, err := postgresStore.UserInsert(email, password, id, admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email, password, id, admin)
+		_, err := postgresStore.UserInsert(context.Background(), email, password, id, admin)
 
 		require.Error(t, err)
 		require.Equal(t, store.ErrUserExists, err)
@@ -74,21 +85,21 @@ func TestPostgresStoreUserList(t *testing.T) {
 		admin := true
 		id := uuid.New()
 
-		_, _ = postgresStore.UserInsert("a"+email, password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email, password, id, admin)
-		_, _ = postgresStore.UserInsert(email+"a", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"b", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"c", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"d", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"e", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"f", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"g", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"h", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"i", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"j", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"k", password, uuid.New(), admin)
-		_, _ = postgresStore.UserInsert(email+"l", password, uuid.New(), admin)
-		users, err := postgresStore.UserList(store.UserListParams{
+		_, _ = postgresStore.UserInsert(context.Background(), "a"+email, password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email, password, id, admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"a", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"b", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"c", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"d", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"e", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"f", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"g", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"h", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"i", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"j", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"k", password, uuid.New(), admin)
+		_, _ = postgresStore.UserInsert(context.Background(), email+"l", password, uuid.New(), admin)
+		users, err := postgresStore.UserList(context.Background(), store.UserListParams{
 			PageNumber: 1,
 			PageSize:   10,
 		})
@@ -108,7 +119,7 @@ func TestPostgresStoreUserList(t *testing.T) {
 		postgresStore, teardownTest := setupTest(t)
 		defer teardownTest(t)
 
-		users, err := postgresStore.UserList(store.UserListParams{
+		users, err := postgresStore.UserList(context.Background(), store.UserListParams{
 			PageNumber: 1,
 			PageSize:   10,
 		})
@@ -130,11 +141,11 @@ func TestPostgresStoreUserRetrieve(t *testing.T) {
 		admin := true
 		id := uuid.New()
 
-		user, err := postgresStore.UserInsert(email, password, id, admin)
+		user, err := postgresStore.UserInsert(context.Background(), email, password, id, admin)
 		require.Nil(t, err)
 		require.NotNil(t, user)
 
-		retrieved, err := postgresStore.UserRetrieve(user.ID)
+		retrieved, err := postgresStore.UserRetrieve(context.Background(), user.ID)
 		t.Log(retrieved)
 		require.Nil(t, err)
 		require.NotNil(t, retrieved)
@@ -148,7 +159,7 @@ func TestPostgresStoreUserRetrieve(t *testing.T) {
 
 		id := uuid.New()
 
-		retrieved, err := postgresStore.UserRetrieve(id)
+		retrieved, err := postgresStore.UserRetrieve(context.Background(), id)
 		require.Nil(t, retrieved)
 		require.NotNil(t, err)
 		t.Log(err)
@@ -165,11 +176,11 @@ func TestPostgresStoreUserRetrieveByEmail(t *testing.T) {
 		admin := true
 		id := uuid.New()
 
-		user, err := postgresStore.UserInsert(email, password, id, admin)
+		user, err := postgresStore.UserInsert(context.Background(), email, password, id, admin)
 		require.Nil(t, err)
 		require.NotNil(t, user)
 
-		retrieved, err := postgresStore.UserRetrieveByEmail(user.Email)
+		retrieved, err := postgresStore.UserRetrieveByEmail(context.Background(), user.Email)
 		t.Log(retrieved)
 		require.Nil(t, err)
 		require.NotNil(t, retrieved)
@@ -183,11 +194,27 @@ func TestPostgresStoreUserRetrieveByEmail(t *testing.T) {
 
 		email := "im@parham.im"
 
-		retrieved, err := postgresStore.UserRetrieveByEmail(email)
+		retrieved, err := postgresStore.UserRetrieveByEmail(context.Background(), email)
 		require.Nil(t, retrieved)
 		require.NotNil(t, err)
 		t.Log(err)
 	})
+
+	t.Run("UserRetrieveByEmail ignores case", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), " Im@Parham.IM ", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Equal(t, "Im@parham.im", user.Email)
+
+		retrieved, err := postgresStore.UserRetrieveByEmail(context.Background(), "iM@pArHaM.iM")
+		require.Nil(t, err)
+		require.Equal(t, user.ID, retrieved.ID)
+
+		_, err = postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Equal(t, store.ErrUserExists, err)
+	})
 }
 func TestNewPostgresStoreUserUpdatePassword(t *testing.T) {
 	t.Run("UserUpdatePassword happy path", func(t *testing.T) {
@@ -200,13 +227,36 @@ func TestNewPostgresStoreUserUpdatePassword(t *testing.T) {
 		admin := true
 		id := uuid.New()
 
-		user, err := postgresStore.UserInsert(email, password, id, admin)
+		user, err := postgresStore.UserInsert(context.Background(), email, password, id, admin)
 		require.Nil(t, err)
 		require.NotNil(t, user)
 
-		err = postgresStore.UserUpdatePassword(user.ID, newPassword)
+		err = postgresStore.UserUpdatePassword(context.Background(), user.ID, newPassword)
 		require.Nil(t, err)
 	})
+	t.Run("UserUpdatePassword invalidates tokens", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im123", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Equal(t, 1, user.TokenVersion)
+
+		refreshToken, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, uuid.New()))
+		require.Nil(t, err)
+
+		err = postgresStore.UserUpdatePassword(context.Background(), user.ID, "newPassword")
+		require.Nil(t, err)
+
+		u, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, "newPassword", u.HashedPassword)
+		require.Equal(t, 2, u.TokenVersion)
+
+		revoked, err := postgresStore.RefreshTokenRetrieve(context.Background(), refreshToken.Hash)
+		require.Nil(t, err)
+		require.NotNil(t, revoked.Revoked)
+	})
 	t.Run("UserUpdatePassword user not exists", func(t *testing.T) {
 		postgresStore, teardownTest := setupTest(t)
 		defer teardownTest(t)
@@ -214,7 +264,7 @@ func TestNewPostgresStoreUserUpdatePassword(t *testing.T) {
 		id := uuid.New()
 		newPassword := "newPassword"
 
-		err := postgresStore.UserUpdatePassword(id, newPassword)
+		err := postgresStore.UserUpdatePassword(context.Background(), id, newPassword)
 		t.Log(err)
 		require.NotNil(t, err)
 		require.Equal(t, store.ErrUserNotFound, err)
@@ -231,14 +281,14 @@ func TestNewPostgresStoreUserUpdateAdmin(t *testing.T) {
 		id := uuid.New()
 		newAdminValue := false
 
-		user, err := postgresStore.UserInsert(email, password, id, admin)
+		user, err := postgresStore.UserInsert(context.Background(), email, password, id, admin)
 		require.Nil(t, err)
 		require.NotNil(t, user)
 
-		err = postgresStore.UserUpdateAdmin(user.ID, newAdminValue)
+		err = postgresStore.UserUpdateAdmin(context.Background(), user.ID, newAdminValue)
 		require.Nil(t, err)
 
-		u, err := postgresStore.UserRetrieve(user.ID)
+		u, err := postgresStore.UserRetrieve(context.Background(), user.ID)
 		require.Nil(t, err)
 		require.NotNil(t, u)
 
@@ -252,7 +302,7 @@ func TestNewPostgresStoreUserUpdateAdmin(t *testing.T) {
 		id := uuid.New()
 		newAdminValue := false
 
-		err := postgresStore.UserUpdateAdmin(id, newAdminValue)
+		err := postgresStore.UserUpdateAdmin(context.Background(), id, newAdminValue)
 		t.Log(err)
 		require.NotNil(t, err)
 		require.Equal(t, store.ErrUserNotFound, err)
@@ -268,12 +318,73 @@ func TestNewPostgresStoreUserDelete(t *testing.T) {
 		admin := true
 		id := uuid.New()
 
-		user, err := postgresStore.UserInsert(email, password, id, admin)
+		user, err := postgresStore.UserInsert(context.Background(), email, password, id, admin)
 		require.Nil(t, err)
 		require.NotNil(t, user)
 
-		err = postgresStore.UserDelete(id)
+		err = postgresStore.UserDelete(context.Background(), id)
+		require.Nil(t, err)
+	})
+	t.Run("UserDelete hides the user until restored", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		refreshToken, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, uuid.New()))
+		require.Nil(t, err)
+
+		err = postgresStore.UserDelete(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = postgresStore.UserRetrieveByEmail(context.Background(), "im@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+		users, err := postgresStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 0, users.TotalObjects)
+		err = postgresStore.UserDelete(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Equal(t, store.ErrUserExists, err)
+
+		revoked, err := postgresStore.RefreshTokenRetrieve(context.Background(), refreshToken.Hash)
+		require.Nil(t, err)
+		require.NotNil(t, revoked.Revoked)
+
+		err = postgresStore.UserRestore(context.Background(), user.ID)
+		require.Nil(t, err)
+		restored, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, 2, restored.TokenVersion)
+		err = postgresStore.UserRestore(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+	t.Run("UserPurgeDeleted", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Nil(t, postgresStore.UserDelete(context.Background(), user.ID))
+
+		purged, err := postgresStore.UserPurgeDeleted(context.Background(), time.Now().Add(-time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, 0, purged)
+
+		purged, err = postgresStore.UserPurgeDeleted(context.Background(), time.Now().Add(time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, 1, purged)
+
+		err = postgresStore.UserRestore(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		events, err := postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserPurge, TargetID: &user.ID, PageNumber: 1, PageSize: 10})
 		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
 	})
 	t.Run("UserUpdateAdmin user not exists", func(t *testing.T) {
 		postgresStore, teardownTest := setupTest(t)
@@ -281,8 +392,224 @@ func TestNewPostgresStoreUserDelete(t *testing.T) {
 
 		id := uuid.New()
 
-		err := postgresStore.UserDelete(id)
+		err := postgresStore.UserDelete(context.Background(), id)
 		require.NotNil(t, err)
 		require.Equal(t, store.ErrUserNotFound, err)
 	})
 }
+
+func TestNewPostgresStoreUserUpdateEmail(t *testing.T) {
+	t.Run("UserUpdateEmail happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		email := "im@parham.im123"
+		newEmail := "new@parham.im123"
+		password := "password"
+		id := uuid.New()
+
+		user, err := postgresStore.UserInsert(context.Background(), email, password, id, false)
+		require.Nil(t, err)
+		require.NotNil(t, user)
+
+		err = postgresStore.UserUpdateEmail(context.Background(), user.ID, newEmail)
+		require.Nil(t, err)
+
+		u, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, newEmail, u.Email)
+	})
+	t.Run("UserUpdateEmail email in use", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		password := "password"
+
+		_, err := postgresStore.UserInsert(context.Background(), "taken@parham.im", password, uuid.New(), false)
+		require.Nil(t, err)
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", password, uuid.New(), false)
+		require.Nil(t, err)
+
+		err = postgresStore.UserUpdateEmail(context.Background(), user.ID, "taken@parham.im")
+		require.Equal(t, store.ErrUserExists, err)
+	})
+	t.Run("UserUpdateEmail user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		err := postgresStore.UserUpdateEmail(context.Background(), uuid.New(), "im@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
+
+func TestNewPostgresStoreUserVerifyEmail(t *testing.T) {
+	t.Run("UserVerifyEmail happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Nil(t, user.EmailVerified)
+
+		err = postgresStore.UserVerifyEmail(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		verified, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.NotNil(t, verified.EmailVerified)
+
+		err = postgresStore.UserVerifyEmail(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		again, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, verified.EmailVerified, again.EmailVerified)
+	})
+
+	t.Run("UserUpdateEmail clears verification", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Nil(t, postgresStore.UserVerifyEmail(context.Background(), user.ID))
+
+		err = postgresStore.UserUpdateEmail(context.Background(), user.ID, "im@parham.im")
+		require.Nil(t, err)
+		unchanged, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.NotNil(t, unchanged.EmailVerified)
+
+		err = postgresStore.UserUpdateEmail(context.Background(), user.ID, "new@parham.im")
+		require.Nil(t, err)
+		changed, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Nil(t, changed.EmailVerified)
+	})
+
+	t.Run("UserVerifyEmail user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		err := postgresStore.UserVerifyEmail(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
+
+func TestNewPostgresStoreUserUpdateStatus(t *testing.T) {
+	t.Run("UserUpdateStatus suspends and reactivates", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Equal(t, store.UserStatusActive, user.Status)
+		require.Nil(t, user.StatusChanged)
+
+		refreshToken, err := postgresStore.RefreshTokenInsert(context.Background(), newTestRefreshToken(t, user.ID, uuid.New()))
+		require.Nil(t, err)
+
+		err = postgresStore.UserUpdateStatus(context.Background(), user.ID, store.UserStatusSuspended, "Chargeback")
+		require.Nil(t, err)
+
+		u, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.UserStatusSuspended, u.Status)
+		require.Equal(t, "Chargeback", u.StatusReason)
+		require.NotNil(t, u.StatusChanged)
+		require.Equal(t, 2, u.TokenVersion)
+
+		revoked, err := postgresStore.RefreshTokenRetrieve(context.Background(), refreshToken.Hash)
+		require.Nil(t, err)
+		require.NotNil(t, revoked.Revoked)
+
+		err = postgresStore.UserUpdateStatus(context.Background(), user.ID, store.UserStatusActive, "")
+		require.Nil(t, err)
+
+		u, err = postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.UserStatusActive, u.Status)
+		require.Equal(t, 2, u.TokenVersion)
+
+		events, err := postgresStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserUpdateStatus, TargetID: &user.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 2)
+	})
+
+	t.Run("UserList filters by status", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		_, err = postgresStore.UserInsert(context.Background(), "other@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Nil(t, postgresStore.UserUpdateStatus(context.Background(), user.ID, store.UserStatusDeactivated, ""))
+
+		users, err := postgresStore.UserList(context.Background(), store.UserListParams{Status: store.UserStatusDeactivated, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 1, users.TotalObjects)
+		require.Equal(t, user.ID, users.Data[0].ID)
+
+		users, err = postgresStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 2, users.TotalObjects)
+	})
+
+	t.Run("UserUpdateStatus user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		err := postgresStore.UserUpdateStatus(context.Background(), uuid.New(), store.UserStatusSuspended, "")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
+
+func TestPostgresStoreContext(t *testing.T) {
+	t.Run("cancelled context is not queried", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := postgresStore.UserRetrieve(ctx, uuid.New())
+		require.ErrorIs(t, err, context.Canceled)
+
+		_, err = postgresStore.UserInsert(ctx, "im@parham.im", "password", uuid.New(), false)
+		require.ErrorIs(t, err, context.Canceled)
+		_, err = postgresStore.UserRetrieveByEmail(context.Background(), "im@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("deadline aborts a slow query", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		// Hold a lock on the user's row, so the update below waits until its
+		// context gives up.
+		lock, err := postgresStore.db.Begin(context.Background())
+		require.Nil(t, err)
+		defer lock.Rollback(context.Background())
+		_, err = lock.Exec(context.Background(), "SELECT 1 FROM users WHERE id = $1 FOR UPDATE", user.ID)
+		require.Nil(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err = postgresStore.UserUpdatePassword(ctx, user.ID, "new-password")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 5*time.Second)
+
+		require.Nil(t, lock.Rollback(context.Background()))
+
+		retrieved, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, "password", retrieved.HashedPassword)
+		require.Equal(t, user.TokenVersion, retrieved.TokenVersion)
+	})
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (p *PostgresStore) TokenInsert(ctx context.Context, token store.Token) error {
+	query := models.New(p.db)
+	params := models.TokenInsertParams{
+		Hash:   token.Hash,
+		UserID: token.UserID,
+		Scope:  token.Scope,
+		Expiry: pgtype.Timestamptz{Time: token.Expiry, Valid: true},
+	}
+	err := query.TokenInsert(ctx, params)
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			if pge.SQLState() == "23503" {
+				return store.ErrUserNotFound
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *PostgresStore) TokenConsume(ctx context.Context, scope string, hash []byte) (uuid.UUID, error) {
+	query := models.New(p.db)
+	userID, err := query.TokenConsume(ctx, models.TokenConsumeParams{Hash: hash, Scope: scope})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, store.ErrTokenNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+func (p *PostgresStore) TokenDeleteAllForUser(ctx context.Context, scope string, userID uuid.UUID) error {
+	query := models.New(p.db)
+	return query.TokenDeleteAllForUser(ctx, models.TokenDeleteAllForUserParams{UserID: userID, Scope: scope})
+}
+
+func (p *PostgresStore) TokenCountSince(ctx context.Context, scope string, userID uuid.UUID, since time.Time) (int, error) {
+	query := models.New(p.db)
+	params := models.TokenCountSinceParams{
+		UserID:  userID,
+		Scope:   scope,
+		Created: pgtype.Timestamptz{Time: since, Valid: true},
+	}
+	count, err := query.TokenCountSince(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
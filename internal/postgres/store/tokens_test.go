@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestToken(t *testing.T, userID uuid.UUID, scope string, ttl time.Duration) store.Token {
+	_, hash, err := token.Generate()
+	require.Nil(t, err)
+	return store.Token{
+		Hash:   hash,
+		UserID: userID,
+		Scope:  scope,
+		Expiry: time.Now().Add(ttl),
+	}
+}
+
+func TestPostgresStoreTokenConsume(t *testing.T) {
+	t.Run("TokenConsume happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		resetToken := newTestToken(t, user.ID, store.ScopePasswordReset, time.Hour)
+		err = postgresStore.TokenInsert(context.Background(), resetToken)
+		require.Nil(t, err)
+
+		userID, err := postgresStore.TokenConsume(context.Background(), store.ScopePasswordReset, resetToken.Hash)
+		require.Nil(t, err)
+		require.Equal(t, user.ID, userID)
+
+		_, err = postgresStore.TokenConsume(context.Background(), store.ScopePasswordReset, resetToken.Hash)
+		require.Equal(t, store.ErrTokenNotFound, err)
+	})
+
+	t.Run("TokenConsume expired", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		resetToken := newTestToken(t, user.ID, store.ScopePasswordReset, -time.Minute)
+		err = postgresStore.TokenInsert(context.Background(), resetToken)
+		require.Nil(t, err)
+
+		_, err = postgresStore.TokenConsume(context.Background(), store.ScopePasswordReset, resetToken.Hash)
+		require.Equal(t, store.ErrTokenNotFound, err)
+	})
+
+	t.Run("TokenConsume wrong scope", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		resetToken := newTestToken(t, user.ID, store.ScopePasswordReset, time.Hour)
+		err = postgresStore.TokenInsert(context.Background(), resetToken)
+		require.Nil(t, err)
+
+		_, err = postgresStore.TokenConsume(context.Background(), "other", resetToken.Hash)
+		require.Equal(t, store.ErrTokenNotFound, err)
+	})
+
+	t.Run("TokenInsert user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		err := postgresStore.TokenInsert(context.Background(), newTestToken(t, uuid.New(), store.ScopePasswordReset, time.Hour))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("TokenDeleteAllForUser", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		first := newTestToken(t, user.ID, store.ScopePasswordReset, time.Hour)
+		second := newTestToken(t, user.ID, store.ScopePasswordReset, time.Hour)
+		require.Nil(t, postgresStore.TokenInsert(context.Background(), first))
+		require.Nil(t, postgresStore.TokenInsert(context.Background(), second))
+
+		err = postgresStore.TokenDeleteAllForUser(context.Background(), store.ScopePasswordReset, user.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.TokenConsume(context.Background(), store.ScopePasswordReset, first.Hash)
+		require.Equal(t, store.ErrTokenNotFound, err)
+		_, err = postgresStore.TokenConsume(context.Background(), store.ScopePasswordReset, second.Hash)
+		require.Equal(t, store.ErrTokenNotFound, err)
+	})
+}
+
+func TestPostgresStoreTokenCountSince(t *testing.T) {
+	postgresStore, teardownTest := setupTest(t)
+	defer teardownTest(t)
+
+	user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+	require.Nil(t, err)
+
+	require.Nil(t, postgresStore.TokenInsert(context.Background(), newTestToken(t, user.ID, store.ScopeActivation, time.Hour)))
+	require.Nil(t, postgresStore.TokenInsert(context.Background(), newTestToken(t, user.ID, store.ScopePasswordReset, time.Hour)))
+
+	count, err := postgresStore.TokenCountSince(context.Background(), store.ScopeActivation, user.ID, time.Now().Add(-time.Minute))
+	require.Nil(t, err)
+	require.Equal(t, 1, count)
+
+	count, err = postgresStore.TokenCountSince(context.Background(), store.ScopeActivation, user.ID, time.Now().Add(time.Minute))
+	require.Nil(t, err)
+	require.Equal(t, 0, count)
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (p *PostgresStore) TOTPRetrieve(ctx context.Context, userID uuid.UUID) (*store.TOTP, error) {
+	query := models.New(p.db)
+	dbTOTP, err := query.TOTPRetrieve(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrTOTPNotFound
+		}
+		return nil, err
+	}
+
+	return &store.TOTP{
+		UserID:    dbTOTP.UserID,
+		Secret:    dbTOTP.Secret,
+		Created:   dbTOTP.Created.Time,
+		Confirmed: nullableTime(dbTOTP.Confirmed),
+	}, nil
+}
+
+func (p *PostgresStore) TOTPEnroll(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	query := models.New(p.db)
+	err := query.TOTPEnroll(ctx, models.TOTPEnrollParams{UserID: userID, Secret: secret})
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			if pge.SQLState() == "23503" {
+				return store.ErrUserNotFound
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (p *PostgresStore) TOTPConfirm(ctx context.Context, userID uuid.UUID, recoveryCodeHashes [][]byte) error {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := models.New(tx)
+	res, err := query.TOTPConfirm(ctx, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrTOTPNotFound
+	}
+
+	err = query.RecoveryCodeDeleteAllForUser(ctx, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		err = query.RecoveryCodeInsert(ctx, models.RecoveryCodeInsertParams{UserID: userID, CodeHash: hash})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (p *PostgresStore) TOTPDelete(ctx context.Context, userID uuid.UUID) error {
+	tx, commit, rollback, err := p.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := models.New(tx)
+	res, err := query.TOTPDelete(ctx, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrTOTPNotFound
+	}
+
+	err = query.RecoveryCodeDeleteAllForUser(ctx, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (p *PostgresStore) RecoveryCodeConsume(ctx context.Context, userID uuid.UUID, hash []byte) error {
+	query := models.New(p.db)
+	res, err := query.RecoveryCodeConsume(ctx, models.RecoveryCodeConsumeParams{UserID: userID, CodeHash: hash})
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() == 0 {
+		return store.ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
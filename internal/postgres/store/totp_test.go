@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/token"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreTOTP(t *testing.T) {
+	t.Run("TOTP enroll and confirm", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		_, err = postgresStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+
+		err = postgresStore.TOTPEnroll(context.Background(), user.ID, []byte("first"))
+		require.Nil(t, err)
+		err = postgresStore.TOTPEnroll(context.Background(), user.ID, []byte("second"))
+		require.Nil(t, err)
+
+		userTOTP, err := postgresStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []byte("second"), userTOTP.Secret)
+		require.Nil(t, userTOTP.Confirmed)
+
+		_, hash, err := token.GenerateRecoveryCode()
+		require.Nil(t, err)
+		err = postgresStore.TOTPConfirm(context.Background(), user.ID, [][]byte{hash})
+		require.Nil(t, err)
+
+		userTOTP, err = postgresStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.NotNil(t, userTOTP.Confirmed)
+
+		err = postgresStore.TOTPConfirm(context.Background(), user.ID, [][]byte{hash})
+		require.Equal(t, store.ErrTOTPNotFound, err)
+	})
+
+	t.Run("TOTPEnroll user not exists", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		err := postgresStore.TOTPEnroll(context.Background(), uuid.New(), []byte("secret"))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("RecoveryCodeConsume", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Nil(t, postgresStore.TOTPEnroll(context.Background(), user.ID, []byte("secret")))
+
+		code, hash, err := token.GenerateRecoveryCode()
+		require.Nil(t, err)
+		require.Nil(t, postgresStore.TOTPConfirm(context.Background(), user.ID, [][]byte{hash}))
+
+		err = postgresStore.RecoveryCodeConsume(context.Background(), user.ID, token.HashRecoveryCode(code))
+		require.Nil(t, err)
+		err = postgresStore.RecoveryCodeConsume(context.Background(), user.ID, token.HashRecoveryCode(code))
+		require.Equal(t, store.ErrRecoveryCodeNotFound, err)
+	})
+
+	t.Run("TOTPDelete", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		require.Nil(t, postgresStore.TOTPEnroll(context.Background(), user.ID, []byte("secret")))
+
+		code, hash, err := token.GenerateRecoveryCode()
+		require.Nil(t, err)
+		require.Nil(t, postgresStore.TOTPConfirm(context.Background(), user.ID, [][]byte{hash}))
+
+		err = postgresStore.TOTPDelete(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+		err = postgresStore.RecoveryCodeConsume(context.Background(), user.ID, token.HashRecoveryCode(code))
+		require.Equal(t, store.ErrRecoveryCodeNotFound, err)
+
+		err = postgresStore.TOTPDelete(context.Background(), user.ID)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+	})
+}
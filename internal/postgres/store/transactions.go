@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// isolationLevels maps the store isolation levels to PostgreSQL's. The
+// default level is left empty, so the server's default applies.
+var isolationLevels = map[store.IsolationLevel]pgx.TxIsoLevel{
+	store.IsolationDefault:        "",
+	store.IsolationReadCommitted:  pgx.ReadCommitted,
+	store.IsolationRepeatableRead: pgx.RepeatableRead,
+	store.IsolationSerializable:   pgx.Serializable,
+}
+
+type beginFunction func(ctx context.Context) (pgx.Tx, error)
+
+func (p *PostgresStore) WithinTx(ctx context.Context, opts store.TxOptions, fn func(tx store.Tx) error) error {
+	pool, ok := p.db.(*pgxpool.Pool)
+	if !ok {
+		// The store is already bound to a transaction, so fn runs in a
+		// savepoint and the outer transaction is the one that is retried.
+		return p.runTx(ctx, p.db.Begin, fn)
+	}
+
+	isoLevel, ok := isolationLevels[opts.Isolation]
+	if !ok {
+		return fmt.Errorf("%w: invalid isolation level %d", store.ErrStoreError, opts.Isolation)
+	}
+	begin := func(ctx context.Context) (pgx.Tx, error) {
+		return pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: isoLevel})
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = store.DefaultTxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := p.runTx(ctx, begin, fn)
+		if err == nil || attempt >= maxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(retryDelay(attempt)):
+		}
+	}
+}
+
+// runTx runs fn with a store bound to a transaction started by begin, and
+// commits the transaction if fn succeeds.
+func (p *PostgresStore) runTx(ctx context.Context, begin beginFunction, fn func(tx store.Tx) error) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", store.ErrStoreError, err)
+	}
+
+	err = fn(&PostgresStore{db: tx, audit: p.audit})
+	if err != nil {
+		// As in createTx, a cancelled context has already discarded the
+		// transaction.
+		if txErr := tx.Rollback(ctx); txErr != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w: %w", store.ErrStoreError, txErr)
+		}
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", store.ErrStoreError, err)
+	}
+	return nil
+}
+
+// isRetryable reports whether err is a serialization failure or a deadlock,
+// which PostgreSQL expects the whole transaction to be retried after.
+func isRetryable(err error) bool {
+	var pge *pgconn.PgError
+	if !errors.As(err, &pge) {
+		return false
+	}
+	return pge.SQLState() == "40001" || pge.SQLState() == "40P01"
+}
+
+// retryDelay waits a little longer after each attempt, with some jitter so
+// that transactions which conflicted don't collide again straight away.
+func retryDelay(attempt int) time.Duration {
+	return time.Duration(attempt)*10*time.Millisecond + time.Duration(rand.Int63n(int64(10*time.Millisecond)))
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, isRetryable(&pgconn.PgError{Code: "40001"}))
+	require.True(t, isRetryable(fmt.Errorf("%w: %w", store.ErrStoreError, &pgconn.PgError{Code: "40P01"})))
+	require.False(t, isRetryable(&pgconn.PgError{Code: "23505"}))
+	require.False(t, isRetryable(errors.New("40001")))
+}
+
+func TestPostgresStoreWithinTx(t *testing.T) {
+	t.Run("serialization failures are retried", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		attempts := 0
+		opts := store.TxOptions{Isolation: store.IsolationRepeatableRead}
+		err = postgresStore.WithinTx(context.Background(), opts, func(tx store.Tx) error {
+			attempts++
+			_, err := tx.UserRetrieve(context.Background(), user.ID)
+			if err != nil {
+				return err
+			}
+
+			// Change the user outside the transaction after its snapshot
+			// was taken, so updating them fails the first time.
+			if attempts == 1 {
+				err = postgresStore.UserUpdateStatus(context.Background(), user.ID, store.UserStatusSuspended, "first")
+				if err != nil {
+					return err
+				}
+			}
+			return tx.UserUpdateStatus(context.Background(), user.ID, store.UserStatusSuspended, "second")
+		})
+		require.Nil(t, err)
+		require.Equal(t, 2, attempts)
+
+		retrieved, err := postgresStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, "second", retrieved.StatusReason)
+	})
+
+	t.Run("retries give up after MaxAttempts", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		attempts := 0
+		err := postgresStore.WithinTx(context.Background(), store.TxOptions{MaxAttempts: 2}, func(tx store.Tx) error {
+			attempts++
+			return &pgconn.PgError{Code: "40001"}
+		})
+		require.True(t, isRetryable(err))
+		require.Equal(t, 2, attempts)
+
+		attempts = 0
+		err = postgresStore.WithinTx(context.Background(), store.TxOptions{}, func(tx store.Tx) error {
+			attempts++
+			return store.ErrUserNotFound
+		})
+		require.Equal(t, store.ErrUserNotFound, err)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("invalid isolation level", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		err := postgresStore.WithinTx(context.Background(), store.TxOptions{Isolation: store.IsolationLevel(42)}, func(tx store.Tx) error {
+			return nil
+		})
+		require.ErrorIs(t, err, store.ErrStoreError)
+	})
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mrityunjaygr8/autostrada-test/internal/postgres/models"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func identityFromModel(dbIdentity models.UserIdentity) *store.Identity {
+	return &store.Identity{
+		Issuer:  dbIdentity.Issuer,
+		Subject: dbIdentity.Subject,
+		UserID:  dbIdentity.UserID,
+		Email:   dbIdentity.Email,
+		Created: dbIdentity.Created.Time,
+	}
+}
+
+func (p *PostgresStore) IdentityRetrieve(ctx context.Context, issuer, subject string) (*store.Identity, error) {
+	query := models.New(p.db)
+	dbIdentity, err := query.IdentityRetrieve(ctx, models.IdentityRetrieveParams{Issuer: issuer, Subject: subject})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, store.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return identityFromModel(dbIdentity), nil
+}
+
+func (p *PostgresStore) IdentityLink(ctx context.Context, identity store.Identity) (*store.Identity, error) {
+	query := models.New(p.db)
+	params := models.IdentityInsertParams{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		UserID:  identity.UserID,
+		Email:   identity.Email,
+	}
+	dbIdentity, err := query.IdentityInsert(ctx, params)
+	if err != nil {
+		var pge *pgconn.PgError
+		if errors.As(err, &pge) {
+			switch pge.SQLState() {
+			case "23505":
+				return nil, store.ErrIdentityExists
+			case "23503":
+				return nil, store.ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	return identityFromModel(dbIdentity), nil
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStoreIdentities(t *testing.T) {
+	t.Run("Identity happy path", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		linked, err := postgresStore.IdentityLink(context.Background(), store.Identity{
+			Issuer:  "https://accounts.example.com",
+			Subject: "1234",
+			UserID:  user.ID,
+			Email:   user.Email,
+		})
+		require.Nil(t, err)
+		require.Equal(t, user.ID, linked.UserID)
+		require.False(t, linked.Created.IsZero())
+
+		retrieved, err := postgresStore.IdentityRetrieve(context.Background(), "https://accounts.example.com", "1234")
+		require.Nil(t, err)
+		require.Equal(t, user.ID, retrieved.UserID)
+		require.Equal(t, user.Email, retrieved.Email)
+
+		_, err = postgresStore.IdentityRetrieve(context.Background(), "https://other.example.com", "1234")
+		require.Equal(t, store.ErrIdentityNotFound, err)
+	})
+
+	t.Run("Identity already linked", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		other, err := postgresStore.UserInsert(context.Background(), "other@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+
+		identity := store.Identity{Issuer: "https://accounts.example.com", Subject: "1234", UserID: user.ID, Email: user.Email}
+		_, err = postgresStore.IdentityLink(context.Background(), identity)
+		require.Nil(t, err)
+
+		identity.UserID = other.ID
+		_, err = postgresStore.IdentityLink(context.Background(), identity)
+		require.Equal(t, store.ErrIdentityExists, err)
+	})
+
+	t.Run("Identity unknown user", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		_, err := postgresStore.IdentityLink(context.Background(), store.Identity{Issuer: "https://accounts.example.com", Subject: "1234", UserID: uuid.New()})
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("Identity removed with user", func(t *testing.T) {
+		postgresStore, teardownTest := setupTest(t)
+		defer teardownTest(t)
+
+		user, err := postgresStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+		require.Nil(t, err)
+		_, err = postgresStore.IdentityLink(context.Background(), store.Identity{Issuer: "https://accounts.example.com", Subject: "1234", UserID: user.ID, Email: user.Email})
+		require.Nil(t, err)
+
+		err = postgresStore.UserDelete(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		_, err = postgresStore.IdentityRetrieve(context.Background(), "https://accounts.example.com", "1234")
+		require.Equal(t, store.ErrIdentityNotFound, err)
+	})
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const apiKeyColumns = `id, user_id, name, prefix, hash, scopes, created, expires, last_used`
+
+func scanAPIKey(row scanner) (*store.APIKey, error) {
+	var key store.APIKey
+	var scopes string
+	var created int64
+	var expires, lastUsed sql.NullInt64
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Hash, &scopes, &created, &expires, &lastUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal([]byte(scopes), &key.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	key.Created = timeFromTimestamp(created)
+	key.Expires = nullableTime(expires)
+	key.LastUsed = nullableTime(lastUsed)
+	return &key, nil
+}
+
+func (s *SQLiteStore) APIKeyInsert(ctx context.Context, key store.APIKey) (*store.APIKey, error) {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.QueryRowContext(ctx, `INSERT INTO api_keys (id, user_id, name, prefix, hash, scopes, created, expires) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING `+apiKeyColumns,
+		key.ID, key.UserID, key.Name, key.Prefix, key.Hash, string(scopesJSON), timestamp(time.Now()), nullableTimestamp(key.Expires))
+	inserted, err := scanAPIKey(row)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+func (s *SQLiteStore) APIKeyList(ctx context.Context, userID uuid.UUID) ([]store.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = ? ORDER BY created, rowid`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]store.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SQLiteStore) APIKeyRetrieve(ctx context.Context, userID, id uuid.UUID) (*store.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ? AND user_id = ?`, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *SQLiteStore) APIKeyUpdateName(ctx context.Context, userID, id uuid.UUID, name string) (*store.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `UPDATE api_keys SET name = ? WHERE id = ? AND user_id = ? RETURNING `+apiKeyColumns, name, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (s *SQLiteStore) APIKeyDelete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return store.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) APIKeyUse(ctx context.Context, hash []byte) (*store.APIKey, error) {
+	now := timestamp(time.Now())
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, `UPDATE api_keys SET last_used = ?1 WHERE hash = ?2 AND (expires IS NULL OR expires > ?1) RETURNING `+apiKeyColumns, now, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (s *SQLiteStore) WithAudit(audit store.AuditContext) store.GuzeiStore {
+	return &SQLiteStore{db: s.db, audit: audit}
+}
+
+// recordAudit writes an audit event using the given querier, which should be
+// the transaction making the change.
+func (s *SQLiteStore) recordAudit(ctx context.Context, q querier, action string, targetID uuid.UUID, diff map[string]store.AuditChange) error {
+	diffJSON, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `INSERT INTO audit_events (id, actor_id, target_id, action, ip, request_id, diff, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		uuid.New(), s.audit.ActorID, targetID, action, s.audit.IP, s.audit.RequestID, string(diffJSON), timestamp(time.Now()))
+	return err
+}
+
+func (s *SQLiteStore) AuditRecord(ctx context.Context, action string, targetID uuid.UUID, diff map[string]store.AuditChange) error {
+	return s.recordAudit(ctx, s.db, action, targetID, diff)
+}
+
+// auditEventFilter matches the events listed by AuditEventList.
+const auditEventFilter = `(?1 IS NULL OR actor_id = ?1)
+	AND (?2 IS NULL OR target_id = ?2)
+	AND (?3 IS NULL OR action = ?3)
+	AND (?4 IS NULL OR created >= ?4)
+	AND (?5 IS NULL OR created < ?5)`
+
+func (s *SQLiteStore) AuditEventList(ctx context.Context, auditEventListParams store.AuditEventListParams) (*store.AuditEventsList, error) {
+	var action sql.NullString
+	if auditEventListParams.Action != "" {
+		action = sql.NullString{String: auditEventListParams.Action, Valid: true}
+	}
+	filter := []any{
+		auditEventListParams.ActorID,
+		auditEventListParams.TargetID,
+		action,
+		nullableTimestamp(auditEventListParams.Since),
+		nullableTimestamp(auditEventListParams.Until),
+	}
+	offset := (auditEventListParams.PageNumber - 1) * auditEventListParams.PageSize
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, actor_id, target_id, action, ip, request_id, diff, created, COUNT(*) OVER () AS row_data
+		FROM audit_events WHERE `+auditEventFilter+`
+		ORDER BY created DESC, id
+		LIMIT ?6 OFFSET ?7`, append(filter, auditEventListParams.PageSize, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]store.AuditEvent, 0)
+	totalObjects := 0
+
+	for rows.Next() {
+		var event store.AuditEvent
+		var actorID, targetID uuid.NullUUID
+		var diffJSON string
+		var created int64
+		err = rows.Scan(&event.ID, &actorID, &targetID, &event.Action, &event.IP, &event.RequestID, &diffJSON, &created, &totalObjects)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(diffJSON), &event.Diff)
+		if err != nil {
+			return nil, err
+		}
+		event.ActorID = nullableUUID(actorID)
+		event.TargetID = nullableUUID(targetID)
+		event.Created = timeFromTimestamp(created)
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 && offset > 0 {
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_events WHERE `+auditEventFilter, filter...).Scan(&totalObjects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	totalPages := int(math.Ceil(float64(totalObjects) / float64(auditEventListParams.PageSize)))
+
+	return &store.AuditEventsList{
+		Data:         events,
+		TotalObjects: totalObjects,
+		TotalPages:   totalPages,
+		Page:         auditEventListParams.PageNumber,
+		PageSize:     auditEventListParams.PageSize,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const invitationColumns = `id, organization_id, email, role, hash, invited_by, created, expires`
+
+func scanInvitation(row scanner) (*store.Invitation, error) {
+	var invitation store.Invitation
+	var invitedBy uuid.NullUUID
+	var created, expires int64
+	err := row.Scan(&invitation.ID, &invitation.OrganizationID, &invitation.Email, &invitation.Role, &invitation.Hash, &invitedBy, &created, &expires)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.InvitedBy = nullableUUID(invitedBy)
+	invitation.Created = timeFromTimestamp(created)
+	invitation.Expires = timeFromTimestamp(expires)
+	return &invitation, nil
+}
+
+func (s *SQLiteStore) InvitationInsert(ctx context.Context, invitation store.Invitation) (*store.Invitation, error) {
+	email, err := emailaddr.Normalize(invitation.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.QueryRowContext(ctx, `INSERT INTO invitations (id, organization_id, email, email_key, role, hash, invited_by, created, expires) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING `+invitationColumns,
+		invitation.ID, invitation.OrganizationID, email, emailaddr.Key(email), invitation.Role, invitation.Hash, invitation.InvitedBy, timestamp(time.Now()), timestamp(invitation.Expires))
+	inserted, err := scanInvitation(row)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return nil, store.ErrInvitationExists
+		case isForeignKeyViolation(err):
+			organizationExists, err := exists(ctx, s.db, `SELECT EXISTS (SELECT 1 FROM organizations WHERE id = ?)`, invitation.OrganizationID)
+			if err != nil {
+				return nil, err
+			}
+			if !organizationExists {
+				return nil, store.ErrOrganizationNotFound
+			}
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+func (s *SQLiteStore) InvitationRetrieve(ctx context.Context, organizationID, id uuid.UUID) (*store.Invitation, error) {
+	invitation, err := scanInvitation(s.db.QueryRowContext(ctx, `SELECT `+invitationColumns+` FROM invitations WHERE organization_id = ? AND id = ?`, organizationID, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (s *SQLiteStore) InvitationRetrieveByHash(ctx context.Context, hash []byte) (*store.Invitation, error) {
+	invitation, err := scanInvitation(s.db.QueryRowContext(ctx, `SELECT `+invitationColumns+` FROM invitations WHERE hash = ? AND expires > ?`, hash, timestamp(time.Now())))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (s *SQLiteStore) InvitationList(ctx context.Context, organizationID uuid.UUID) ([]store.Invitation, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+invitationColumns+` FROM invitations WHERE organization_id = ? ORDER BY created DESC, id`, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := make([]store.Invitation, 0)
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *SQLiteStore) InvitationRenew(ctx context.Context, organizationID, id uuid.UUID, hash []byte, expires time.Time) (*store.Invitation, error) {
+	row := s.db.QueryRowContext(ctx, `UPDATE invitations SET hash = ?, expires = ? WHERE organization_id = ? AND id = ? RETURNING `+invitationColumns,
+		hash, timestamp(expires), organizationID, id)
+	invitation, err := scanInvitation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+func (s *SQLiteStore) InvitationDelete(ctx context.Context, organizationID, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM invitations WHERE organization_id = ? AND id = ?`, organizationID, id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return store.ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) InvitationAccept(ctx context.Context, hash []byte, userID uuid.UUID, hashedPassword string) (*store.Membership, error) {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := s.acceptInvitation(ctx, tx, hash, userID, hashedPassword)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return membership, nil
+}
+
+func (s *SQLiteStore) acceptInvitation(ctx context.Context, q querier, hash []byte, userID uuid.UUID, hashedPassword string) (*store.Membership, error) {
+	row := q.QueryRowContext(ctx, `DELETE FROM invitations WHERE hash = ? AND expires > ? RETURNING `+invitationColumns, hash, timestamp(time.Now()))
+	invitation, err := scanInvitation(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	user, err := retrieveUserByEmail(ctx, q, invitation.Email)
+	switch {
+	case err == nil:
+		userID = user.ID
+	case errors.Is(err, store.ErrUserNotFound):
+		_, err = insertUser(ctx, q, invitation.Email, hashedPassword, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = verifyEmail(ctx, q, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.recordAudit(ctx, q, store.AuditUserCreate, userID, map[string]store.AuditChange{
+			"email": {New: invitation.Email},
+			"admin": {New: false},
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	current, err := retrieveMembership(ctx, q, invitation.OrganizationID, userID)
+	switch {
+	case err == nil:
+		return current, nil
+	case !errors.Is(err, store.ErrMembershipNotFound):
+		return nil, err
+	}
+
+	return s.upsertMembership(ctx, q, invitation.OrganizationID, userID, invitation.Role)
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const loginThrottleColumns = `kind, subject, failures, last_failure, locked_until`
+
+func scanLoginThrottle(row scanner) (*store.LoginThrottle, error) {
+	var throttle store.LoginThrottle
+	var lastFailure int64
+	var lockedUntil sql.NullInt64
+	err := row.Scan(&throttle.Kind, &throttle.Subject, &throttle.Failures, &lastFailure, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	throttle.LastFailure = timeFromTimestamp(lastFailure)
+	throttle.LockedUntil = nullableTime(lockedUntil)
+	return &throttle, nil
+}
+
+func (s *SQLiteStore) LoginThrottleRetrieve(ctx context.Context, kind, subject string) (*store.LoginThrottle, error) {
+	throttle, err := scanLoginThrottle(s.db.QueryRowContext(ctx, `SELECT `+loginThrottleColumns+` FROM login_throttles WHERE kind = ? AND subject = ?`, kind, subject))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrLoginThrottleNotFound
+		}
+		return nil, err
+	}
+
+	return throttle, nil
+}
+
+func (s *SQLiteStore) LoginFailureRecord(ctx context.Context, kind, subject string, at, windowStart time.Time) (*store.LoginThrottle, error) {
+	row := s.db.QueryRowContext(ctx, `INSERT INTO login_throttles (kind, subject, failures, last_failure) VALUES (?1, ?2, 1, ?3)
+		ON CONFLICT (kind, subject) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure < ?4 THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure = excluded.last_failure
+		RETURNING `+loginThrottleColumns, kind, subject, timestamp(at), timestamp(windowStart))
+	return scanLoginThrottle(row)
+}
+
+func (s *SQLiteStore) LoginThrottleLock(ctx context.Context, kind, subject string, until time.Time) error {
+	// Unlike GREATEST in PostgreSQL, max() returns NULL if any argument is.
+	_, err := s.db.ExecContext(ctx, `UPDATE login_throttles SET locked_until = max(COALESCE(locked_until, ?1), ?1) WHERE kind = ?2 AND subject = ?3`,
+		timestamp(until), kind, subject)
+	return err
+}
+
+func (s *SQLiteStore) LoginThrottleReset(ctx context.Context, kind, subject string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM login_throttles WHERE kind = ? AND subject = ?`, kind, subject)
+	return err
+}
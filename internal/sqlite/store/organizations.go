@@ -0,0 +1,251 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func scanOrganization(row scanner) (*store.Organization, error) {
+	var organization store.Organization
+	var created int64
+	err := row.Scan(&organization.ID, &organization.Name, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	organization.Created = timeFromTimestamp(created)
+	return &organization, nil
+}
+
+// membershipColumns are the columns read by scanMembership, from
+// organization_memberships joined with users.
+const membershipColumns = `organization_memberships.organization_id, organization_memberships.user_id, users.email, organization_memberships.role, organization_memberships.created`
+
+func scanMembership(row scanner) (*store.Membership, error) {
+	var membership store.Membership
+	var created int64
+	err := row.Scan(&membership.OrganizationID, &membership.UserID, &membership.Email, &membership.Role, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	membership.Created = timeFromTimestamp(created)
+	return &membership, nil
+}
+
+func retrieveMembership(ctx context.Context, q querier, organizationID, userID uuid.UUID) (*store.Membership, error) {
+	row := q.QueryRowContext(ctx, `SELECT `+membershipColumns+` FROM organization_memberships
+		JOIN users ON users.id = organization_memberships.user_id
+		WHERE organization_memberships.organization_id = ? AND organization_memberships.user_id = ? AND users.deleted_at IS NULL`, organizationID, userID)
+	membership, err := scanMembership(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrMembershipNotFound
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+func (s *SQLiteStore) OrganizationInsert(ctx context.Context, organization store.Organization, ownerID uuid.UUID) (*store.Organization, error) {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := tx.QueryRowContext(ctx, `INSERT INTO organizations (id, name, created) VALUES (?, ?, ?) RETURNING id, name, created`,
+		organization.ID, organization.Name, timestamp(time.Now()))
+	inserted, err := scanOrganization(row)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	_, err = s.upsertMembership(ctx, tx, organization.ID, ownerID, store.OrganizationRoleOwner)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return inserted, nil
+}
+
+func (s *SQLiteStore) OrganizationRetrieve(ctx context.Context, id uuid.UUID) (*store.Organization, error) {
+	organization, err := scanOrganization(s.db.QueryRowContext(ctx, `SELECT id, name, created FROM organizations WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+
+	return organization, nil
+}
+
+func (s *SQLiteStore) OrganizationListForUser(ctx context.Context, userID uuid.UUID) ([]store.Organization, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT organizations.id, organizations.name, organizations.created FROM organizations
+		JOIN organization_memberships ON organization_memberships.organization_id = organizations.id
+		WHERE organization_memberships.user_id = ?
+		ORDER BY organizations.name, organizations.id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizations := make([]store.Organization, 0)
+	for rows.Next() {
+		organization, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, *organization)
+	}
+	return organizations, rows.Err()
+}
+
+func (s *SQLiteStore) MembershipRetrieve(ctx context.Context, organizationID, userID uuid.UUID) (*store.Membership, error) {
+	return retrieveMembership(ctx, s.db, organizationID, userID)
+}
+
+func (s *SQLiteStore) MembershipList(ctx context.Context, organizationID uuid.UUID) ([]store.Membership, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+membershipColumns+` FROM organization_memberships
+		JOIN users ON users.id = organization_memberships.user_id
+		WHERE organization_memberships.organization_id = ? AND users.deleted_at IS NULL
+		ORDER BY users.email`, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := make([]store.Membership, 0)
+	for rows.Next() {
+		membership, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, *membership)
+	}
+	return memberships, rows.Err()
+}
+
+func (s *SQLiteStore) MembershipUpsert(ctx context.Context, organizationID, userID uuid.UUID, role string) (*store.Membership, error) {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := s.upsertMembership(ctx, tx, organizationID, userID, role)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return membership, nil
+}
+
+// upsertMembership adds or updates a membership using the given querier,
+// which should be a transaction, and records the change in the audit log.
+func (s *SQLiteStore) upsertMembership(ctx context.Context, q querier, organizationID, userID uuid.UUID, role string) (*store.Membership, error) {
+	current, err := retrieveMembership(ctx, q, organizationID, userID)
+	found := err == nil
+	if err != nil && !errors.Is(err, store.ErrMembershipNotFound) {
+		return nil, err
+	}
+
+	_, err = q.ExecContext(ctx, `INSERT INTO organization_memberships (organization_id, user_id, role, created) VALUES (?, ?, ?, ?)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET role = excluded.role`,
+		organizationID, userID, role, timestamp(time.Now()))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			organizationExists, err := exists(ctx, q, `SELECT EXISTS (SELECT 1 FROM organizations WHERE id = ?)`, organizationID)
+			if err != nil {
+				return nil, err
+			}
+			if !organizationExists {
+				return nil, store.ErrOrganizationNotFound
+			}
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if !found || current.Role != role {
+		roleChange := store.AuditChange{New: role}
+		if found {
+			roleChange.Old = current.Role
+		}
+		err = s.recordAudit(ctx, q, store.AuditMembershipUpdate, userID, map[string]store.AuditChange{
+			"organization": {New: organizationID},
+			"role":         roleChange,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	membership, err := retrieveMembership(ctx, q, organizationID, userID)
+	if err != nil {
+		// Deleted users keep their row, so the foreign key doesn't catch them.
+		if errors.Is(err, store.ErrMembershipNotFound) {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return membership, nil
+}
+
+func (s *SQLiteStore) MembershipDelete(ctx context.Context, organizationID, userID uuid.UUID) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := retrieveMembership(ctx, tx, organizationID, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM organization_memberships WHERE organization_id = ? AND user_id = ?`, organizationID, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = s.recordAudit(ctx, tx, store.AuditMembershipDelete, userID, map[string]store.AuditChange{
+		"organization": {Old: organizationID},
+		"role":         {Old: current.Role},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const refreshTokenColumns = `id, family_id, user_id, token_hash, created, expires, used, revoked`
+
+func scanRefreshToken(row scanner) (*store.RefreshToken, error) {
+	var token store.RefreshToken
+	var created, expires int64
+	var used, revoked sql.NullInt64
+	err := row.Scan(&token.ID, &token.FamilyID, &token.UserID, &token.Hash, &created, &expires, &used, &revoked)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Created = timeFromTimestamp(created)
+	token.Expires = timeFromTimestamp(expires)
+	token.Used = nullableTime(used)
+	token.Revoked = nullableTime(revoked)
+	return &token, nil
+}
+
+func insertRefreshToken(ctx context.Context, q querier, token store.RefreshToken) (*store.RefreshToken, error) {
+	row := q.QueryRowContext(ctx, `INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, created, expires) VALUES (?, ?, ?, ?, ?, ?) RETURNING `+refreshTokenColumns,
+		token.ID, token.FamilyID, token.UserID, token.Hash, timestamp(time.Now()), timestamp(token.Expires))
+	return scanRefreshToken(row)
+}
+
+func (s *SQLiteStore) RefreshTokenInsert(ctx context.Context, token store.RefreshToken) (*store.RefreshToken, error) {
+	inserted, err := insertRefreshToken(ctx, s.db, token)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+func (s *SQLiteStore) RefreshTokenRetrieve(ctx context.Context, hash []byte) (*store.RefreshToken, error) {
+	token, err := scanRefreshToken(s.db.QueryRowContext(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = ?`, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (s *SQLiteStore) RefreshTokenRotate(ctx context.Context, id uuid.UUID, next store.RefreshToken) (*store.RefreshToken, error) {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used = ? WHERE id = ? AND used IS NULL AND revoked IS NULL`, timestamp(time.Now()), id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, store.ErrRefreshTokenReused
+	}
+
+	token, err := insertRefreshToken(ctx, tx, next)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return token, nil
+}
+
+func (s *SQLiteStore) RefreshTokenRevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE refresh_tokens SET revoked = ? WHERE family_id = ? AND revoked IS NULL`, timestamp(time.Now()), familyID)
+	return err
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (s *SQLiteStore) RoleList(ctx context.Context) ([]store.Role, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT roles.name, roles.description, role_permissions.permission FROM roles
+		LEFT JOIN role_permissions ON role_permissions.role = roles.name
+		ORDER BY roles.name, role_permissions.permission`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]store.Role, 0)
+	for rows.Next() {
+		var name, description string
+		var permission sql.NullString
+		err = rows.Scan(&name, &description, &permission)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, store.Role{Name: name, Description: description, Permissions: []string{}})
+		}
+		if permission.Valid {
+			role := &roles[len(roles)-1]
+			role.Permissions = append(role.Permissions, permission.String)
+		}
+	}
+	return roles, rows.Err()
+}
+
+// queryStrings runs a query that selects a single text column.
+func queryStrings(ctx context.Context, q querier, query string, args ...any) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		err = rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func (s *SQLiteStore) UserRoles(ctx context.Context, id uuid.UUID) ([]string, error) {
+	found, err := userExists(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, store.ErrUserNotFound
+	}
+
+	return queryStrings(ctx, s.db, `SELECT role FROM user_roles WHERE user_id = ? ORDER BY role`, id)
+}
+
+func (s *SQLiteStore) UserPermissions(ctx context.Context, id uuid.UUID) ([]string, error) {
+	return queryStrings(ctx, s.db, `SELECT DISTINCT role_permissions.permission FROM user_roles
+		JOIN role_permissions ON role_permissions.role = user_roles.role
+		WHERE user_roles.user_id = ? ORDER BY role_permissions.permission`, id)
+}
+
+func (s *SQLiteStore) UserRoleGrant(ctx context.Context, id uuid.UUID, role string) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO user_roles (user_id, role) VALUES (?, ?) ON CONFLICT DO NOTHING`, id, role)
+	if err != nil {
+		if !isForeignKeyViolation(err) {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+
+		roleExists, err := exists(ctx, tx, `SELECT EXISTS (SELECT 1 FROM roles WHERE name = ?)`, role)
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if err != nil {
+			return err
+		}
+		if !roleExists {
+			return store.ErrRoleNotFound
+		}
+		return store.ErrUserNotFound
+	}
+
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		err = s.recordAudit(ctx, tx, store.AuditUserRoleGrant, id, map[string]store.AuditChange{
+			"role": {New: role},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UserRoleRevoke(ctx context.Context, id uuid.UUID, role string) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	found, err := userExists(ctx, tx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+	if !found {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrUserNotFound
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = ? AND role = ?`, id, role)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if affected, _ := res.RowsAffected(); affected > 0 {
+		err = s.recordAudit(ctx, tx, store.AuditUserRoleRevoke, id, map[string]store.AuditChange{
+			"role": {Old: role},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
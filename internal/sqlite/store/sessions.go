@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const sessionColumns = `id, user_id, hash, device, ip, user_agent, created, last_seen, expires`
+
+func scanSession(row scanner) (*store.Session, error) {
+	var session store.Session
+	var created, lastSeen, expires int64
+	err := row.Scan(&session.ID, &session.UserID, &session.Hash, &session.Device, &session.IP, &session.UserAgent, &created, &lastSeen, &expires)
+	if err != nil {
+		return nil, err
+	}
+
+	session.Created = timeFromTimestamp(created)
+	session.LastSeen = timeFromTimestamp(lastSeen)
+	session.Expires = timeFromTimestamp(expires)
+	return &session, nil
+}
+
+func (s *SQLiteStore) SessionInsert(ctx context.Context, session store.Session) (*store.Session, error) {
+	now := timestamp(time.Now())
+	row := s.db.QueryRowContext(ctx, `INSERT INTO sessions (id, user_id, hash, device, ip, user_agent, created, last_seen, expires) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING `+sessionColumns,
+		session.ID, session.UserID, session.Hash, session.Device, session.IP, session.UserAgent, now, now, timestamp(session.Expires))
+	inserted, err := scanSession(row)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return inserted, nil
+}
+
+func (s *SQLiteStore) SessionUse(ctx context.Context, hash []byte, expires time.Time) (*store.Session, error) {
+	row := s.db.QueryRowContext(ctx, `UPDATE sessions SET last_seen = ?1, expires = ?2 WHERE hash = ?3 AND expires > ?1 RETURNING `+sessionColumns,
+		timestamp(time.Now()), timestamp(expires), hash)
+	session, err := scanSession(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (s *SQLiteStore) SessionList(ctx context.Context, userID uuid.UUID) ([]store.Session, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE user_id = ? AND expires > ? ORDER BY last_seen DESC`, userID, timestamp(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]store.Session, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (s *SQLiteStore) SessionDelete(ctx context.Context, userID, id uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return store.ErrSessionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,749 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/assets"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLiteStore keeps everything in a single SQLite database file, for small
+// deployments that don't want to run PostgreSQL. It uses its own migrations
+// from assets/sqlite_migrations, which mirror the PostgreSQL schema, and
+// returns the same errors as PostgresStore.
+type SQLiteStore struct {
+	// db is the *sql.DB, or the *sql.Tx for stores passed to a WithinTx
+	// callback.
+	db    querier
+	audit store.AuditContext
+}
+
+var ErrOpeningSQLite = errors.New("error opening sqlite database")
+
+type transactionFunction func() error
+
+// querier runs statements either directly on the database or in a
+// transaction, like the DBTX interface of the PostgreSQL models.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// timestamp converts a time to the microseconds since the Unix epoch that
+// timestamps are stored as, which is the precision of PostgreSQL timestamps.
+func timestamp(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func nullableTimestamp(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: timestamp(*t), Valid: true}
+}
+
+func timeFromTimestamp(ts int64) time.Time {
+	return time.UnixMicro(ts)
+}
+
+func nullableTime(ts sql.NullInt64) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := timeFromTimestamp(ts.Int64)
+	return &t
+}
+
+func nullableUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
+
+// isUniqueViolation reports whether err was caused by a primary key or unique
+// constraint, like the 23505 SQLSTATE in PostgreSQL.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// isForeignKeyViolation reports whether err was caused by a foreign key, like
+// the 23503 SQLSTATE in PostgreSQL. SQLite doesn't say which foreign key
+// failed, so callers that need to know have to look for the missing row.
+func isForeignKeyViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+}
+
+// exists runs a query that selects a single boolean.
+func exists(ctx context.Context, q querier, query string, args ...any) (bool, error) {
+	var found bool
+	err := q.QueryRowContext(ctx, query, args...).Scan(&found)
+	return found, err
+}
+
+// createTx begins a transaction bound to ctx, so cancelling the context
+// aborts whichever statement is running and rolls the transaction back. If
+// the store is already bound to a transaction, it creates a savepoint in it
+// instead, which commit releases and rollback rolls back to.
+func (s *SQLiteStore) createTx(ctx context.Context) (tx querier, commit transactionFunction, rollback transactionFunction, err error) {
+	if outer, ok := s.db.(*sql.Tx); ok {
+		return createSavepoint(ctx, outer)
+	}
+
+	sqlTx, err := s.db.(*sql.DB).BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %w", store.ErrStoreError, err)
+	}
+
+	commit = func() error {
+		err := sqlTx.Commit()
+		if err != nil {
+			return fmt.Errorf("%w: %w", store.ErrStoreError, err)
+		}
+		return nil
+	}
+	rollback = func() error {
+		err := sqlTx.Rollback()
+		// database/sql rolls the transaction back itself when the context
+		// is cancelled, so let the caller report the cancellation instead.
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w: %w", store.ErrStoreError, err)
+		}
+		return nil
+	}
+
+	return sqlTx, commit, rollback, nil
+}
+
+// createSavepoint is createTx for a store bound to a transaction. Savepoints
+// with the same name nest, and each RELEASE or ROLLBACK TO applies to the
+// innermost one.
+func createSavepoint(ctx context.Context, tx *sql.Tx) (querier, transactionFunction, transactionFunction, error) {
+	_, err := tx.ExecContext(ctx, `SAVEPOINT tx`)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %w", store.ErrStoreError, err)
+	}
+
+	commit := func() error {
+		_, err := tx.ExecContext(ctx, `RELEASE tx`)
+		if err != nil {
+			return fmt.Errorf("%w: %w", store.ErrStoreError, err)
+		}
+		return nil
+	}
+	rollback := func() error {
+		_, err := tx.ExecContext(ctx, `ROLLBACK TO tx`)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `RELEASE tx`)
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%w: %w", store.ErrStoreError, err)
+		}
+		return nil
+	}
+
+	return tx, commit, rollback, nil
+}
+
+// NewSQLiteStore opens the database file at path, creating it if it doesn't
+// exist. Every connection enforces foreign keys and waits for locks held by
+// other connections instead of failing, and transactions take the write lock
+// as soon as they begin so that they can't deadlock upgrading to it.
+func NewSQLiteStore(path string, autoMigrate bool) (*SQLiteStore, func(), error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate")
+	if err != nil {
+		return nil, nil, ErrOpeningSQLite
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, nil, ErrOpeningSQLite
+	}
+
+	if autoMigrate {
+		err := migrateDb(path)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+	}
+
+	closer := func() {
+		db.Close()
+	}
+
+	return &SQLiteStore{db: db}, closer, nil
+}
+
+// migrateDb applies the embedded SQLite migrations to the database at path.
+func migrateDb(path string) error {
+	iofsDriver, err := iofs.New(assets.EmbeddedFiles, "sqlite_migrations")
+	if err != nil {
+		return err
+	}
+
+	migrator, err := migrate.NewWithSourceInstance("iofs", iofsDriver, "sqlite://"+path)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	err = migrator.Up()
+	switch {
+	case errors.Is(err, migrate.ErrNoChange):
+		break
+	case err != nil:
+		return err
+	}
+	return nil
+}
+
+// userColumns are the columns read by scanUser.
+const userColumns = `email, created, id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, hashed_password, token_version, email_verified_at, status, status_reason, status_changed_at`
+
+func scanUser(row scanner) (*store.User, error) {
+	var user store.User
+	var created int64
+	var emailVerified, statusChanged sql.NullInt64
+	err := row.Scan(&user.Email, &created, &user.ID, &user.Admin, &user.HashedPassword, &user.TokenVersion, &emailVerified, &user.Status, &user.StatusReason, &statusChanged)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Created = timeFromTimestamp(created)
+	user.EmailVerified = nullableTime(emailVerified)
+	user.StatusChanged = nullableTime(statusChanged)
+	return &user, nil
+}
+
+func retrieveUser(ctx context.Context, q querier, id uuid.UUID) (*store.User, error) {
+	user, err := scanUser(q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ? AND deleted_at IS NULL`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func retrieveUserByEmail(ctx context.Context, q querier, email string) (*store.User, error) {
+	user, err := scanUser(q.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email_key = ? AND deleted_at IS NULL`, emailaddr.Key(email)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func userExists(ctx context.Context, q querier, id uuid.UUID) (bool, error) {
+	return exists(ctx, q, `SELECT EXISTS (SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL)`, id)
+}
+
+// insertUser adds a user with the defaults from the users table. Emails are
+// unique regardless of case, including those of deleted users.
+func insertUser(ctx context.Context, q querier, email, password string, id uuid.UUID) (*store.User, error) {
+	_, err := q.ExecContext(ctx, `INSERT INTO users (id, created, email, email_key, hashed_password) VALUES (?, ?, ?, ?, ?)`,
+		id, timestamp(time.Now()), email, emailaddr.Key(email), password)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, store.ErrUserExists
+		}
+		return nil, err
+	}
+	return retrieveUser(ctx, q, id)
+}
+
+func verifyEmail(ctx context.Context, q querier, id uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `UPDATE users SET email_verified_at = COALESCE(email_verified_at, ?) WHERE id = ? AND deleted_at IS NULL`, timestamp(time.Now()), id)
+	return err
+}
+
+// logOut revokes the user's refresh tokens and deletes their sessions.
+func logOut(ctx context.Context, q querier, id uuid.UUID) error {
+	_, err := q.ExecContext(ctx, `UPDATE refresh_tokens SET revoked = ? WHERE user_id = ? AND revoked IS NULL`, timestamp(time.Now()), id)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, id)
+	return err
+}
+
+func (s *SQLiteStore) UserInsert(ctx context.Context, email, password string, id uuid.UUID, admin bool) (*store.User, error) {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := insertUser(ctx, tx, email, password, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if admin {
+		_, err = tx.ExecContext(ctx, `INSERT INTO user_roles (user_id, role) VALUES (?, ?) ON CONFLICT DO NOTHING`, id, store.RoleAdmin)
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return nil, txErr
+			}
+			return nil, err
+		}
+		user.Admin = true
+	}
+
+	err = s.recordAudit(ctx, tx, store.AuditUserCreate, id, map[string]store.AuditChange{
+		"email": {New: email},
+		"admin": {New: admin},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return nil, txErr
+		}
+		return nil, err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return nil, txErr
+	}
+	return user, nil
+}
+
+// userFilter matches the users listed by UserList.
+const userFilter = `deleted_at IS NULL
+	AND (?1 IS NULL OR EXISTS (SELECT 1 FROM organization_memberships WHERE organization_memberships.user_id = users.id AND organization_memberships.organization_id = ?1))
+	AND (?2 IS NULL OR status = ?2)`
+
+func (s *SQLiteStore) UserList(ctx context.Context, userListParams store.UserListParams) (*store.UsersList, error) {
+	var organizationID uuid.NullUUID
+	if userListParams.OrganizationID != nil {
+		organizationID = uuid.NullUUID{UUID: *userListParams.OrganizationID, Valid: true}
+	}
+	var status sql.NullString
+	if userListParams.Status != "" {
+		status = sql.NullString{String: userListParams.Status, Valid: true}
+	}
+	offset := (userListParams.PageNumber - 1) * userListParams.PageSize
+
+	rows, err := s.db.QueryContext(ctx, `SELECT email, created, id, EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id AND user_roles.role = 'admin') AS admin, email_verified_at, status, status_reason, status_changed_at, COUNT(*) OVER () AS row_data
+		FROM users WHERE `+userFilter+`
+		ORDER BY email LIMIT ?3 OFFSET ?4`, organizationID, status, userListParams.PageSize, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]store.User, 0)
+	totalObjects := 0
+
+	for rows.Next() {
+		var user store.User
+		var created int64
+		var emailVerified, statusChanged sql.NullInt64
+		err = rows.Scan(&user.Email, &created, &user.ID, &user.Admin, &emailVerified, &user.Status, &user.StatusReason, &statusChanged, &totalObjects)
+		if err != nil {
+			return nil, err
+		}
+		user.Created = timeFromTimestamp(created)
+		user.EmailVerified = nullableTime(emailVerified)
+		user.StatusChanged = nullableTime(statusChanged)
+		users = append(users, user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 && offset > 0 {
+		// The count comes with the rows, so a page past the end has to
+		// count the users separately.
+		err = s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+userFilter, organizationID, status).Scan(&totalObjects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	totalPages := int(math.Ceil(float64(totalObjects) / float64(userListParams.PageSize)))
+
+	return &store.UsersList{
+		Data:         users,
+		TotalObjects: totalObjects,
+		TotalPages:   totalPages,
+		Page:         userListParams.PageNumber,
+		PageSize:     userListParams.PageSize,
+	}, nil
+}
+
+func (s *SQLiteStore) UserRetrieve(ctx context.Context, id uuid.UUID) (*store.User, error) {
+	return retrieveUser(ctx, s.db, id)
+}
+
+func (s *SQLiteStore) UserRetrieveByEmail(ctx context.Context, email string) (*store.User, error) {
+	email, err := emailaddr.Normalize(email)
+	if err != nil {
+		return nil, store.ErrUserNotFound
+	}
+
+	return retrieveUserByEmail(ctx, s.db, email)
+}
+
+func (s *SQLiteStore) UserUpdateEmail(ctx context.Context, id uuid.UUID, newEmail string) error {
+	newEmail, err := emailaddr.Normalize(newEmail)
+	if err != nil {
+		return err
+	}
+
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := retrieveUser(ctx, tx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	newKey := emailaddr.Key(newEmail)
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email = ?1, email_key = ?2, email_verified_at = CASE WHEN email_key = ?2 THEN email_verified_at END WHERE id = ?3 AND deleted_at IS NULL`, newEmail, newKey, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if isUniqueViolation(err) {
+			return store.ErrUserExists
+		}
+		return err
+	}
+
+	if current.Email != newEmail {
+		err = s.recordAudit(ctx, tx, store.AuditUserUpdateEmail, id, map[string]store.AuditChange{
+			"email": {Old: current.Email, New: newEmail},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UserUpdatePassword(ctx context.Context, id uuid.UUID, newPassword string) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET hashed_password = ?, token_version = token_version + 1 WHERE id = ? AND deleted_at IS NULL`, newPassword, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrUserNotFound
+	}
+
+	err = logOut(ctx, tx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = s.recordAudit(ctx, tx, store.AuditUserUpdatePassword, id, map[string]store.AuditChange{
+		"password": {New: store.AuditRedacted},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UserVerifyEmail(ctx context.Context, id uuid.UUID) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := retrieveUser(ctx, tx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if current.EmailVerified == nil {
+		err = verifyEmail(ctx, tx, id)
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+
+		err = s.recordAudit(ctx, tx, store.AuditUserVerifyEmail, id, map[string]store.AuditChange{
+			"email_verified": {Old: false, New: true},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+// UserUpdateAdmin is kept for callers that predate roles; it grants or
+// revokes the admin role.
+func (s *SQLiteStore) UserUpdateAdmin(ctx context.Context, id uuid.UUID, newAdminValue bool) error {
+	if newAdminValue {
+		return s.UserRoleGrant(ctx, id, store.RoleAdmin)
+	}
+	return s.UserRoleRevoke(ctx, id, store.RoleAdmin)
+}
+
+func (s *SQLiteStore) UserUpdateStatus(ctx context.Context, id uuid.UUID, status, reason string) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := retrieveUser(ctx, tx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET status = ?1, status_reason = ?2, status_changed_at = ?3, token_version = CASE WHEN ?1 = 'active' THEN token_version ELSE token_version + 1 END WHERE id = ?4 AND deleted_at IS NULL`,
+		status, reason, timestamp(time.Now()), id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if status != store.UserStatusActive {
+		err = logOut(ctx, tx, id)
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if current.Status != status || current.StatusReason != reason {
+		err = s.recordAudit(ctx, tx, store.AuditUserUpdateStatus, id, map[string]store.AuditChange{
+			"status": {Old: current.Status, New: status},
+			"reason": {Old: current.StatusReason, New: reason},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UserDelete(ctx context.Context, id uuid.UUID) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := retrieveUser(ctx, tx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE users SET deleted_at = ?, token_version = token_version + 1 WHERE id = ? AND deleted_at IS NULL`, timestamp(time.Now()), id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = logOut(ctx, tx, id)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	err = s.recordAudit(ctx, tx, store.AuditUserDelete, id, map[string]store.AuditChange{
+		"email": {Old: current.Email},
+		"admin": {Old: current.Admin},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UserRestore(ctx context.Context, id uuid.UUID) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	var email string
+	err = tx.QueryRowContext(ctx, `UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL RETURNING email`, id).Scan(&email)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
+	err = s.recordAudit(ctx, tx, store.AuditUserRestore, id, map[string]store.AuditChange{
+		"email": {New: email},
+	})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UserPurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error) {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged, err := purgeDeletedUsers(ctx, tx, deletedBefore)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return 0, txErr
+		}
+		return 0, err
+	}
+
+	for _, user := range purged {
+		err = s.recordAudit(ctx, tx, store.AuditUserPurge, user.ID, map[string]store.AuditChange{
+			"email": {Old: user.Email},
+		})
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return 0, txErr
+			}
+			return 0, err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return 0, txErr
+	}
+	return len(purged), nil
+}
+
+// purgeDeletedUsers deletes the users who were deleted before the given time
+// and returns their IDs and emails. The foreign keys to the users table
+// delete or detach everything that references them.
+func purgeDeletedUsers(ctx context.Context, q querier, deletedBefore time.Time) ([]store.User, error) {
+	rows, err := q.QueryContext(ctx, `DELETE FROM users WHERE deleted_at < ? RETURNING id, email`, timestamp(deletedBefore))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var purged []store.User
+	for rows.Next() {
+		var user store.User
+		err = rows.Scan(&user.ID, &user.Email)
+		if err != nil {
+			return nil, err
+		}
+		purged = append(purged, user)
+	}
+	return purged, rows.Err()
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/mrityunjaygr8/autostrada-test/store/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStoreConformance(t *testing.T) {
+	storetest.RunConformance(t, func(t *testing.T) store.GuzeiStore {
+		sqliteStore, closer, err := NewSQLiteStore(filepath.Join(t.TempDir(), "guzei.db"), true)
+		require.Nil(t, err)
+		t.Cleanup(closer)
+		return sqliteStore
+	})
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (s *SQLiteStore) TokenInsert(ctx context.Context, token store.Token) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO tokens (hash, user_id, scope, created, expiry) VALUES (?, ?, ?, ?, ?)`,
+		token.Hash, token.UserID, token.Scope, timestamp(time.Now()), timestamp(token.Expiry))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) TokenConsume(ctx context.Context, scope string, hash []byte) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := s.db.QueryRowContext(ctx, `DELETE FROM tokens WHERE hash = ? AND scope = ? AND expiry > ? RETURNING user_id`, hash, scope, timestamp(time.Now())).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, store.ErrTokenNotFound
+		}
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+func (s *SQLiteStore) TokenDeleteAllForUser(ctx context.Context, scope string, userID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM tokens WHERE user_id = ? AND scope = ?`, userID, scope)
+	return err
+}
+
+func (s *SQLiteStore) TokenCountSince(ctx context.Context, scope string, userID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tokens WHERE user_id = ? AND scope = ? AND created > ?`, userID, scope, timestamp(since)).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+func (s *SQLiteStore) TOTPRetrieve(ctx context.Context, userID uuid.UUID) (*store.TOTP, error) {
+	totp := store.TOTP{UserID: userID}
+	var created int64
+	var confirmed sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `SELECT secret, created, confirmed FROM user_totp WHERE user_id = ?`, userID).Scan(&totp.Secret, &created, &confirmed)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrTOTPNotFound
+		}
+		return nil, err
+	}
+
+	totp.Created = timeFromTimestamp(created)
+	totp.Confirmed = nullableTime(confirmed)
+	return &totp, nil
+}
+
+func (s *SQLiteStore) TOTPEnroll(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO user_totp (user_id, secret, created) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET secret = excluded.secret, created = excluded.created, confirmed = NULL`,
+		userID, secret, timestamp(time.Now()))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return store.ErrUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) TOTPConfirm(ctx context.Context, userID uuid.UUID, recoveryCodeHashes [][]byte) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE user_totp SET confirmed = ? WHERE user_id = ? AND confirmed IS NULL`, timestamp(time.Now()), userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrTOTPNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash)
+		if err != nil {
+			if txErr := rollback(); txErr != nil {
+				return txErr
+			}
+			return err
+		}
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) TOTPDelete(ctx context.Context, userID uuid.UUID) error {
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = ?`, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return store.ErrTOTPNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	if txErr := commit(); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RecoveryCodeConsume(ctx context.Context, userID uuid.UUID, hash []byte) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE recovery_codes SET used = ? WHERE user_id = ? AND code_hash = ? AND used IS NULL`, timestamp(time.Now()), userID, hash)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return store.ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+// WithinTx runs fn in a transaction. Transactions take the write lock as
+// soon as they begin, so they are always serializable and never deadlock,
+// and there is nothing to retry. The isolation level is only validated.
+func (s *SQLiteStore) WithinTx(ctx context.Context, opts store.TxOptions, fn func(tx store.Tx) error) error {
+	if opts.Isolation < store.IsolationDefault || opts.Isolation > store.IsolationSerializable {
+		return fmt.Errorf("%w: invalid isolation level %d", store.ErrStoreError, opts.Isolation)
+	}
+
+	tx, commit, rollback, err := s.createTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(&SQLiteStore{db: tx, audit: s.audit})
+	if err != nil {
+		if txErr := rollback(); txErr != nil {
+			return txErr
+		}
+		return err
+	}
+
+	return commit()
+}
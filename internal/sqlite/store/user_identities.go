@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+)
+
+const identityColumns = `issuer, subject, user_id, email, created`
+
+func scanIdentity(row scanner) (*store.Identity, error) {
+	var identity store.Identity
+	var created int64
+	err := row.Scan(&identity.Issuer, &identity.Subject, &identity.UserID, &identity.Email, &created)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.Created = timeFromTimestamp(created)
+	return &identity, nil
+}
+
+func (s *SQLiteStore) IdentityRetrieve(ctx context.Context, issuer, subject string) (*store.Identity, error) {
+	identity, err := scanIdentity(s.db.QueryRowContext(ctx, `SELECT `+identityColumns+` FROM user_identities WHERE issuer = ? AND subject = ?`, issuer, subject))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (s *SQLiteStore) IdentityLink(ctx context.Context, identity store.Identity) (*store.Identity, error) {
+	row := s.db.QueryRowContext(ctx, `INSERT INTO user_identities (issuer, subject, user_id, email, created) VALUES (?, ?, ?, ?, ?) RETURNING `+identityColumns,
+		identity.Issuer, identity.Subject, identity.UserID, identity.Email, timestamp(time.Now()))
+	linked, err := scanIdentity(row)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return nil, store.ErrIdentityExists
+		case isForeignKeyViolation(err):
+			return nil, store.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return linked, nil
+}
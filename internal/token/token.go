@@ -0,0 +1,51 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"strings"
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func Generate() (plaintext string, hash []byte, err error) {
+	randomBytes := make([]byte, 32)
+
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plaintext = encoding.EncodeToString(randomBytes)
+
+	return plaintext, Hash(plaintext), nil
+}
+
+func Hash(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+// GenerateRecoveryCode returns a short, human friendly one-time code in the
+// form "xxxxx-xxxxx" along with its hash.
+func GenerateRecoveryCode() (plaintext string, hash []byte, err error) {
+	randomBytes := make([]byte, 10)
+
+	_, err = rand.Read(randomBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	code := strings.ToLower(encoding.EncodeToString(randomBytes))[:10]
+	plaintext = code[:5] + "-" + code[5:]
+
+	return plaintext, HashRecoveryCode(plaintext), nil
+}
+
+// HashRecoveryCode hashes a recovery code ignoring case, spaces and dashes so
+// users can type it however it was written down.
+func HashRecoveryCode(plaintext string) []byte {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(plaintext))
+	return Hash(normalized)
+}
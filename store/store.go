@@ -1,34 +1,199 @@
 package store
 
 import (
+	"context"
 	"errors"
 	"github.com/google/uuid"
 	"time"
 )
 
 type GuzeiStore interface {
-	UserInsert(email, password string, id uuid.UUID, admin bool) (*User, error)
-	UserList(userListParams UserListParams) (*UsersList, error)
-	UserRetrieveByEmail(email string) (*User, error)
-	UserRetrieve(id uuid.UUID) (*User, error)
-	UserUpdatePassword(id uuid.UUID, newPassword string) error
-	UserUpdateAdmin(id uuid.UUID, newAdminValue bool) error
-	UserDelete(id uuid.UUID) error
+	UserInsert(ctx context.Context, email, password string, id uuid.UUID, admin bool) (*User, error)
+	UserList(ctx context.Context, userListParams UserListParams) (*UsersList, error)
+	UserRetrieveByEmail(ctx context.Context, email string) (*User, error)
+	UserRetrieve(ctx context.Context, id uuid.UUID) (*User, error)
+	// UserUpdateEmail clears the user's email verification when the email
+	// address changes.
+	UserUpdateEmail(ctx context.Context, id uuid.UUID, newEmail string) error
+	UserVerifyEmail(ctx context.Context, id uuid.UUID) error
+	// UserUpdatePassword also bumps the user's token version and revokes
+	// their refresh tokens and sessions, so existing logins stop working.
+	UserUpdatePassword(ctx context.Context, id uuid.UUID, newPassword string) error
+	UserUpdateAdmin(ctx context.Context, id uuid.UUID, newAdminValue bool) error
+	// UserUpdateStatus changes the user's status. Moving a user out of the
+	// active status also bumps their token version and revokes their refresh
+	// tokens and sessions, so they are logged out everywhere at once.
+	UserUpdateStatus(ctx context.Context, id uuid.UUID, status, reason string) error
+	// UserDelete soft deletes the user, logging them out everywhere. Deleted
+	// users are hidden from the other methods until they are restored or
+	// purged.
+	UserDelete(ctx context.Context, id uuid.UUID) error
+	// UserRestore undoes UserDelete for a user who hasn't been purged yet.
+	UserRestore(ctx context.Context, id uuid.UUID) error
+	// UserPurgeDeleted permanently deletes the users who were deleted before
+	// the given time, and returns how many there were.
+	UserPurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error)
+	RoleList(ctx context.Context) ([]Role, error)
+	UserRoles(ctx context.Context, id uuid.UUID) ([]string, error)
+	UserPermissions(ctx context.Context, id uuid.UUID) ([]string, error)
+	UserRoleGrant(ctx context.Context, id uuid.UUID, role string) error
+	UserRoleRevoke(ctx context.Context, id uuid.UUID, role string) error
+	RefreshTokenInsert(ctx context.Context, token RefreshToken) (*RefreshToken, error)
+	RefreshTokenRetrieve(ctx context.Context, hash []byte) (*RefreshToken, error)
+	RefreshTokenRotate(ctx context.Context, id uuid.UUID, next RefreshToken) (*RefreshToken, error)
+	RefreshTokenRevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	TokenInsert(ctx context.Context, token Token) error
+	// TokenConsume deletes the unexpired token matching the hash and scope
+	// and returns the ID of the user it was issued for.
+	TokenConsume(ctx context.Context, scope string, hash []byte) (uuid.UUID, error)
+	TokenDeleteAllForUser(ctx context.Context, scope string, userID uuid.UUID) error
+	TokenCountSince(ctx context.Context, scope string, userID uuid.UUID, since time.Time) (int, error)
+	TOTPRetrieve(ctx context.Context, userID uuid.UUID) (*TOTP, error)
+	// TOTPEnroll stores a new unconfirmed secret for the user, replacing any
+	// existing one.
+	TOTPEnroll(ctx context.Context, userID uuid.UUID, secret []byte) error
+	// TOTPConfirm enables the user's pending TOTP secret and replaces their
+	// recovery codes with the given hashes.
+	TOTPConfirm(ctx context.Context, userID uuid.UUID, recoveryCodeHashes [][]byte) error
+	// TOTPDelete disables TOTP for the user and removes their recovery codes.
+	TOTPDelete(ctx context.Context, userID uuid.UUID) error
+	RecoveryCodeConsume(ctx context.Context, userID uuid.UUID, hash []byte) error
+	APIKeyInsert(ctx context.Context, key APIKey) (*APIKey, error)
+	APIKeyList(ctx context.Context, userID uuid.UUID) ([]APIKey, error)
+	APIKeyRetrieve(ctx context.Context, userID, id uuid.UUID) (*APIKey, error)
+	APIKeyUpdateName(ctx context.Context, userID, id uuid.UUID, name string) (*APIKey, error)
+	APIKeyDelete(ctx context.Context, userID, id uuid.UUID) error
+	// APIKeyUse returns the unexpired key matching the hash and records that
+	// it has been used.
+	APIKeyUse(ctx context.Context, hash []byte) (*APIKey, error)
+	IdentityRetrieve(ctx context.Context, issuer, subject string) (*Identity, error)
+	IdentityLink(ctx context.Context, identity Identity) (*Identity, error)
+	LoginThrottleRetrieve(ctx context.Context, kind, subject string) (*LoginThrottle, error)
+	// LoginFailureRecord counts a failed login at the given time. The count
+	// starts again from one if the previous failure was before windowStart.
+	LoginFailureRecord(ctx context.Context, kind, subject string, at, windowStart time.Time) (*LoginThrottle, error)
+	// LoginThrottleLock locks the subject until the given time, unless it is
+	// already locked for longer.
+	LoginThrottleLock(ctx context.Context, kind, subject string, until time.Time) error
+	// LoginThrottleReset clears the failure count and any lock.
+	LoginThrottleReset(ctx context.Context, kind, subject string) error
+	SessionInsert(ctx context.Context, session Session) (*Session, error)
+	// SessionUse returns the unexpired session matching the hash, records
+	// that it has been used and extends it until the given expiry.
+	SessionUse(ctx context.Context, hash []byte, expires time.Time) (*Session, error)
+	SessionList(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	SessionDelete(ctx context.Context, userID, id uuid.UUID) error
+	// WithAudit returns a store whose changes are recorded in the audit log
+	// as made by the given actor. Changes made through a store without an
+	// audit context are still recorded, without an actor.
+	WithAudit(audit AuditContext) GuzeiStore
+	// AuditRecord records an action that doesn't change any stored data, such
+	// as an admin starting to impersonate a user.
+	AuditRecord(ctx context.Context, action string, targetID uuid.UUID, diff map[string]AuditChange) error
+	AuditEventList(ctx context.Context, params AuditEventListParams) (*AuditEventsList, error)
+	// WithinTx runs fn in a transaction, passing it a store whose methods all
+	// run in that transaction, so that several operations succeed or fail
+	// together. The transaction is committed if fn returns nil and rolled
+	// back otherwise, and WithinTx returns fn's error. If the transaction
+	// hits a serialization failure or a deadlock, it is retried from the
+	// start, so fn may run more than once and shouldn't have side effects
+	// outside the store.
+	WithinTx(ctx context.Context, opts TxOptions, fn func(tx Tx) error) error
+	// OrganizationInsert creates the organization with the given user as its
+	// owner.
+	OrganizationInsert(ctx context.Context, organization Organization, ownerID uuid.UUID) (*Organization, error)
+	OrganizationRetrieve(ctx context.Context, id uuid.UUID) (*Organization, error)
+	OrganizationListForUser(ctx context.Context, userID uuid.UUID) ([]Organization, error)
+	MembershipRetrieve(ctx context.Context, organizationID, userID uuid.UUID) (*Membership, error)
+	MembershipList(ctx context.Context, organizationID uuid.UUID) ([]Membership, error)
+	// MembershipUpsert adds the user to the organization, or changes their
+	// role if they are already a member.
+	MembershipUpsert(ctx context.Context, organizationID, userID uuid.UUID, role string) (*Membership, error)
+	MembershipDelete(ctx context.Context, organizationID, userID uuid.UUID) error
+	InvitationInsert(ctx context.Context, invitation Invitation) (*Invitation, error)
+	InvitationRetrieve(ctx context.Context, organizationID, id uuid.UUID) (*Invitation, error)
+	// InvitationRetrieveByHash returns the unexpired invitation matching the
+	// hash.
+	InvitationRetrieveByHash(ctx context.Context, hash []byte) (*Invitation, error)
+	InvitationList(ctx context.Context, organizationID uuid.UUID) ([]Invitation, error)
+	// InvitationRenew replaces the invitation's token and expiry, so it can be
+	// sent again.
+	InvitationRenew(ctx context.Context, organizationID, id uuid.UUID, hash []byte, expires time.Time) (*Invitation, error)
+	InvitationDelete(ctx context.Context, organizationID, id uuid.UUID) error
+	// InvitationAccept consumes the unexpired invitation matching the hash
+	// and adds the user with the invitation's email address to the
+	// organization. If there is no such user, one is created with the given
+	// ID and hashed password, and with their email address verified. Users
+	// who are already members keep their current role.
+	InvitationAccept(ctx context.Context, hash []byte, userID uuid.UUID, hashedPassword string) (*Membership, error)
 }
 
+// Tx is a store bound to a transaction started by WithinTx. It must not be
+// used after fn returns. Calling WithinTx on it runs the inner fn in a nested
+// transaction, which can be rolled back on its own, and is never retried by
+// itself since the outer transaction is what gets retried.
+type Tx interface {
+	GuzeiStore
+}
+
+// IsolationLevel is the isolation level of a transaction started by WithinTx.
+// Stores that run transactions one at a time always behave as if it were
+// IsolationSerializable.
+type IsolationLevel int
+
+const (
+	// IsolationDefault uses the database's default, which is read committed
+	// in PostgreSQL.
+	IsolationDefault IsolationLevel = iota
+	IsolationReadCommitted
+	IsolationRepeatableRead
+	IsolationSerializable
+)
+
+// DefaultTxAttempts is how many times WithinTx runs a transaction that keeps
+// hitting serialization failures or deadlocks before giving up, unless
+// TxOptions says otherwise.
+const DefaultTxAttempts = 3
+
+// TxOptions configures WithinTx. The zero value uses the database's default
+// isolation level and DefaultTxAttempts.
+type TxOptions struct {
+	Isolation   IsolationLevel
+	MaxAttempts int
+}
+
+// UserListParams pages through users. If OrganizationID is set, only members
+// of that organization are listed, and if Status is set, only users with that
+// status. Pages are numbered from 1, and the totals in the result count every
+// matching user, even on a page past the end.
 type UserListParams struct {
-	PageNumber int
-	PageSize   int
+	OrganizationID *uuid.UUID
+	Status         string
+	PageNumber     int
+	PageSize       int
 }
 
 type User struct {
-	Email          string    `json:"email"`
-	ID             uuid.UUID `json:"id"`
-	Admin          bool      `json:"admin"`
-	Created        time.Time `json:"created"`
-	HashedPassword string    `json:"-"`
+	Email          string     `json:"email"`
+	ID             uuid.UUID  `json:"id"`
+	Admin          bool       `json:"admin"`
+	Created        time.Time  `json:"created"`
+	HashedPassword string     `json:"-"`
+	TokenVersion   int        `json:"-"`
+	EmailVerified  *time.Time `json:"email_verified_at"`
+	Status         string     `json:"status"`
+	StatusReason   string     `json:"status_reason"`
+	StatusChanged  *time.Time `json:"status_changed_at"`
 }
 
+// Users can only authenticate while they are active. Suspension is meant to be
+// temporary and deactivation permanent, but an admin can reactivate either.
+const (
+	UserStatusActive      = "active"
+	UserStatusSuspended   = "suspended"
+	UserStatusDeactivated = "deactivated"
+)
+
 type UsersList struct {
 	Data         []User `json:"data"`
 	TotalObjects int    `json:"total_count"`
@@ -37,6 +202,219 @@ type UsersList struct {
 	PageSize     int    `json:"page_size"`
 }
 
+const (
+	PermissionUsersRead  = "users:read"
+	PermissionUsersWrite = "users:write"
+	PermissionUsersAdmin = "users:admin"
+)
+
+const RoleAdmin = "admin"
+
+type Role struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RefreshToken struct {
+	ID       uuid.UUID
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+	Hash     []byte
+	Created  time.Time
+	Expires  time.Time
+	Used     *time.Time
+	Revoked  *time.Time
+}
+
+const (
+	ScopePasswordReset = "password-reset"
+	ScopeActivation    = "activation"
+	ScopeMFAChallenge  = "mfa-challenge"
+	ScopeMagicLink     = "magic-link"
+)
+
+type Token struct {
+	Hash    []byte
+	UserID  uuid.UUID
+	Scope   string
+	Created time.Time
+	Expiry  time.Time
+}
+
+// TOTP holds a user's TOTP enrollment. Secret is encrypted and is only
+// decrypted by the API when validating codes.
+type TOTP struct {
+	UserID    uuid.UUID
+	Secret    []byte
+	Created   time.Time
+	Confirmed *time.Time
+}
+
+type APIKey struct {
+	ID       uuid.UUID  `json:"id"`
+	UserID   uuid.UUID  `json:"-"`
+	Name     string     `json:"name"`
+	Prefix   string     `json:"prefix"`
+	Hash     []byte     `json:"-"`
+	Scopes   []string   `json:"scopes"`
+	Created  time.Time  `json:"created"`
+	Expires  *time.Time `json:"expires"`
+	LastUsed *time.Time `json:"last_used"`
+}
+
+// Identity links an account at an external OpenID Connect provider,
+// identified by its issuer and subject, to a user.
+type Identity struct {
+	Issuer  string
+	Subject string
+	UserID  uuid.UUID
+	Email   string
+	Created time.Time
+}
+
+const (
+	LoginThrottleAccount   = "account"
+	LoginThrottleIP        = "ip"
+	LoginThrottleMagicLink = "magic_link"
+)
+
+// LoginThrottle tracks failed logins for an account (keyed by email address)
+// or a client IP address, or the magic links sent to a user (keyed by user
+// ID), in which case Failures counts the links.
+type LoginThrottle struct {
+	Kind        string
+	Subject     string
+	Failures    int
+	LastFailure time.Time
+	LockedUntil *time.Time
+}
+
+// Session is a browser login authenticated with a cookie.
+type Session struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"-"`
+	Hash      []byte    `json:"-"`
+	Device    string    `json:"device"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Created   time.Time `json:"created"`
+	LastSeen  time.Time `json:"last_seen"`
+	Expires   time.Time `json:"expires"`
+}
+
+// AuditContext describes who is making changes, for the audit log.
+type AuditContext struct {
+	ActorID   *uuid.UUID
+	IP        string
+	RequestID string
+}
+
+const (
+	AuditUserCreate         = "user.create"
+	AuditUserUpdateEmail    = "user.update_email"
+	AuditUserVerifyEmail    = "user.verify_email"
+	AuditUserUpdatePassword = "user.update_password"
+	AuditUserDelete         = "user.delete"
+	AuditUserUpdateStatus   = "user.update_status"
+	AuditUserRestore        = "user.restore"
+	AuditUserPurge          = "user.purge"
+	AuditUserRoleGrant      = "user.role_grant"
+	AuditUserRoleRevoke     = "user.role_revoke"
+	AuditUserImpersonate    = "user.impersonate"
+	AuditMembershipUpdate   = "organization.member_update"
+	AuditMembershipDelete   = "organization.member_remove"
+)
+
+// AuditRedacted replaces secret values, such as password hashes, in audit
+// event diffs.
+const AuditRedacted = "[redacted]"
+
+// AuditChange is the value of a field before and after an audited change.
+type AuditChange struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID              `json:"id"`
+	ActorID   *uuid.UUID             `json:"actor_id"`
+	TargetID  *uuid.UUID             `json:"target_id"`
+	Action    string                 `json:"action"`
+	IP        string                 `json:"ip"`
+	RequestID string                 `json:"request_id"`
+	Diff      map[string]AuditChange `json:"diff"`
+	Created   time.Time              `json:"created"`
+}
+
+// AuditEventListParams filters the audit log. Zero values match every event.
+// Pages work the same way as in UserListParams.
+type AuditEventListParams struct {
+	ActorID    *uuid.UUID
+	TargetID   *uuid.UUID
+	Action     string
+	Since      *time.Time
+	Until      *time.Time
+	PageNumber int
+	PageSize   int
+}
+
+type AuditEventsList struct {
+	Data         []AuditEvent `json:"data"`
+	TotalObjects int          `json:"total_count"`
+	TotalPages   int          `json:"total_pages"`
+	Page         int          `json:"page"`
+	PageSize     int          `json:"page_size"`
+}
+
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+type Organization struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+// Membership gives a user one of the organization roles in an organization.
+type Membership struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	Created        time.Time `json:"created"`
+}
+
+// Invitation invites an email address to join an organization with a role.
+type Invitation struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	Hash           []byte     `json:"-"`
+	InvitedBy      *uuid.UUID `json:"invited_by"`
+	Created        time.Time  `json:"created"`
+	Expires        time.Time  `json:"expires"`
+}
+
 var ErrUserExists = errors.New("user with specified email already exists")
 var ErrUserNotFound = errors.New("specified user does not exists")
 var ErrStoreError = errors.New("error persisting in storage")
+var ErrRoleNotFound = errors.New("specified role does not exists")
+var ErrRefreshTokenNotFound = errors.New("specified refresh token does not exists")
+var ErrRefreshTokenReused = errors.New("specified refresh token has already been used")
+var ErrTokenNotFound = errors.New("specified token does not exists or has expired")
+var ErrTOTPNotFound = errors.New("specified user does not have totp enrolled")
+var ErrRecoveryCodeNotFound = errors.New("specified recovery code does not exists or has been used")
+var ErrAPIKeyNotFound = errors.New("specified api key does not exists or has expired")
+var ErrIdentityNotFound = errors.New("specified identity does not exists")
+var ErrIdentityExists = errors.New("specified identity is already linked")
+var ErrLoginThrottleNotFound = errors.New("no failed logins recorded")
+var ErrSessionNotFound = errors.New("session not found")
+var ErrOrganizationNotFound = errors.New("specified organization does not exists")
+var ErrMembershipNotFound = errors.New("specified user is not a member of the organization")
+var ErrInvitationNotFound = errors.New("specified invitation does not exists or has expired")
+var ErrInvitationExists = errors.New("specified email address has already been invited")
@@ -0,0 +1,138 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newAPIKey(userID uuid.UUID, name string) store.APIKey {
+	id := uuid.New()
+	return store.APIKey{
+		ID:     id,
+		UserID: userID,
+		Name:   name,
+		Prefix: id.String()[:8],
+		Hash:   id[:],
+	}
+}
+
+func testAPIKeys(t *testing.T, newStore Factory) {
+	t.Run("APIKeyInsert", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		key := newAPIKey(user.ID, "deploy")
+		key.Scopes = []string{store.PermissionUsersRead}
+		expires := time.Now().Add(time.Hour)
+		key.Expires = &expires
+
+		inserted, err := guzeiStore.APIKeyInsert(context.Background(), key)
+		require.Nil(t, err)
+		require.Equal(t, key.ID, inserted.ID)
+		require.Equal(t, user.ID, inserted.UserID)
+		require.Equal(t, "deploy", inserted.Name)
+		require.Equal(t, key.Prefix, inserted.Prefix)
+		require.Equal(t, key.Hash, inserted.Hash)
+		require.Equal(t, []string{store.PermissionUsersRead}, inserted.Scopes)
+		require.False(t, inserted.Created.IsZero())
+		require.WithinDuration(t, expires, *inserted.Expires, time.Millisecond)
+		require.Nil(t, inserted.LastUsed)
+
+		inserted, err = guzeiStore.APIKeyInsert(context.Background(), newAPIKey(user.ID, "no scopes"))
+		require.Nil(t, err)
+		require.NotNil(t, inserted.Scopes)
+		require.Empty(t, inserted.Scopes)
+		require.Nil(t, inserted.Expires)
+
+		_, err = guzeiStore.APIKeyInsert(context.Background(), newAPIKey(uuid.New(), "unknown"))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("APIKeyList and APIKeyRetrieve", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+
+		keys, err := guzeiStore.APIKeyList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, keys)
+
+		first, err := guzeiStore.APIKeyInsert(context.Background(), newAPIKey(user.ID, "first"))
+		require.Nil(t, err)
+		second, err := guzeiStore.APIKeyInsert(context.Background(), newAPIKey(user.ID, "second"))
+		require.Nil(t, err)
+		otherKey, err := guzeiStore.APIKeyInsert(context.Background(), newAPIKey(other.ID, "other"))
+		require.Nil(t, err)
+
+		keys, err = guzeiStore.APIKeyList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Len(t, keys, 2)
+		require.Equal(t, first.ID, keys[0].ID)
+		require.Equal(t, second.ID, keys[1].ID)
+
+		retrieved, err := guzeiStore.APIKeyRetrieve(context.Background(), user.ID, first.ID)
+		require.Nil(t, err)
+		require.Equal(t, "first", retrieved.Name)
+
+		_, err = guzeiStore.APIKeyRetrieve(context.Background(), user.ID, otherKey.ID)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+		_, err = guzeiStore.APIKeyRetrieve(context.Background(), user.ID, uuid.New())
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+	})
+
+	t.Run("APIKeyUpdateName and APIKeyDelete", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+		key, err := guzeiStore.APIKeyInsert(context.Background(), newAPIKey(user.ID, "deploy"))
+		require.Nil(t, err)
+
+		updated, err := guzeiStore.APIKeyUpdateName(context.Background(), user.ID, key.ID, "renamed")
+		require.Nil(t, err)
+		require.Equal(t, "renamed", updated.Name)
+		require.Equal(t, key.Prefix, updated.Prefix)
+
+		_, err = guzeiStore.APIKeyUpdateName(context.Background(), other.ID, key.ID, "stolen")
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+
+		err = guzeiStore.APIKeyDelete(context.Background(), other.ID, key.ID)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+		err = guzeiStore.APIKeyDelete(context.Background(), user.ID, key.ID)
+		require.Nil(t, err)
+		err = guzeiStore.APIKeyDelete(context.Background(), user.ID, key.ID)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+
+		_, err = guzeiStore.APIKeyRetrieve(context.Background(), user.ID, key.ID)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+		_, err = guzeiStore.APIKeyUse(context.Background(), key.Hash)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+	})
+
+	t.Run("APIKeyUse", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		key, err := guzeiStore.APIKeyInsert(context.Background(), newAPIKey(user.ID, "deploy"))
+		require.Nil(t, err)
+		used, err := guzeiStore.APIKeyUse(context.Background(), key.Hash)
+		require.Nil(t, err)
+		require.Equal(t, key.ID, used.ID)
+		require.NotNil(t, used.LastUsed)
+
+		expired := newAPIKey(user.ID, "expired")
+		expires := time.Now().Add(-time.Second)
+		expired.Expires = &expires
+		_, err = guzeiStore.APIKeyInsert(context.Background(), expired)
+		require.Nil(t, err)
+		_, err = guzeiStore.APIKeyUse(context.Background(), expired.Hash)
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+
+		_, err = guzeiStore.APIKeyUse(context.Background(), []byte("unknown"))
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+	})
+}
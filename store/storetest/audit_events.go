@@ -0,0 +1,146 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testAuditEvents(t *testing.T, newStore Factory) {
+	t.Run("WithAudit", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		actorID := uuid.New()
+		audited := guzeiStore.WithAudit(store.AuditContext{ActorID: &actorID, IP: "192.0.2.1", RequestID: "request"})
+
+		user, err := audited.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), true)
+		require.Nil(t, err)
+		unaudited := insertUser(t, guzeiStore, "other@parham.im")
+
+		// Both stores share the same data.
+		_, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		_, err = audited.UserRetrieve(context.Background(), unaudited.ID)
+		require.Nil(t, err)
+
+		events, err := guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{ActorID: &actorID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		event := events.Data[0]
+		require.Equal(t, &actorID, event.ActorID)
+		require.Equal(t, &user.ID, event.TargetID)
+		require.Equal(t, store.AuditUserCreate, event.Action)
+		require.Equal(t, "192.0.2.1", event.IP)
+		require.Equal(t, "request", event.RequestID)
+		require.Equal(t, map[string]store.AuditChange{
+			"email": {New: "im@parham.im"},
+			"admin": {New: true},
+		}, event.Diff)
+		require.False(t, event.Created.IsZero())
+
+		events, err = guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{TargetID: &unaudited.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Nil(t, events.Data[0].ActorID)
+		require.Empty(t, events.Data[0].IP)
+	})
+
+	t.Run("AuditRecord", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		actorID := uuid.New()
+		targetID := uuid.New()
+		audited := guzeiStore.WithAudit(store.AuditContext{ActorID: &actorID})
+
+		err := audited.AuditRecord(context.Background(), store.AuditUserImpersonate, targetID, map[string]store.AuditChange{
+			"organization": {New: targetID},
+			"attempts":     {Old: 1, New: 2},
+		})
+		require.Nil(t, err)
+
+		events, err := guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserImpersonate, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Equal(t, &targetID, events.Data[0].TargetID)
+		// Diffs are stored as JSON, so values come back as JSON types.
+		require.Equal(t, map[string]store.AuditChange{
+			"organization": {New: targetID.String()},
+			"attempts":     {Old: float64(1), New: float64(2)},
+		}, events.Data[0].Diff)
+	})
+
+	t.Run("AuditEventList filters", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		err := guzeiStore.UserVerifyEmail(context.Background(), user.ID)
+		require.Nil(t, err)
+		other := insertUser(t, guzeiStore, "other@parham.im")
+
+		events, err := guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 3, events.TotalObjects)
+
+		events, err = guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{TargetID: &user.ID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 2, events.TotalObjects)
+
+		events, err = guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{Action: store.AuditUserCreate, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 2, events.TotalObjects)
+
+		events, err = guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{TargetID: &other.ID, Action: store.AuditUserVerifyEmail, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.NotNil(t, events.Data)
+		require.Empty(t, events.Data)
+		require.Equal(t, 0, events.TotalObjects)
+
+		since := time.Now().Add(time.Minute)
+		events, err = guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{Since: &since, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 0, events.TotalObjects)
+
+		until := time.Now().Add(time.Minute)
+		events, err = guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{Until: &until, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 3, events.TotalObjects)
+
+		until = time.Now().Add(-time.Minute)
+		events, err = guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{Until: &until, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 0, events.TotalObjects)
+	})
+
+	t.Run("AuditEventList pagination", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		for i := 0; i < 5; i++ {
+			err := guzeiStore.AuditRecord(context.Background(), store.AuditUserImpersonate, uuid.New(), nil)
+			require.Nil(t, err)
+		}
+
+		seen := make(map[uuid.UUID]bool)
+		var previous time.Time
+		for pageNumber, size := range []int{2, 2, 1, 0} {
+			events, err := guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{PageNumber: pageNumber + 1, PageSize: 2})
+			require.Nil(t, err)
+			require.NotNil(t, events.Data)
+			require.Len(t, events.Data, size)
+			require.Equal(t, 5, events.TotalObjects)
+			require.Equal(t, 3, events.TotalPages)
+			require.Equal(t, pageNumber+1, events.Page)
+			require.Equal(t, 2, events.PageSize)
+
+			// Events are listed latest first.
+			for _, event := range events.Data {
+				require.False(t, seen[event.ID])
+				seen[event.ID] = true
+				if !previous.IsZero() {
+					require.False(t, event.Created.After(previous))
+				}
+				previous = event.Created
+			}
+		}
+		require.Len(t, seen, 5)
+	})
+}
@@ -0,0 +1,254 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newInvitation(organizationID uuid.UUID, email string, invitedBy *uuid.UUID) store.Invitation {
+	id := uuid.New()
+	return store.Invitation{
+		ID:             id,
+		OrganizationID: organizationID,
+		Email:          email,
+		Role:           store.OrganizationRoleMember,
+		Hash:           id[:],
+		InvitedBy:      invitedBy,
+		Expires:        time.Now().Add(time.Hour),
+	}
+}
+
+func insertInvitation(t *testing.T, guzeiStore store.GuzeiStore, invitation store.Invitation) *store.Invitation {
+	t.Helper()
+
+	inserted, err := guzeiStore.InvitationInsert(context.Background(), invitation)
+	require.Nil(t, err)
+	return inserted
+}
+
+func testInvitations(t *testing.T, newStore Factory) {
+	t.Run("InvitationInsert", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+
+		invitation := newInvitation(organization.ID, " New@Parham.IM", &owner.ID)
+		inserted, err := guzeiStore.InvitationInsert(context.Background(), invitation)
+		require.Nil(t, err)
+		require.Equal(t, invitation.ID, inserted.ID)
+		require.Equal(t, organization.ID, inserted.OrganizationID)
+		require.Equal(t, "New@parham.im", inserted.Email)
+		require.Equal(t, store.OrganizationRoleMember, inserted.Role)
+		require.Equal(t, invitation.Hash, inserted.Hash)
+		require.Equal(t, &owner.ID, inserted.InvitedBy)
+		require.False(t, inserted.Created.IsZero())
+		require.WithinDuration(t, invitation.Expires, inserted.Expires, time.Millisecond)
+
+		retrieved, err := guzeiStore.InvitationRetrieve(context.Background(), organization.ID, invitation.ID)
+		require.Nil(t, err)
+		require.Equal(t, "New@parham.im", retrieved.Email)
+
+		_, err = guzeiStore.InvitationRetrieve(context.Background(), uuid.New(), invitation.ID)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+		_, err = guzeiStore.InvitationRetrieve(context.Background(), organization.ID, uuid.New())
+		require.Equal(t, store.ErrInvitationNotFound, err)
+	})
+
+	t.Run("InvitationInsert errors", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+		other := insertOrganization(t, guzeiStore, "Other", owner.ID)
+		insertInvitation(t, guzeiStore, newInvitation(organization.ID, "new@parham.im", nil))
+
+		_, err := guzeiStore.InvitationInsert(context.Background(), newInvitation(organization.ID, "NEW@parham.im", nil))
+		require.Equal(t, store.ErrInvitationExists, err)
+		insertInvitation(t, guzeiStore, newInvitation(other.ID, "new@parham.im", nil))
+
+		_, err = guzeiStore.InvitationInsert(context.Background(), newInvitation(uuid.New(), "new@parham.im", nil))
+		require.Equal(t, store.ErrOrganizationNotFound, err)
+
+		unknownID := uuid.New()
+		_, err = guzeiStore.InvitationInsert(context.Background(), newInvitation(organization.ID, "unknown@parham.im", &unknownID))
+		require.Equal(t, store.ErrUserNotFound, err)
+
+		_, err = guzeiStore.InvitationInsert(context.Background(), newInvitation(organization.ID, "parham.im", nil))
+		require.ErrorIs(t, err, emailaddr.ErrInvalid)
+
+		invitation := newInvitation(organization.ID, "role@parham.im", nil)
+		invitation.Role = "superuser"
+		_, err = guzeiStore.InvitationInsert(context.Background(), invitation)
+		require.Error(t, err)
+		_, err = guzeiStore.InvitationRetrieve(context.Background(), organization.ID, invitation.ID)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+	})
+
+	t.Run("InvitationRetrieveByHash", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+
+		invitation := insertInvitation(t, guzeiStore, newInvitation(organization.ID, "new@parham.im", nil))
+		retrieved, err := guzeiStore.InvitationRetrieveByHash(context.Background(), invitation.Hash)
+		require.Nil(t, err)
+		require.Equal(t, invitation.ID, retrieved.ID)
+
+		expired := newInvitation(organization.ID, "expired@parham.im", nil)
+		expired.Expires = time.Now().Add(-time.Second)
+		insertInvitation(t, guzeiStore, expired)
+		_, err = guzeiStore.InvitationRetrieveByHash(context.Background(), expired.Hash)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+
+		_, err = guzeiStore.InvitationRetrieveByHash(context.Background(), []byte("unknown"))
+		require.Equal(t, store.ErrInvitationNotFound, err)
+	})
+
+	t.Run("InvitationList", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+		other := insertOrganization(t, guzeiStore, "Other", owner.ID)
+
+		invitations, err := guzeiStore.InvitationList(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Empty(t, invitations)
+
+		first := insertInvitation(t, guzeiStore, newInvitation(organization.ID, "first@parham.im", nil))
+		time.Sleep(time.Millisecond)
+		second := insertInvitation(t, guzeiStore, newInvitation(organization.ID, "second@parham.im", nil))
+		insertInvitation(t, guzeiStore, newInvitation(other.ID, "other@parham.im", nil))
+
+		invitations, err = guzeiStore.InvitationList(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Len(t, invitations, 2)
+		require.Equal(t, second.ID, invitations[0].ID)
+		require.Equal(t, first.ID, invitations[1].ID)
+	})
+
+	t.Run("InvitationRenew and InvitationDelete", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+		invitation := newInvitation(organization.ID, "new@parham.im", nil)
+		invitation.Expires = time.Now().Add(-time.Second)
+		insertInvitation(t, guzeiStore, invitation)
+
+		expires := time.Now().Add(time.Hour)
+		renewed, err := guzeiStore.InvitationRenew(context.Background(), organization.ID, invitation.ID, []byte("renewed"), expires)
+		require.Nil(t, err)
+		require.Equal(t, []byte("renewed"), renewed.Hash)
+		require.WithinDuration(t, expires, renewed.Expires, time.Millisecond)
+
+		_, err = guzeiStore.InvitationRetrieveByHash(context.Background(), invitation.Hash)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+		retrieved, err := guzeiStore.InvitationRetrieveByHash(context.Background(), []byte("renewed"))
+		require.Nil(t, err)
+		require.Equal(t, invitation.ID, retrieved.ID)
+
+		_, err = guzeiStore.InvitationRenew(context.Background(), uuid.New(), invitation.ID, []byte("other"), expires)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+
+		err = guzeiStore.InvitationDelete(context.Background(), uuid.New(), invitation.ID)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+		err = guzeiStore.InvitationDelete(context.Background(), organization.ID, invitation.ID)
+		require.Nil(t, err)
+		err = guzeiStore.InvitationDelete(context.Background(), organization.ID, invitation.ID)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+
+		_, err = guzeiStore.InvitationRetrieveByHash(context.Background(), []byte("renewed"))
+		require.Equal(t, store.ErrInvitationNotFound, err)
+
+		insertInvitation(t, guzeiStore, newInvitation(organization.ID, "new@parham.im", nil))
+	})
+
+	t.Run("InvitationAccept for a new user", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+		invitation := newInvitation(organization.ID, "New@parham.im", &owner.ID)
+		invitation.Role = store.OrganizationRoleAdmin
+		insertInvitation(t, guzeiStore, invitation)
+
+		userID := uuid.New()
+		membership, err := guzeiStore.InvitationAccept(context.Background(), invitation.Hash, userID, "hashed")
+		require.Nil(t, err)
+		require.Equal(t, organization.ID, membership.OrganizationID)
+		require.Equal(t, userID, membership.UserID)
+		require.Equal(t, "New@parham.im", membership.Email)
+		require.Equal(t, store.OrganizationRoleAdmin, membership.Role)
+
+		user, err := guzeiStore.UserRetrieve(context.Background(), userID)
+		require.Nil(t, err)
+		require.Equal(t, "New@parham.im", user.Email)
+		require.Equal(t, "hashed", user.HashedPassword)
+		require.NotNil(t, user.EmailVerified)
+
+		_, err = guzeiStore.InvitationRetrieve(context.Background(), organization.ID, invitation.ID)
+		require.Equal(t, store.ErrInvitationNotFound, err)
+		_, err = guzeiStore.InvitationAccept(context.Background(), invitation.Hash, uuid.New(), "hashed")
+		require.Equal(t, store.ErrInvitationNotFound, err)
+	})
+
+	t.Run("InvitationAccept for an existing user", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+
+		invitation := insertInvitation(t, guzeiStore, newInvitation(organization.ID, "IM@parham.im", nil))
+		membership, err := guzeiStore.InvitationAccept(context.Background(), invitation.Hash, uuid.New(), "hashed")
+		require.Nil(t, err)
+		require.Equal(t, user.ID, membership.UserID)
+		require.Equal(t, store.OrganizationRoleMember, membership.Role)
+
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, "password", retrieved.HashedPassword)
+
+		// Members keep their current role.
+		invitation = insertInvitation(t, guzeiStore, newInvitation(organization.ID, owner.Email, nil))
+		membership, err = guzeiStore.InvitationAccept(context.Background(), invitation.Hash, uuid.New(), "hashed")
+		require.Nil(t, err)
+		require.Equal(t, owner.ID, membership.UserID)
+		require.Equal(t, store.OrganizationRoleOwner, membership.Role)
+
+		users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 2, users.TotalObjects)
+	})
+
+	t.Run("InvitationAccept errors", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		deleted := insertUser(t, guzeiStore, "deleted@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+		err := guzeiStore.UserDelete(context.Background(), deleted.ID)
+		require.Nil(t, err)
+
+		expired := newInvitation(organization.ID, "expired@parham.im", nil)
+		expired.Expires = time.Now().Add(-time.Second)
+		insertInvitation(t, guzeiStore, expired)
+		_, err = guzeiStore.InvitationAccept(context.Background(), expired.Hash, uuid.New(), "hashed")
+		require.Equal(t, store.ErrInvitationNotFound, err)
+
+		// A deleted user's email address can't be used for a new user, and
+		// the invitation is left for later.
+		invitation := insertInvitation(t, guzeiStore, newInvitation(organization.ID, "deleted@parham.im", nil))
+		_, err = guzeiStore.InvitationAccept(context.Background(), invitation.Hash, uuid.New(), "hashed")
+		require.Equal(t, store.ErrUserExists, err)
+		_, err = guzeiStore.InvitationRetrieveByHash(context.Background(), invitation.Hash)
+		require.Nil(t, err)
+
+		invitation = insertInvitation(t, guzeiStore, newInvitation(organization.ID, "new@parham.im", nil))
+		_, err = guzeiStore.InvitationAccept(context.Background(), invitation.Hash, owner.ID, "hashed")
+		require.Equal(t, store.ErrUserExists, err)
+		_, err = guzeiStore.InvitationRetrieveByHash(context.Background(), invitation.Hash)
+		require.Nil(t, err)
+	})
+}
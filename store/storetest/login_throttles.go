@@ -0,0 +1,77 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testLoginThrottles(t *testing.T, newStore Factory) {
+	t.Run("LoginFailureRecord", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		start := time.Now().Add(-time.Hour)
+
+		_, err := guzeiStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleAccount, "im@parham.im")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+
+		throttle, err := guzeiStore.LoginFailureRecord(context.Background(), store.LoginThrottleAccount, "im@parham.im", start, start.Add(-time.Minute))
+		require.Nil(t, err)
+		require.Equal(t, store.LoginThrottleAccount, throttle.Kind)
+		require.Equal(t, "im@parham.im", throttle.Subject)
+		require.Equal(t, 1, throttle.Failures)
+		require.WithinDuration(t, start, throttle.LastFailure, time.Millisecond)
+		require.Nil(t, throttle.LockedUntil)
+
+		throttle, err = guzeiStore.LoginFailureRecord(context.Background(), store.LoginThrottleAccount, "im@parham.im", start.Add(time.Minute), start.Add(-time.Minute))
+		require.Nil(t, err)
+		require.Equal(t, 2, throttle.Failures)
+
+		// The previous failure is before the window, so counting starts again.
+		throttle, err = guzeiStore.LoginFailureRecord(context.Background(), store.LoginThrottleAccount, "im@parham.im", start.Add(time.Hour), start.Add(30*time.Minute))
+		require.Nil(t, err)
+		require.Equal(t, 1, throttle.Failures)
+
+		throttle, err = guzeiStore.LoginFailureRecord(context.Background(), store.LoginThrottleIP, "im@parham.im", start, start)
+		require.Nil(t, err)
+		require.Equal(t, 1, throttle.Failures)
+
+		retrieved, err := guzeiStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleAccount, "im@parham.im")
+		require.Nil(t, err)
+		require.Equal(t, 1, retrieved.Failures)
+		require.WithinDuration(t, start.Add(time.Hour), retrieved.LastFailure, time.Millisecond)
+	})
+
+	t.Run("LoginThrottleLock and LoginThrottleReset", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		now := time.Now()
+
+		err := guzeiStore.LoginThrottleLock(context.Background(), store.LoginThrottleIP, "192.0.2.1", now.Add(time.Hour))
+		require.Nil(t, err)
+		_, err = guzeiStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleIP, "192.0.2.1")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+
+		_, err = guzeiStore.LoginFailureRecord(context.Background(), store.LoginThrottleIP, "192.0.2.1", now, now.Add(-time.Minute))
+		require.Nil(t, err)
+
+		err = guzeiStore.LoginThrottleLock(context.Background(), store.LoginThrottleIP, "192.0.2.1", now.Add(time.Hour))
+		require.Nil(t, err)
+		err = guzeiStore.LoginThrottleLock(context.Background(), store.LoginThrottleIP, "192.0.2.1", now.Add(time.Minute))
+		require.Nil(t, err)
+
+		throttle, err := guzeiStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleIP, "192.0.2.1")
+		require.Nil(t, err)
+		require.NotNil(t, throttle.LockedUntil)
+		require.WithinDuration(t, now.Add(time.Hour), *throttle.LockedUntil, time.Millisecond)
+
+		err = guzeiStore.LoginThrottleReset(context.Background(), store.LoginThrottleIP, "192.0.2.1")
+		require.Nil(t, err)
+		_, err = guzeiStore.LoginThrottleRetrieve(context.Background(), store.LoginThrottleIP, "192.0.2.1")
+		require.Equal(t, store.ErrLoginThrottleNotFound, err)
+
+		err = guzeiStore.LoginThrottleReset(context.Background(), store.LoginThrottleIP, "192.0.2.1")
+		require.Nil(t, err)
+	})
+}
@@ -0,0 +1,174 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func insertOrganization(t *testing.T, guzeiStore store.GuzeiStore, name string, ownerID uuid.UUID) *store.Organization {
+	t.Helper()
+
+	organization, err := guzeiStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: name}, ownerID)
+	require.Nil(t, err)
+	return organization
+}
+
+func testOrganizations(t *testing.T, newStore Factory) {
+	t.Run("OrganizationInsert", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "im@parham.im")
+
+		id := uuid.New()
+		organization, err := guzeiStore.OrganizationInsert(context.Background(), store.Organization{ID: id, Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+		require.Equal(t, id, organization.ID)
+		require.Equal(t, "Acme", organization.Name)
+		require.False(t, organization.Created.IsZero())
+
+		retrieved, err := guzeiStore.OrganizationRetrieve(context.Background(), id)
+		require.Nil(t, err)
+		require.Equal(t, "Acme", retrieved.Name)
+
+		membership, err := guzeiStore.MembershipRetrieve(context.Background(), id, owner.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleOwner, membership.Role)
+		require.Equal(t, owner.Email, membership.Email)
+
+		_, err = guzeiStore.OrganizationRetrieve(context.Background(), uuid.New())
+		require.Equal(t, store.ErrOrganizationNotFound, err)
+	})
+
+	t.Run("OrganizationInsert for an unknown owner", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		deleted := insertUser(t, guzeiStore, "im@parham.im")
+		err := guzeiStore.UserDelete(context.Background(), deleted.ID)
+		require.Nil(t, err)
+
+		for _, ownerID := range []uuid.UUID{uuid.New(), deleted.ID} {
+			id := uuid.New()
+			_, err = guzeiStore.OrganizationInsert(context.Background(), store.Organization{ID: id, Name: "Acme"}, ownerID)
+			require.Equal(t, store.ErrUserNotFound, err)
+
+			_, err = guzeiStore.OrganizationRetrieve(context.Background(), id)
+			require.Equal(t, store.ErrOrganizationNotFound, err)
+		}
+	})
+
+	t.Run("OrganizationListForUser", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+
+		organizations, err := guzeiStore.OrganizationListForUser(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, organizations)
+
+		insertOrganization(t, guzeiStore, "Zeta", user.ID)
+		alpha := insertOrganization(t, guzeiStore, "Alpha", other.ID)
+		insertOrganization(t, guzeiStore, "Other", other.ID)
+		_, err = guzeiStore.MembershipUpsert(context.Background(), alpha.ID, user.ID, store.OrganizationRoleMember)
+		require.Nil(t, err)
+
+		organizations, err = guzeiStore.OrganizationListForUser(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Len(t, organizations, 2)
+		require.Equal(t, "Alpha", organizations[0].Name)
+		require.Equal(t, "Zeta", organizations[1].Name)
+	})
+
+	t.Run("MembershipUpsert", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+
+		membership, err := guzeiStore.MembershipUpsert(context.Background(), organization.ID, user.ID, store.OrganizationRoleMember)
+		require.Nil(t, err)
+		require.Equal(t, organization.ID, membership.OrganizationID)
+		require.Equal(t, user.ID, membership.UserID)
+		require.Equal(t, user.Email, membership.Email)
+		require.Equal(t, store.OrganizationRoleMember, membership.Role)
+		require.False(t, membership.Created.IsZero())
+
+		_, err = guzeiStore.MembershipUpsert(context.Background(), organization.ID, user.ID, store.OrganizationRoleMember)
+		require.Nil(t, err)
+		updated, err := guzeiStore.MembershipUpsert(context.Background(), organization.ID, user.ID, store.OrganizationRoleAdmin)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleAdmin, updated.Role)
+		require.True(t, membership.Created.Equal(updated.Created))
+
+		// Only changes are audited.
+		events, err := guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{TargetID: &user.ID, Action: store.AuditMembershipUpdate, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 2, events.TotalObjects)
+		roleChanges := make([]store.AuditChange, 0, len(events.Data))
+		for _, event := range events.Data {
+			roleChanges = append(roleChanges, event.Diff["role"])
+		}
+		require.ElementsMatch(t, []store.AuditChange{
+			{New: store.OrganizationRoleMember},
+			{Old: store.OrganizationRoleMember, New: store.OrganizationRoleAdmin},
+		}, roleChanges)
+
+		_, err = guzeiStore.MembershipUpsert(context.Background(), organization.ID, user.ID, "superuser")
+		require.Error(t, err)
+		retrieved, err := guzeiStore.MembershipRetrieve(context.Background(), organization.ID, user.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.OrganizationRoleAdmin, retrieved.Role)
+	})
+
+	t.Run("MembershipUpsert errors", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		deleted := insertUser(t, guzeiStore, "im@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+		err := guzeiStore.UserDelete(context.Background(), deleted.ID)
+		require.Nil(t, err)
+
+		_, err = guzeiStore.MembershipUpsert(context.Background(), uuid.New(), owner.ID, store.OrganizationRoleMember)
+		require.Equal(t, store.ErrOrganizationNotFound, err)
+		_, err = guzeiStore.MembershipUpsert(context.Background(), organization.ID, uuid.New(), store.OrganizationRoleMember)
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = guzeiStore.MembershipUpsert(context.Background(), organization.ID, deleted.ID, store.OrganizationRoleMember)
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("MembershipList and MembershipDelete", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "c@parham.im")
+		first := insertUser(t, guzeiStore, "a@parham.im")
+		second := insertUser(t, guzeiStore, "b@parham.im")
+		organization := insertOrganization(t, guzeiStore, "Acme", owner.ID)
+		for _, userID := range []uuid.UUID{second.ID, first.ID} {
+			_, err := guzeiStore.MembershipUpsert(context.Background(), organization.ID, userID, store.OrganizationRoleMember)
+			require.Nil(t, err)
+		}
+
+		memberships, err := guzeiStore.MembershipList(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Len(t, memberships, 3)
+		require.Equal(t, first.ID, memberships[0].UserID)
+		require.Equal(t, second.ID, memberships[1].UserID)
+		require.Equal(t, owner.ID, memberships[2].UserID)
+		require.Equal(t, store.OrganizationRoleOwner, memberships[2].Role)
+
+		err = guzeiStore.MembershipDelete(context.Background(), organization.ID, first.ID)
+		require.Nil(t, err)
+		err = guzeiStore.MembershipDelete(context.Background(), organization.ID, first.ID)
+		require.Equal(t, store.ErrMembershipNotFound, err)
+		_, err = guzeiStore.MembershipRetrieve(context.Background(), organization.ID, first.ID)
+		require.Equal(t, store.ErrMembershipNotFound, err)
+
+		memberships, err = guzeiStore.MembershipList(context.Background(), organization.ID)
+		require.Nil(t, err)
+		require.Len(t, memberships, 2)
+
+		memberships, err = guzeiStore.MembershipList(context.Background(), uuid.New())
+		require.Nil(t, err)
+		require.Empty(t, memberships)
+	})
+}
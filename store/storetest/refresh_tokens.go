@@ -0,0 +1,109 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newRefreshToken(userID, familyID uuid.UUID) store.RefreshToken {
+	id := uuid.New()
+	return store.RefreshToken{
+		ID:       id,
+		FamilyID: familyID,
+		UserID:   userID,
+		Hash:     id[:],
+		Expires:  time.Now().Add(time.Hour),
+	}
+}
+
+func insertRefreshToken(t *testing.T, guzeiStore store.GuzeiStore, userID, familyID uuid.UUID) *store.RefreshToken {
+	t.Helper()
+
+	token, err := guzeiStore.RefreshTokenInsert(context.Background(), newRefreshToken(userID, familyID))
+	require.Nil(t, err)
+	return token
+}
+
+func testRefreshTokens(t *testing.T, newStore Factory) {
+	t.Run("RefreshTokenInsert", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		token := newRefreshToken(user.ID, uuid.New())
+		inserted, err := guzeiStore.RefreshTokenInsert(context.Background(), token)
+		require.Nil(t, err)
+		require.Equal(t, token.ID, inserted.ID)
+		require.Equal(t, token.FamilyID, inserted.FamilyID)
+		require.Equal(t, user.ID, inserted.UserID)
+		require.Equal(t, token.Hash, inserted.Hash)
+		require.False(t, inserted.Created.IsZero())
+		require.Nil(t, inserted.Used)
+		require.Nil(t, inserted.Revoked)
+
+		retrieved, err := guzeiStore.RefreshTokenRetrieve(context.Background(), token.Hash)
+		require.Nil(t, err)
+		require.Equal(t, token.ID, retrieved.ID)
+		require.WithinDuration(t, token.Expires, retrieved.Expires, time.Millisecond)
+
+		_, err = guzeiStore.RefreshTokenRetrieve(context.Background(), []byte("unknown"))
+		require.Equal(t, store.ErrRefreshTokenNotFound, err)
+
+		_, err = guzeiStore.RefreshTokenInsert(context.Background(), newRefreshToken(uuid.New(), uuid.New()))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("RefreshTokenRotate", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		current := insertRefreshToken(t, guzeiStore, user.ID, uuid.New())
+
+		next := newRefreshToken(user.ID, current.FamilyID)
+		rotated, err := guzeiStore.RefreshTokenRotate(context.Background(), current.ID, next)
+		require.Nil(t, err)
+		require.Equal(t, next.ID, rotated.ID)
+		require.Equal(t, current.FamilyID, rotated.FamilyID)
+
+		retrieved, err := guzeiStore.RefreshTokenRetrieve(context.Background(), current.Hash)
+		require.Nil(t, err)
+		require.NotNil(t, retrieved.Used)
+		require.Nil(t, retrieved.Revoked)
+
+		_, err = guzeiStore.RefreshTokenRotate(context.Background(), current.ID, newRefreshToken(user.ID, current.FamilyID))
+		require.Equal(t, store.ErrRefreshTokenReused, err)
+
+		_, err = guzeiStore.RefreshTokenRotate(context.Background(), uuid.New(), newRefreshToken(user.ID, uuid.New()))
+		require.Equal(t, store.ErrRefreshTokenReused, err)
+	})
+
+	t.Run("RefreshTokenRevokeFamily", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		familyID := uuid.New()
+		first := insertRefreshToken(t, guzeiStore, user.ID, familyID)
+		second := insertRefreshToken(t, guzeiStore, user.ID, familyID)
+		other := insertRefreshToken(t, guzeiStore, user.ID, uuid.New())
+
+		err := guzeiStore.RefreshTokenRevokeFamily(context.Background(), familyID)
+		require.Nil(t, err)
+
+		for _, token := range []*store.RefreshToken{first, second} {
+			retrieved, err := guzeiStore.RefreshTokenRetrieve(context.Background(), token.Hash)
+			require.Nil(t, err)
+			require.NotNil(t, retrieved.Revoked)
+		}
+		retrieved, err := guzeiStore.RefreshTokenRetrieve(context.Background(), other.Hash)
+		require.Nil(t, err)
+		require.Nil(t, retrieved.Revoked)
+
+		_, err = guzeiStore.RefreshTokenRotate(context.Background(), first.ID, newRefreshToken(user.ID, familyID))
+		require.Equal(t, store.ErrRefreshTokenReused, err)
+
+		err = guzeiStore.RefreshTokenRevokeFamily(context.Background(), uuid.New())
+		require.Nil(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testRoles(t *testing.T, newStore Factory) {
+	t.Run("RoleList", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		roles, err := guzeiStore.RoleList(context.Background())
+		require.Nil(t, err)
+		require.Equal(t, []store.Role{
+			{
+				Name:        store.RoleAdmin,
+				Description: "Full access to user management, including granting roles",
+				Permissions: []string{store.PermissionUsersAdmin, store.PermissionUsersRead, store.PermissionUsersWrite},
+			},
+			{
+				Name:        "user-manager",
+				Description: "Can view and modify user accounts",
+				Permissions: []string{store.PermissionUsersRead, store.PermissionUsersWrite},
+			},
+		}, roles)
+	})
+
+	t.Run("UserRoleGrant", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		roles, err := guzeiStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, roles)
+		permissions, err := guzeiStore.UserPermissions(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, permissions)
+
+		err = guzeiStore.UserRoleGrant(context.Background(), user.ID, "user-manager")
+		require.Nil(t, err)
+		err = guzeiStore.UserRoleGrant(context.Background(), user.ID, "user-manager")
+		require.Nil(t, err)
+
+		roles, err = guzeiStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{"user-manager"}, roles)
+		permissions, err = guzeiStore.UserPermissions(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.PermissionUsersRead, store.PermissionUsersWrite}, permissions)
+
+		err = guzeiStore.UserRoleGrant(context.Background(), user.ID, store.RoleAdmin)
+		require.Nil(t, err)
+
+		roles, err = guzeiStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.RoleAdmin, "user-manager"}, roles)
+		permissions, err = guzeiStore.UserPermissions(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.PermissionUsersAdmin, store.PermissionUsersRead, store.PermissionUsersWrite}, permissions)
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.True(t, retrieved.Admin)
+	})
+
+	t.Run("UserRoleGrant errors", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		err := guzeiStore.UserRoleGrant(context.Background(), user.ID, "superuser")
+		require.Equal(t, store.ErrRoleNotFound, err)
+
+		err = guzeiStore.UserRoleGrant(context.Background(), uuid.New(), store.RoleAdmin)
+		require.Equal(t, store.ErrUserNotFound, err)
+
+		_, err = guzeiStore.UserRoles(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserRoleRevoke", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user, err := guzeiStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), true)
+		require.Nil(t, err)
+
+		err = guzeiStore.UserRoleRevoke(context.Background(), user.ID, store.RoleAdmin)
+		require.Nil(t, err)
+		err = guzeiStore.UserRoleRevoke(context.Background(), user.ID, store.RoleAdmin)
+		require.Nil(t, err)
+
+		roles, err := guzeiStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, roles)
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.False(t, retrieved.Admin)
+
+		err = guzeiStore.UserRoleRevoke(context.Background(), uuid.New(), store.RoleAdmin)
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
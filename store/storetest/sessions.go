@@ -0,0 +1,118 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func newSession(userID uuid.UUID) store.Session {
+	id := uuid.New()
+	return store.Session{
+		ID:        id,
+		UserID:    userID,
+		Hash:      id[:],
+		Device:    "Firefox on Linux",
+		IP:        "192.0.2.1",
+		UserAgent: "Mozilla/5.0",
+		Expires:   time.Now().Add(time.Hour),
+	}
+}
+
+func insertSession(t *testing.T, guzeiStore store.GuzeiStore, userID uuid.UUID) *store.Session {
+	t.Helper()
+
+	session, err := guzeiStore.SessionInsert(context.Background(), newSession(userID))
+	require.Nil(t, err)
+	return session
+}
+
+func testSessions(t *testing.T, newStore Factory) {
+	t.Run("SessionInsert and SessionUse", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		session := newSession(user.ID)
+		inserted, err := guzeiStore.SessionInsert(context.Background(), session)
+		require.Nil(t, err)
+		require.Equal(t, session.ID, inserted.ID)
+		require.Equal(t, user.ID, inserted.UserID)
+		require.Equal(t, session.Device, inserted.Device)
+		require.Equal(t, session.IP, inserted.IP)
+		require.Equal(t, session.UserAgent, inserted.UserAgent)
+		require.False(t, inserted.Created.IsZero())
+		require.True(t, inserted.Created.Equal(inserted.LastSeen))
+
+		expires := time.Now().Add(2 * time.Hour)
+		used, err := guzeiStore.SessionUse(context.Background(), session.Hash, expires)
+		require.Nil(t, err)
+		require.Equal(t, session.ID, used.ID)
+		require.False(t, used.LastSeen.Before(inserted.LastSeen))
+		require.WithinDuration(t, expires, used.Expires, time.Millisecond)
+
+		_, err = guzeiStore.SessionUse(context.Background(), []byte("unknown"), expires)
+		require.Equal(t, store.ErrSessionNotFound, err)
+
+		_, err = guzeiStore.SessionInsert(context.Background(), newSession(uuid.New()))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("SessionUse expired", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		session := newSession(user.ID)
+		session.Expires = time.Now().Add(-time.Second)
+		_, err := guzeiStore.SessionInsert(context.Background(), session)
+		require.Nil(t, err)
+
+		_, err = guzeiStore.SessionUse(context.Background(), session.Hash, time.Now().Add(time.Hour))
+		require.Equal(t, store.ErrSessionNotFound, err)
+
+		sessions, err := guzeiStore.SessionList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Empty(t, sessions)
+	})
+
+	t.Run("SessionList", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+
+		first := insertSession(t, guzeiStore, user.ID)
+		second := insertSession(t, guzeiStore, user.ID)
+		insertSession(t, guzeiStore, other.ID)
+
+		time.Sleep(time.Millisecond)
+		_, err := guzeiStore.SessionUse(context.Background(), first.Hash, time.Now().Add(time.Hour))
+		require.Nil(t, err)
+
+		sessions, err := guzeiStore.SessionList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Len(t, sessions, 2)
+		require.Equal(t, first.ID, sessions[0].ID)
+		require.Equal(t, second.ID, sessions[1].ID)
+	})
+
+	t.Run("SessionDelete", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+		session := insertSession(t, guzeiStore, user.ID)
+
+		err := guzeiStore.SessionDelete(context.Background(), other.ID, session.ID)
+		require.Equal(t, store.ErrSessionNotFound, err)
+
+		err = guzeiStore.SessionDelete(context.Background(), user.ID, session.ID)
+		require.Nil(t, err)
+		err = guzeiStore.SessionDelete(context.Background(), user.ID, session.ID)
+		require.Equal(t, store.ErrSessionNotFound, err)
+
+		_, err = guzeiStore.SessionUse(context.Background(), session.Hash, time.Now().Add(time.Hour))
+		require.Equal(t, store.ErrSessionNotFound, err)
+	})
+}
@@ -0,0 +1,72 @@
+// Package storetest checks that a store.GuzeiStore implementation behaves
+// the way the application expects, so that every implementation returns the
+// same results and errors for the same calls.
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns an empty store for a single test. Anything the store needs
+// cleaning up afterwards should be registered with t.Cleanup.
+type Factory func(t *testing.T) store.GuzeiStore
+
+// RunConformance runs the conformance suite against the stores returned by
+// newStore. Each test gets a store of its own, and the tests don't run in
+// parallel, so a factory may hand out the same database every time as long as
+// it is emptied in between.
+func RunConformance(t *testing.T, newStore Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newStore) })
+	t.Run("UserList", func(t *testing.T) { testUserList(t, newStore) })
+	t.Run("UserLifecycle", func(t *testing.T) { testUserLifecycle(t, newStore) })
+	t.Run("Roles", func(t *testing.T) { testRoles(t, newStore) })
+	t.Run("RefreshTokens", func(t *testing.T) { testRefreshTokens(t, newStore) })
+	t.Run("Tokens", func(t *testing.T) { testTokens(t, newStore) })
+	t.Run("TOTP", func(t *testing.T) { testTOTP(t, newStore) })
+	t.Run("APIKeys", func(t *testing.T) { testAPIKeys(t, newStore) })
+	t.Run("Identities", func(t *testing.T) { testIdentities(t, newStore) })
+	t.Run("LoginThrottles", func(t *testing.T) { testLoginThrottles(t, newStore) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, newStore) })
+	t.Run("AuditEvents", func(t *testing.T) { testAuditEvents(t, newStore) })
+	t.Run("Organizations", func(t *testing.T) { testOrganizations(t, newStore) })
+	t.Run("Invitations", func(t *testing.T) { testInvitations(t, newStore) })
+	t.Run("Transactions", func(t *testing.T) { testTransactions(t, newStore) })
+	t.Run("Context", func(t *testing.T) { testContext(t, newStore) })
+}
+
+// insertUser creates a user with the given email address, failing the test if
+// it can't.
+func insertUser(t *testing.T, guzeiStore store.GuzeiStore, email string) *store.User {
+	t.Helper()
+
+	user, err := guzeiStore.UserInsert(context.Background(), email, "password", uuid.New(), false)
+	require.Nil(t, err)
+	return user
+}
+
+func testContext(t *testing.T, newStore Factory) {
+	t.Run("cancelled context", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := guzeiStore.UserInsert(ctx, "other@parham.im", "password", uuid.New(), false)
+		require.ErrorIs(t, err, context.Canceled)
+		_, err = guzeiStore.UserRetrieve(ctx, user.ID)
+		require.ErrorIs(t, err, context.Canceled)
+		err = guzeiStore.UserDelete(ctx, user.ID)
+		require.ErrorIs(t, err, context.Canceled)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "other@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+	})
+}
@@ -0,0 +1,88 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testTokens(t *testing.T, newStore Factory) {
+	t.Run("TokenConsume", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		err := guzeiStore.TokenInsert(context.Background(), store.Token{Hash: []byte("token"), UserID: user.ID, Scope: store.ScopePasswordReset, Expiry: time.Now().Add(time.Hour)})
+		require.Nil(t, err)
+
+		_, err = guzeiStore.TokenConsume(context.Background(), store.ScopeActivation, []byte("token"))
+		require.Equal(t, store.ErrTokenNotFound, err)
+
+		userID, err := guzeiStore.TokenConsume(context.Background(), store.ScopePasswordReset, []byte("token"))
+		require.Nil(t, err)
+		require.Equal(t, user.ID, userID)
+
+		_, err = guzeiStore.TokenConsume(context.Background(), store.ScopePasswordReset, []byte("token"))
+		require.Equal(t, store.ErrTokenNotFound, err)
+	})
+
+	t.Run("TokenConsume expired", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		err := guzeiStore.TokenInsert(context.Background(), store.Token{Hash: []byte("token"), UserID: user.ID, Scope: store.ScopeMagicLink, Expiry: time.Now().Add(-time.Second)})
+		require.Nil(t, err)
+
+		_, err = guzeiStore.TokenConsume(context.Background(), store.ScopeMagicLink, []byte("token"))
+		require.Equal(t, store.ErrTokenNotFound, err)
+	})
+
+	t.Run("TokenInsert for an unknown user", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		err := guzeiStore.TokenInsert(context.Background(), store.Token{Hash: []byte("token"), UserID: uuid.New(), Scope: store.ScopeActivation, Expiry: time.Now().Add(time.Hour)})
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("TokenDeleteAllForUser and TokenCountSince", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+		since := time.Now().Add(-time.Minute)
+
+		tokens := []store.Token{
+			{Hash: []byte("first"), UserID: user.ID, Scope: store.ScopePasswordReset},
+			{Hash: []byte("second"), UserID: user.ID, Scope: store.ScopePasswordReset},
+			{Hash: []byte("activation"), UserID: user.ID, Scope: store.ScopeActivation},
+			{Hash: []byte("other"), UserID: other.ID, Scope: store.ScopePasswordReset},
+		}
+		for _, token := range tokens {
+			token.Expiry = time.Now().Add(time.Hour)
+			err := guzeiStore.TokenInsert(context.Background(), token)
+			require.Nil(t, err)
+		}
+
+		count, err := guzeiStore.TokenCountSince(context.Background(), store.ScopePasswordReset, user.ID, since)
+		require.Nil(t, err)
+		require.Equal(t, 2, count)
+		count, err = guzeiStore.TokenCountSince(context.Background(), store.ScopePasswordReset, user.ID, time.Now().Add(time.Minute))
+		require.Nil(t, err)
+		require.Equal(t, 0, count)
+
+		err = guzeiStore.TokenDeleteAllForUser(context.Background(), store.ScopePasswordReset, user.ID)
+		require.Nil(t, err)
+
+		count, err = guzeiStore.TokenCountSince(context.Background(), store.ScopePasswordReset, user.ID, since)
+		require.Nil(t, err)
+		require.Equal(t, 0, count)
+		count, err = guzeiStore.TokenCountSince(context.Background(), store.ScopeActivation, user.ID, since)
+		require.Nil(t, err)
+		require.Equal(t, 1, count)
+		count, err = guzeiStore.TokenCountSince(context.Background(), store.ScopePasswordReset, other.ID, since)
+		require.Nil(t, err)
+		require.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,89 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testTOTP(t *testing.T, newStore Factory) {
+	t.Run("TOTPEnroll and TOTPConfirm", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		_, err := guzeiStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+		err = guzeiStore.TOTPConfirm(context.Background(), user.ID, nil)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+
+		err = guzeiStore.TOTPEnroll(context.Background(), user.ID, []byte("secret"))
+		require.Nil(t, err)
+		userTOTP, err := guzeiStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, user.ID, userTOTP.UserID)
+		require.Equal(t, []byte("secret"), userTOTP.Secret)
+		require.Nil(t, userTOTP.Confirmed)
+
+		err = guzeiStore.TOTPEnroll(context.Background(), user.ID, []byte("new secret"))
+		require.Nil(t, err)
+		err = guzeiStore.TOTPConfirm(context.Background(), user.ID, [][]byte{[]byte("first"), []byte("second")})
+		require.Nil(t, err)
+
+		userTOTP, err = guzeiStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []byte("new secret"), userTOTP.Secret)
+		require.NotNil(t, userTOTP.Confirmed)
+
+		err = guzeiStore.TOTPConfirm(context.Background(), user.ID, nil)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+
+		err = guzeiStore.TOTPEnroll(context.Background(), uuid.New(), []byte("secret"))
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("RecoveryCodeConsume", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+
+		err := guzeiStore.TOTPEnroll(context.Background(), user.ID, []byte("secret"))
+		require.Nil(t, err)
+		err = guzeiStore.TOTPConfirm(context.Background(), user.ID, [][]byte{[]byte("first"), []byte("second")})
+		require.Nil(t, err)
+
+		err = guzeiStore.RecoveryCodeConsume(context.Background(), user.ID, []byte("first"))
+		require.Nil(t, err)
+		err = guzeiStore.RecoveryCodeConsume(context.Background(), user.ID, []byte("first"))
+		require.Equal(t, store.ErrRecoveryCodeNotFound, err)
+		err = guzeiStore.RecoveryCodeConsume(context.Background(), user.ID, []byte("unknown"))
+		require.Equal(t, store.ErrRecoveryCodeNotFound, err)
+		err = guzeiStore.RecoveryCodeConsume(context.Background(), other.ID, []byte("second"))
+		require.Equal(t, store.ErrRecoveryCodeNotFound, err)
+		err = guzeiStore.RecoveryCodeConsume(context.Background(), user.ID, []byte("second"))
+		require.Nil(t, err)
+	})
+
+	t.Run("TOTPDelete", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		err := guzeiStore.TOTPDelete(context.Background(), user.ID)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+
+		err = guzeiStore.TOTPEnroll(context.Background(), user.ID, []byte("secret"))
+		require.Nil(t, err)
+		err = guzeiStore.TOTPConfirm(context.Background(), user.ID, [][]byte{[]byte("first")})
+		require.Nil(t, err)
+
+		err = guzeiStore.TOTPDelete(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		_, err = guzeiStore.TOTPRetrieve(context.Background(), user.ID)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+		err = guzeiStore.RecoveryCodeConsume(context.Background(), user.ID, []byte("first"))
+		require.Equal(t, store.ErrRecoveryCodeNotFound, err)
+	})
+}
@@ -0,0 +1,140 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testTransactions(t *testing.T, newStore Factory) {
+	t.Run("WithinTx commits", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		actorID := uuid.New()
+		audited := guzeiStore.WithAudit(store.AuditContext{ActorID: &actorID})
+
+		var user *store.User
+		err := audited.WithinTx(context.Background(), store.TxOptions{Isolation: store.IsolationSerializable}, func(tx store.Tx) error {
+			var err error
+			user, err = tx.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+			if err != nil {
+				return err
+			}
+
+			// The transaction sees its own changes.
+			retrieved, err := tx.UserRetrieveByEmail(context.Background(), "im@parham.im")
+			if err != nil {
+				return err
+			}
+			require.Equal(t, user.ID, retrieved.ID)
+
+			_, err = tx.SessionInsert(context.Background(), newSession(user.ID))
+			return err
+		})
+		require.Nil(t, err)
+
+		_, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		sessions, err := guzeiStore.SessionList(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Len(t, sessions, 1)
+
+		// Stores passed to fn keep the audit context they were started from.
+		events, err := guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{ActorID: &actorID, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+		require.Equal(t, store.AuditUserCreate, events.Data[0].Action)
+	})
+
+	t.Run("WithinTx rolls back", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		existing := insertUser(t, guzeiStore, "other@parham.im")
+
+		errFailed := errors.New("failed")
+		err := guzeiStore.WithinTx(context.Background(), store.TxOptions{}, func(tx store.Tx) error {
+			_, err := tx.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), true)
+			if err != nil {
+				return err
+			}
+			err = tx.UserUpdateEmail(context.Background(), existing.ID, "updated@parham.im")
+			if err != nil {
+				return err
+			}
+			return errFailed
+		})
+		require.Equal(t, errFailed, err)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "im@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), existing.ID)
+		require.Nil(t, err)
+		require.Equal(t, "other@parham.im", retrieved.Email)
+
+		events, err := guzeiStore.AuditEventList(context.Background(), store.AuditEventListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, events.Data, 1)
+
+		// Errors from the store roll back the changes made before them too.
+		err = guzeiStore.WithinTx(context.Background(), store.TxOptions{}, func(tx store.Tx) error {
+			_, err := tx.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+			if err != nil {
+				return err
+			}
+			_, err = tx.UserInsert(context.Background(), "OTHER@parham.im", "password", uuid.New(), false)
+			return err
+		})
+		require.Equal(t, store.ErrUserExists, err)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "im@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("nested WithinTx", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		errFailed := errors.New("failed")
+		err := guzeiStore.WithinTx(context.Background(), store.TxOptions{}, func(tx store.Tx) error {
+			_, err := tx.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+			if err != nil {
+				return err
+			}
+
+			err = tx.WithinTx(context.Background(), store.TxOptions{}, func(inner store.Tx) error {
+				_, err := inner.UserInsert(context.Background(), "other@parham.im", "password", uuid.New(), false)
+				if err != nil {
+					return err
+				}
+				return errFailed
+			})
+			require.Equal(t, errFailed, err)
+
+			return tx.WithinTx(context.Background(), store.TxOptions{}, func(inner store.Tx) error {
+				_, err := inner.UserInsert(context.Background(), "third@parham.im", "password", uuid.New(), false)
+				return err
+			})
+		})
+		require.Nil(t, err)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "im@parham.im")
+		require.Nil(t, err)
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "other@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "third@parham.im")
+		require.Nil(t, err)
+	})
+
+	t.Run("invalid isolation level", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		called := false
+		err := guzeiStore.WithinTx(context.Background(), store.TxOptions{Isolation: store.IsolationLevel(42)}, func(tx store.Tx) error {
+			called = true
+			return nil
+		})
+		require.ErrorIs(t, err, store.ErrStoreError)
+		require.False(t, called)
+	})
+}
@@ -0,0 +1,55 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testIdentities(t *testing.T, newStore Factory) {
+	t.Run("IdentityLink and IdentityRetrieve", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		issuer := "https://accounts.example.com"
+
+		_, err := guzeiStore.IdentityRetrieve(context.Background(), issuer, "subject")
+		require.Equal(t, store.ErrIdentityNotFound, err)
+
+		linked, err := guzeiStore.IdentityLink(context.Background(), store.Identity{Issuer: issuer, Subject: "subject", UserID: user.ID, Email: user.Email})
+		require.Nil(t, err)
+		require.Equal(t, issuer, linked.Issuer)
+		require.Equal(t, "subject", linked.Subject)
+		require.Equal(t, user.ID, linked.UserID)
+		require.Equal(t, user.Email, linked.Email)
+		require.False(t, linked.Created.IsZero())
+
+		retrieved, err := guzeiStore.IdentityRetrieve(context.Background(), issuer, "subject")
+		require.Nil(t, err)
+		require.Equal(t, user.ID, retrieved.UserID)
+
+		_, err = guzeiStore.IdentityRetrieve(context.Background(), "https://other.example.com", "subject")
+		require.Equal(t, store.ErrIdentityNotFound, err)
+
+		_, err = guzeiStore.IdentityLink(context.Background(), store.Identity{Issuer: "https://other.example.com", Subject: "subject", UserID: user.ID, Email: user.Email})
+		require.Nil(t, err)
+	})
+
+	t.Run("IdentityLink errors", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+		issuer := "https://accounts.example.com"
+
+		_, err := guzeiStore.IdentityLink(context.Background(), store.Identity{Issuer: issuer, Subject: "subject", UserID: user.ID, Email: user.Email})
+		require.Nil(t, err)
+
+		_, err = guzeiStore.IdentityLink(context.Background(), store.Identity{Issuer: issuer, Subject: "subject", UserID: other.ID, Email: other.Email})
+		require.Equal(t, store.ErrIdentityExists, err)
+
+		_, err = guzeiStore.IdentityLink(context.Background(), store.Identity{Issuer: issuer, Subject: "unknown", UserID: uuid.New(), Email: "unknown@parham.im"})
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
@@ -0,0 +1,536 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mrityunjaygr8/autostrada-test/internal/emailaddr"
+	"github.com/mrityunjaygr8/autostrada-test/store"
+	"github.com/stretchr/testify/require"
+)
+
+func testUsers(t *testing.T, newStore Factory) {
+	t.Run("UserInsert happy path", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		id := uuid.New()
+		user, err := guzeiStore.UserInsert(context.Background(), " Im@Parham.IM ", "password", id, false)
+		require.Nil(t, err)
+
+		require.Equal(t, "Im@parham.im", user.Email)
+		require.Equal(t, id, user.ID)
+		require.False(t, user.Admin)
+		require.False(t, user.Created.IsZero())
+		require.Equal(t, "password", user.HashedPassword)
+		require.Equal(t, 1, user.TokenVersion)
+		require.Nil(t, user.EmailVerified)
+		require.Equal(t, store.UserStatusActive, user.Status)
+		require.Empty(t, user.StatusReason)
+		require.Nil(t, user.StatusChanged)
+	})
+
+	t.Run("UserInsert with admin", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		user, err := guzeiStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), true)
+		require.Nil(t, err)
+		require.True(t, user.Admin)
+
+		roles, err := guzeiStore.UserRoles(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, []string{store.RoleAdmin}, roles)
+	})
+
+	t.Run("UserInsert with an invalid email", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		_, err := guzeiStore.UserInsert(context.Background(), "parham.im", "password", uuid.New(), false)
+		require.ErrorIs(t, err, emailaddr.ErrInvalid)
+	})
+
+	t.Run("UserInsert for duplicates", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		_, err := guzeiStore.UserInsert(context.Background(), "IM@Parham.im", "password", uuid.New(), false)
+		require.Equal(t, store.ErrUserExists, err)
+
+		_, err = guzeiStore.UserInsert(context.Background(), "other@parham.im", "password", user.ID, false)
+		require.Equal(t, store.ErrUserExists, err)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "other@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("concurrent UserInsert with the same email", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		errs := make([]error, 20)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = guzeiStore.UserInsert(context.Background(), "im@parham.im", "password", uuid.New(), false)
+			}(i)
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			if err == nil {
+				created++
+				continue
+			}
+			require.Equal(t, store.ErrUserExists, err)
+		}
+		require.Equal(t, 1, created)
+
+		users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, 1, users.TotalObjects)
+	})
+
+	t.Run("concurrent UserInsert with different emails", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		errs := make([]error, 20)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = guzeiStore.UserInsert(context.Background(), fmt.Sprintf("user%02d@parham.im", i), "password", uuid.New(), false)
+			}(i)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			require.Nil(t, err)
+		}
+
+		users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 50})
+		require.Nil(t, err)
+		require.Equal(t, len(errs), users.TotalObjects)
+		require.Len(t, users.Data, len(errs))
+	})
+
+	t.Run("UserRetrieve", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, user.ID, retrieved.ID)
+		require.Equal(t, user.Email, retrieved.Email)
+		require.Equal(t, user.HashedPassword, retrieved.HashedPassword)
+		require.Equal(t, user.TokenVersion, retrieved.TokenVersion)
+		require.True(t, user.Created.Equal(retrieved.Created))
+
+		_, err = guzeiStore.UserRetrieve(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserRetrieveByEmail", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "Im@parham.im")
+
+		retrieved, err := guzeiStore.UserRetrieveByEmail(context.Background(), " iM@PARHAM.IM")
+		require.Nil(t, err)
+		require.Equal(t, user.ID, retrieved.ID)
+		require.Equal(t, "Im@parham.im", retrieved.Email)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "other@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserUpdateEmail", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		other := insertUser(t, guzeiStore, "other@parham.im")
+
+		err := guzeiStore.UserVerifyEmail(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		err = guzeiStore.UserUpdateEmail(context.Background(), user.ID, "IM@parham.im")
+		require.Nil(t, err)
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, "IM@parham.im", retrieved.Email)
+		require.NotNil(t, retrieved.EmailVerified)
+
+		err = guzeiStore.UserUpdateEmail(context.Background(), user.ID, "new@parham.im")
+		require.Nil(t, err)
+		retrieved, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, "new@parham.im", retrieved.Email)
+		require.Nil(t, retrieved.EmailVerified)
+
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "im@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+		insertUser(t, guzeiStore, "im@parham.im")
+
+		err = guzeiStore.UserUpdateEmail(context.Background(), user.ID, "Other@parham.im")
+		require.Equal(t, store.ErrUserExists, err)
+		retrieved, err = guzeiStore.UserRetrieve(context.Background(), other.ID)
+		require.Nil(t, err)
+		require.Equal(t, "other@parham.im", retrieved.Email)
+
+		err = guzeiStore.UserUpdateEmail(context.Background(), user.ID, "parham.im")
+		require.ErrorIs(t, err, emailaddr.ErrInvalid)
+
+		err = guzeiStore.UserUpdateEmail(context.Background(), uuid.New(), "unknown@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserVerifyEmail", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		err := guzeiStore.UserVerifyEmail(context.Background(), user.ID)
+		require.Nil(t, err)
+		verified, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.NotNil(t, verified.EmailVerified)
+
+		err = guzeiStore.UserVerifyEmail(context.Background(), user.ID)
+		require.Nil(t, err)
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.True(t, verified.EmailVerified.Equal(*retrieved.EmailVerified))
+
+		err = guzeiStore.UserVerifyEmail(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserUpdatePassword", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		refreshToken := insertRefreshToken(t, guzeiStore, user.ID, uuid.New())
+		session := insertSession(t, guzeiStore, user.ID)
+
+		err := guzeiStore.UserUpdatePassword(context.Background(), user.ID, "new password")
+		require.Nil(t, err)
+
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, "new password", retrieved.HashedPassword)
+		require.Equal(t, user.TokenVersion+1, retrieved.TokenVersion)
+		requireLoggedOut(t, guzeiStore, refreshToken, session)
+
+		err = guzeiStore.UserUpdatePassword(context.Background(), uuid.New(), "new password")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserUpdateAdmin", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		err := guzeiStore.UserUpdateAdmin(context.Background(), user.ID, true)
+		require.Nil(t, err)
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.True(t, retrieved.Admin)
+
+		err = guzeiStore.UserUpdateAdmin(context.Background(), user.ID, false)
+		require.Nil(t, err)
+		retrieved, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.False(t, retrieved.Admin)
+
+		err = guzeiStore.UserUpdateAdmin(context.Background(), uuid.New(), true)
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserUpdateStatus", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		refreshToken := insertRefreshToken(t, guzeiStore, user.ID, uuid.New())
+		session := insertSession(t, guzeiStore, user.ID)
+
+		err := guzeiStore.UserUpdateStatus(context.Background(), user.ID, store.UserStatusSuspended, "spam")
+		require.Nil(t, err)
+
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.UserStatusSuspended, retrieved.Status)
+		require.Equal(t, "spam", retrieved.StatusReason)
+		require.NotNil(t, retrieved.StatusChanged)
+		require.Equal(t, user.TokenVersion+1, retrieved.TokenVersion)
+		requireLoggedOut(t, guzeiStore, refreshToken, session)
+
+		err = guzeiStore.UserUpdateStatus(context.Background(), user.ID, store.UserStatusActive, "")
+		require.Nil(t, err)
+		retrieved, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.UserStatusActive, retrieved.Status)
+		require.Empty(t, retrieved.StatusReason)
+		require.Equal(t, user.TokenVersion+1, retrieved.TokenVersion)
+
+		err = guzeiStore.UserUpdateStatus(context.Background(), user.ID, "banned", "")
+		require.Error(t, err)
+		retrieved, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, store.UserStatusActive, retrieved.Status)
+
+		err = guzeiStore.UserUpdateStatus(context.Background(), uuid.New(), store.UserStatusSuspended, "")
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+}
+
+func testUserList(t *testing.T, newStore Factory) {
+	t.Run("UserList with no users", func(t *testing.T) {
+		guzeiStore := newStore(t)
+
+		users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.NotNil(t, users.Data)
+		require.Empty(t, users.Data)
+		require.Equal(t, 0, users.TotalObjects)
+		require.Equal(t, 0, users.TotalPages)
+		require.Equal(t, 1, users.Page)
+		require.Equal(t, 10, users.PageSize)
+	})
+
+	t.Run("UserList pagination", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		for _, email := range []string{"c@parham.im", "e@parham.im", "a@parham.im", "d@parham.im", "b@parham.im"} {
+			insertUser(t, guzeiStore, email)
+		}
+
+		pages := map[int][]string{
+			1: {"a@parham.im", "b@parham.im"},
+			2: {"c@parham.im", "d@parham.im"},
+			3: {"e@parham.im"},
+			4: {},
+		}
+		for pageNumber, emails := range pages {
+			users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: pageNumber, PageSize: 2})
+			require.Nil(t, err)
+			require.NotNil(t, users.Data)
+			require.Equal(t, emails, listedEmails(users), "page %d", pageNumber)
+			require.Equal(t, 5, users.TotalObjects, "page %d", pageNumber)
+			require.Equal(t, 3, users.TotalPages, "page %d", pageNumber)
+			require.Equal(t, pageNumber, users.Page)
+			require.Equal(t, 2, users.PageSize)
+		}
+
+		users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 5})
+		require.Nil(t, err)
+		require.Len(t, users.Data, 5)
+		require.Equal(t, 1, users.TotalPages)
+	})
+
+	t.Run("UserList hides secrets", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		admin, err := guzeiStore.UserInsert(context.Background(), "admin@parham.im", "password", uuid.New(), true)
+		require.Nil(t, err)
+		insertUser(t, guzeiStore, "im@parham.im")
+
+		users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Len(t, users.Data, 2)
+		require.Equal(t, admin.ID, users.Data[0].ID)
+		require.True(t, users.Data[0].Admin)
+		require.False(t, users.Data[1].Admin)
+		for _, user := range users.Data {
+			require.Empty(t, user.HashedPassword)
+			require.Zero(t, user.TokenVersion)
+			require.Equal(t, store.UserStatusActive, user.Status)
+		}
+	})
+
+	t.Run("UserList filters", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "a@parham.im")
+		member := insertUser(t, guzeiStore, "b@parham.im")
+		suspended := insertUser(t, guzeiStore, "c@parham.im")
+		deleted := insertUser(t, guzeiStore, "d@parham.im")
+
+		organization, err := guzeiStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+		_, err = guzeiStore.MembershipUpsert(context.Background(), organization.ID, member.ID, store.OrganizationRoleMember)
+		require.Nil(t, err)
+		_, err = guzeiStore.MembershipUpsert(context.Background(), organization.ID, deleted.ID, store.OrganizationRoleMember)
+		require.Nil(t, err)
+
+		err = guzeiStore.UserUpdateStatus(context.Background(), suspended.ID, store.UserStatusSuspended, "")
+		require.Nil(t, err)
+		err = guzeiStore.UserDelete(context.Background(), deleted.ID)
+		require.Nil(t, err)
+
+		users, err := guzeiStore.UserList(context.Background(), store.UserListParams{PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, []string{"a@parham.im", "b@parham.im", "c@parham.im"}, listedEmails(users))
+		require.Equal(t, 3, users.TotalObjects)
+
+		users, err = guzeiStore.UserList(context.Background(), store.UserListParams{Status: store.UserStatusSuspended, PageNumber: 1, PageSize: 10})
+		require.Nil(t, err)
+		require.Equal(t, []string{"c@parham.im"}, listedEmails(users))
+		require.Equal(t, 1, users.TotalObjects)
+
+		users, err = guzeiStore.UserList(context.Background(), store.UserListParams{OrganizationID: &organization.ID, PageNumber: 1, PageSize: 1})
+		require.Nil(t, err)
+		require.Equal(t, []string{"a@parham.im"}, listedEmails(users))
+		require.Equal(t, 2, users.TotalObjects)
+		require.Equal(t, 2, users.TotalPages)
+
+		users, err = guzeiStore.UserList(context.Background(), store.UserListParams{OrganizationID: &organization.ID, Status: store.UserStatusSuspended, PageNumber: 2, PageSize: 1})
+		require.Nil(t, err)
+		require.Empty(t, users.Data)
+		require.Equal(t, 0, users.TotalObjects)
+		require.Equal(t, 0, users.TotalPages)
+	})
+}
+
+func listedEmails(users *store.UsersList) []string {
+	emails := make([]string, 0, len(users.Data))
+	for _, user := range users.Data {
+		emails = append(emails, user.Email)
+	}
+	return emails
+}
+
+func testUserLifecycle(t *testing.T, newStore Factory) {
+	t.Run("UserDelete", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		user := insertUser(t, guzeiStore, "im@parham.im")
+		refreshToken := insertRefreshToken(t, guzeiStore, user.ID, uuid.New())
+		session := insertSession(t, guzeiStore, user.ID)
+
+		organization, err := guzeiStore.OrganizationInsert(context.Background(), store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+		_, err = guzeiStore.MembershipUpsert(context.Background(), organization.ID, user.ID, store.OrganizationRoleMember)
+		require.Nil(t, err)
+
+		err = guzeiStore.UserDelete(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		_, err = guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = guzeiStore.UserRetrieveByEmail(context.Background(), "im@parham.im")
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = guzeiStore.UserRoles(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		_, err = guzeiStore.MembershipRetrieve(context.Background(), organization.ID, user.ID)
+		require.Equal(t, store.ErrMembershipNotFound, err)
+		requireLoggedOut(t, guzeiStore, refreshToken, session)
+
+		_, err = guzeiStore.UserInsert(context.Background(), "IM@parham.im", "password", uuid.New(), false)
+		require.Equal(t, store.ErrUserExists, err)
+
+		err = guzeiStore.UserDelete(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		err = guzeiStore.UserDelete(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserRestore", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		user := insertUser(t, guzeiStore, "im@parham.im")
+
+		err := guzeiStore.UserRestore(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+
+		err = guzeiStore.UserDelete(context.Background(), user.ID)
+		require.Nil(t, err)
+		err = guzeiStore.UserRestore(context.Background(), user.ID)
+		require.Nil(t, err)
+
+		retrieved, err := guzeiStore.UserRetrieve(context.Background(), user.ID)
+		require.Nil(t, err)
+		require.Equal(t, user.Email, retrieved.Email)
+		require.Equal(t, user.TokenVersion+1, retrieved.TokenVersion)
+
+		err = guzeiStore.UserRestore(context.Background(), user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		err = guzeiStore.UserRestore(context.Background(), uuid.New())
+		require.Equal(t, store.ErrUserNotFound, err)
+	})
+
+	t.Run("UserPurgeDeleted", func(t *testing.T) {
+		guzeiStore := newStore(t)
+		ctx := context.Background()
+		owner := insertUser(t, guzeiStore, "owner@parham.im")
+		kept := insertUser(t, guzeiStore, "kept@parham.im")
+		user, err := guzeiStore.UserInsert(ctx, "im@parham.im", "password", uuid.New(), true)
+		require.Nil(t, err)
+
+		organization, err := guzeiStore.OrganizationInsert(ctx, store.Organization{ID: uuid.New(), Name: "Acme"}, owner.ID)
+		require.Nil(t, err)
+		_, err = guzeiStore.MembershipUpsert(ctx, organization.ID, user.ID, store.OrganizationRoleAdmin)
+		require.Nil(t, err)
+		invitation, err := guzeiStore.InvitationInsert(ctx, store.Invitation{ID: uuid.New(), OrganizationID: organization.ID, Email: "new@parham.im", Role: store.OrganizationRoleMember, Hash: []byte("invitation"), InvitedBy: &user.ID, Expires: time.Now().Add(time.Hour)})
+		require.Nil(t, err)
+		refreshToken := insertRefreshToken(t, guzeiStore, user.ID, uuid.New())
+		err = guzeiStore.TokenInsert(ctx, store.Token{Hash: []byte("token"), UserID: user.ID, Scope: store.ScopePasswordReset, Expiry: time.Now().Add(time.Hour)})
+		require.Nil(t, err)
+		err = guzeiStore.TOTPEnroll(ctx, user.ID, []byte("secret"))
+		require.Nil(t, err)
+		_, err = guzeiStore.APIKeyInsert(ctx, store.APIKey{ID: uuid.New(), UserID: user.ID, Name: "key", Prefix: "prefix", Hash: []byte("key")})
+		require.Nil(t, err)
+		_, err = guzeiStore.IdentityLink(ctx, store.Identity{Issuer: "https://accounts.example.com", Subject: "subject", UserID: user.ID, Email: user.Email})
+		require.Nil(t, err)
+
+		err = guzeiStore.UserDelete(ctx, user.ID)
+		require.Nil(t, err)
+		err = guzeiStore.UserDelete(ctx, kept.ID)
+		require.Nil(t, err)
+
+		purged, err := guzeiStore.UserPurgeDeleted(ctx, time.Now().Add(-time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, 0, purged)
+
+		purged, err = guzeiStore.UserPurgeDeleted(ctx, time.Now().Add(time.Hour))
+		require.Nil(t, err)
+		require.Equal(t, 2, purged)
+
+		err = guzeiStore.UserRestore(ctx, user.ID)
+		require.Equal(t, store.ErrUserNotFound, err)
+		permissions, err := guzeiStore.UserPermissions(ctx, user.ID)
+		require.Nil(t, err)
+		require.Empty(t, permissions)
+		_, err = guzeiStore.RefreshTokenRetrieve(ctx, refreshToken.Hash)
+		require.Equal(t, store.ErrRefreshTokenNotFound, err)
+		_, err = guzeiStore.TokenConsume(ctx, store.ScopePasswordReset, []byte("token"))
+		require.Equal(t, store.ErrTokenNotFound, err)
+		_, err = guzeiStore.TOTPRetrieve(ctx, user.ID)
+		require.Equal(t, store.ErrTOTPNotFound, err)
+		_, err = guzeiStore.APIKeyUse(ctx, []byte("key"))
+		require.Equal(t, store.ErrAPIKeyNotFound, err)
+		_, err = guzeiStore.IdentityRetrieve(ctx, "https://accounts.example.com", "subject")
+		require.Equal(t, store.ErrIdentityNotFound, err)
+
+		memberships, err := guzeiStore.MembershipList(ctx, organization.ID)
+		require.Nil(t, err)
+		require.Len(t, memberships, 1)
+		require.Equal(t, owner.ID, memberships[0].UserID)
+
+		retrieved, err := guzeiStore.InvitationRetrieve(ctx, organization.ID, invitation.ID)
+		require.Nil(t, err)
+		require.Nil(t, retrieved.InvitedBy)
+
+		insertUser(t, guzeiStore, "im@parham.im")
+	})
+}
+
+// requireLoggedOut checks that the refresh token has been revoked and the
+// session deleted.
+func requireLoggedOut(t *testing.T, guzeiStore store.GuzeiStore, refreshToken *store.RefreshToken, session *store.Session) {
+	t.Helper()
+
+	retrieved, err := guzeiStore.RefreshTokenRetrieve(context.Background(), refreshToken.Hash)
+	require.Nil(t, err)
+	require.NotNil(t, retrieved.Revoked)
+
+	_, err = guzeiStore.SessionUse(context.Background(), session.Hash, time.Now().Add(time.Hour))
+	require.Equal(t, store.ErrSessionNotFound, err)
+}